@@ -97,7 +97,7 @@ func (c *Client) CreateTranscription(
 	ctx context.Context,
 	request AudioRequest,
 ) (response AudioResponse, err error) {
-	return c.callAudioAPI(ctx, request, "transcriptions")
+	return c.callAudioAPI(ctx, request, "transcriptions", OperationCreateTranscription)
 }
 
 // CreateTranslation — API call to translate audio into English.
@@ -105,7 +105,7 @@ func (c *Client) CreateTranslation(
 	ctx context.Context,
 	request AudioRequest,
 ) (response AudioResponse, err error) {
-	return c.callAudioAPI(ctx, request, "translations")
+	return c.callAudioAPI(ctx, request, "translations", OperationCreateTranslation)
 }
 
 // callAudioAPI — API call to an audio endpoint.
@@ -113,6 +113,7 @@ func (c *Client) callAudioAPI(
 	ctx context.Context,
 	request AudioRequest,
 	endpointSuffix string,
+	op Operation,
 ) (response AudioResponse, err error) {
 	var formBody bytes.Buffer
 	builder := c.createFormBuilder(&formBody)
@@ -128,6 +129,7 @@ func (c *Client) callAudioAPI(
 		c.fullURL(urlSuffix, withModel(request.Model)),
 		withBody(&formBody),
 		withContentType(builder.FormDataContentType()),
+		withOperation(op),
 	)
 	if err != nil {
 		return AudioResponse{}, err
@@ -117,7 +117,8 @@ func (c *Client) CreateBatch(
 		request.CompletionWindow = "24h"
 	}
 
-	req, err := c.newRequest(ctx, http.MethodPost, c.fullURL(batchesSuffix), withBody(request))
+	req, err := c.newRequest(ctx, http.MethodPost, c.fullURL(batchesSuffix), withBody(request),
+		withOperation(OperationCreateBatch))
 	if err != nil {
 		return
 	}
@@ -215,7 +216,7 @@ func (c *Client) RetrieveBatch(
 	batchID string,
 ) (response BatchResponse, err error) {
 	urlSuffix := fmt.Sprintf("%s/%s", batchesSuffix, batchID)
-	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL(urlSuffix))
+	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL(urlSuffix), withOperation(OperationRetrieveBatch))
 	if err != nil {
 		return
 	}
@@ -229,7 +230,7 @@ func (c *Client) CancelBatch(
 	batchID string,
 ) (response BatchResponse, err error) {
 	urlSuffix := fmt.Sprintf("%s/%s/cancel", batchesSuffix, batchID)
-	req, err := c.newRequest(ctx, http.MethodPost, c.fullURL(urlSuffix))
+	req, err := c.newRequest(ctx, http.MethodPost, c.fullURL(urlSuffix), withOperation(OperationCancelBatch))
 	if err != nil {
 		return
 	}
@@ -261,7 +262,7 @@ func (c *Client) ListBatch(ctx context.Context, after *string, limit *int) (resp
 	}
 
 	urlSuffix := fmt.Sprintf("%s%s", batchesSuffix, encodedValues)
-	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL(urlSuffix))
+	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL(urlSuffix), withOperation(OperationListBatch))
 	if err != nil {
 		return
 	}
@@ -78,7 +78,13 @@ type ChatCompletionStream struct {
 func (c *Client) CreateChatCompletionStream(
 	ctx context.Context,
 	request ChatCompletionRequest,
+	opts ...ChatCompletionStreamOption,
 ) (stream *ChatCompletionStream, err error) {
+	options := chatCompletionStreamOptions{continuation: continueWithPrefix}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	urlSuffix := chatCompletionsSuffix
 	if !checkEndpointSupportsModel(urlSuffix, request.Model) {
 		err = ErrChatCompletionInvalidModel
@@ -90,23 +96,45 @@ func (c *Client) CreateChatCompletionStream(
 	if err = reasoningValidator.Validate(request); err != nil {
 		return
 	}
+	if request, err = strictSchemas(request); err != nil {
+		return
+	}
 
+	resp, err := c.openChatCompletionStream(ctx, request, "")
+	if err != nil {
+		return
+	}
+	if options.maxReconnects > 0 {
+		resumer := &streamResumer{client: c, request: request, options: options, stream: resp}
+		resumer.ctx, resumer.cancel = context.WithCancel(ctx)
+		resp.reconnect = resumer.reconnect
+		resp.observers = append([]StreamObserver{resumer}, resp.observers...)
+	}
+	stream = &ChatCompletionStream{
+		streamReader: resp,
+	}
+	return
+}
+
+// openChatCompletionStream sends the streaming request. The last event ID is
+// sent when resuming a stream that set one.
+func (c *Client) openChatCompletionStream(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	lastEventID string,
+) (*streamReader[ChatCompletionStreamResponse], error) {
 	req, err := c.newRequest(
 		ctx,
 		http.MethodPost,
-		c.fullURL(urlSuffix, withModel(request.Model)),
+		c.fullURL(chatCompletionsSuffix, withModel(request.Model)),
 		withBody(request),
+		withOperation(OperationCreateChatCompletionStream),
 	)
 	if err != nil {
 		return nil, err
 	}
-
-	resp, err := sendRequestStream[ChatCompletionStreamResponse](c, req)
-	if err != nil {
-		return
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
 	}
-	stream = &ChatCompletionStream{
-		streamReader: resp,
-	}
-	return
+	return sendRequestStream[ChatCompletionStreamResponse](c, req)
 }
@@ -0,0 +1,266 @@
+package giteeai
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+const chatCompletionObject = "chat.completion"
+
+// ChatCompletionAccumulator merges the chunks of a chat completion stream.
+// At any point Response returns what has been received so far in the shape
+// returned by CreateChatCompletion. The zero value is ready to use.
+type ChatCompletionAccumulator struct {
+	id                  string
+	created             int64
+	model               string
+	systemFingerprint   string
+	promptFilterResults []PromptFilterResult
+	usage               *Usage
+	choices             map[int]*accumulatedChoice
+}
+
+type accumulatedChoice struct {
+	role                 string
+	content              strings.Builder
+	reasoningContent     strings.Builder
+	refusal              strings.Builder
+	functionCall         *FunctionCall
+	toolCalls            []*accumulatedToolCall
+	finishReason         FinishReason
+	logProbs             []LogProb
+	hasLogProbs          bool
+	contentFilterResults ContentFilterResults
+}
+
+type accumulatedToolCall struct {
+	index     int
+	id        string
+	toolType  ToolType
+	name      strings.Builder
+	arguments strings.Builder
+}
+
+// NewChatCompletionAccumulator creates an empty ChatCompletionAccumulator.
+func NewChatCompletionAccumulator() *ChatCompletionAccumulator {
+	return &ChatCompletionAccumulator{}
+}
+
+// Add merges a chunk into the accumulated response.
+func (a *ChatCompletionAccumulator) Add(chunk ChatCompletionStreamResponse) {
+	if chunk.ID != "" {
+		a.id = chunk.ID
+	}
+	if chunk.Created != 0 {
+		a.created = chunk.Created
+	}
+	if chunk.Model != "" {
+		a.model = chunk.Model
+	}
+	if chunk.SystemFingerprint != "" {
+		a.systemFingerprint = chunk.SystemFingerprint
+	}
+	a.promptFilterResults = append(a.promptFilterResults, chunk.PromptFilterResults...)
+	if chunk.Usage != nil {
+		usage := *chunk.Usage
+		a.usage = &usage
+	}
+	for _, choice := range chunk.Choices {
+		a.addChoice(choice)
+	}
+}
+
+func (a *ChatCompletionAccumulator) addChoice(choice ChatCompletionStreamChoice) {
+	if a.choices == nil {
+		a.choices = make(map[int]*accumulatedChoice)
+	}
+	acc, ok := a.choices[choice.Index]
+	if !ok {
+		acc = &accumulatedChoice{}
+		a.choices[choice.Index] = acc
+	}
+
+	delta := choice.Delta
+	if delta.Role != "" {
+		acc.role = delta.Role
+	}
+	acc.content.WriteString(delta.Content)
+	acc.reasoningContent.WriteString(delta.ReasoningContent)
+	acc.refusal.WriteString(delta.Refusal)
+	if delta.FunctionCall != nil {
+		if acc.functionCall == nil {
+			acc.functionCall = &FunctionCall{}
+		}
+		acc.functionCall.Name += delta.FunctionCall.Name
+		acc.functionCall.Arguments += delta.FunctionCall.Arguments
+	}
+	for _, toolCall := range delta.ToolCalls {
+		acc.addToolCall(toolCall)
+	}
+	if choice.FinishReason != "" && choice.FinishReason != FinishReasonNull {
+		acc.finishReason = choice.FinishReason
+	}
+	if choice.Logprobs != nil {
+		acc.hasLogProbs = true
+		for _, logprob := range choice.Logprobs.Content {
+			acc.logProbs = append(acc.logProbs, convertTokenLogprob(logprob))
+		}
+	}
+	if choice.ContentFilterResults != (ContentFilterResults{}) {
+		acc.contentFilterResults = choice.ContentFilterResults
+	}
+}
+
+// addToolCall merges a tool call delta with the call of the same index.
+// Deltas without an index continue the last call unless they carry a new ID.
+func (acc *accumulatedChoice) addToolCall(delta ToolCall) {
+	var call *accumulatedToolCall
+	switch {
+	case delta.Index != nil:
+		for _, c := range acc.toolCalls {
+			if c.index == *delta.Index {
+				call = c
+				break
+			}
+		}
+		if call == nil {
+			call = &accumulatedToolCall{index: *delta.Index}
+			acc.toolCalls = append(acc.toolCalls, call)
+		}
+	case len(acc.toolCalls) > 0 && (delta.ID == "" || delta.ID == acc.toolCalls[len(acc.toolCalls)-1].id):
+		call = acc.toolCalls[len(acc.toolCalls)-1]
+	default:
+		call = &accumulatedToolCall{index: len(acc.toolCalls)}
+		acc.toolCalls = append(acc.toolCalls, call)
+	}
+
+	if delta.ID != "" {
+		call.id = delta.ID
+	}
+	if delta.Type != "" {
+		call.toolType = delta.Type
+	}
+	call.name.WriteString(delta.Function.Name)
+	call.arguments.WriteString(delta.Function.Arguments)
+}
+
+func convertTokenLogprob(logprob ChatCompletionTokenLogprob) LogProb {
+	converted := LogProb{
+		Token:   logprob.Token,
+		LogProb: logprob.Logprob,
+		Bytes:   int64sToBytes(logprob.Bytes),
+	}
+	for _, top := range logprob.TopLogprobs {
+		converted.TopLogProbs = append(converted.TopLogProbs, TopLogProbs{
+			Token:   top.Token,
+			LogProb: top.Logprob,
+			Bytes:   int64sToBytes(top.Bytes),
+		})
+	}
+	return converted
+}
+
+func int64sToBytes(values []int64) []byte {
+	if values == nil {
+		return nil
+	}
+	b := make([]byte, len(values))
+	for i, v := range values {
+		b[i] = byte(v)
+	}
+	return b
+}
+
+// Response returns the response accumulated so far. Choices are sorted by
+// index and tool calls by their index within the choice.
+func (a *ChatCompletionAccumulator) Response() ChatCompletionResponse {
+	response := ChatCompletionResponse{
+		ID:                  a.id,
+		Object:              chatCompletionObject,
+		Created:             a.created,
+		Model:               a.model,
+		SystemFingerprint:   a.systemFingerprint,
+		PromptFilterResults: append([]PromptFilterResult(nil), a.promptFilterResults...),
+		Choices:             make([]ChatCompletionChoice, 0, len(a.choices)),
+	}
+	if a.usage != nil {
+		response.Usage = *a.usage
+	}
+
+	indexes := make([]int, 0, len(a.choices))
+	for index := range a.choices {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		response.Choices = append(response.Choices, a.choices[index].choice(index))
+	}
+	return response
+}
+
+func (acc *accumulatedChoice) choice(index int) ChatCompletionChoice {
+	role := acc.role
+	if role == "" {
+		role = ChatMessageRoleAssistant
+	}
+	choice := ChatCompletionChoice{
+		Index: index,
+		Message: ChatCompletionMessage{
+			Role:             role,
+			Content:          acc.content.String(),
+			Refusal:          acc.refusal.String(),
+			ReasoningContent: acc.reasoningContent.String(),
+		},
+		FinishReason:         acc.finishReason,
+		ContentFilterResults: acc.contentFilterResults,
+	}
+	if acc.functionCall != nil {
+		functionCall := *acc.functionCall
+		choice.Message.FunctionCall = &functionCall
+	}
+	if len(acc.toolCalls) > 0 {
+		calls := make([]*accumulatedToolCall, len(acc.toolCalls))
+		copy(calls, acc.toolCalls)
+		sort.SliceStable(calls, func(i, j int) bool { return calls[i].index < calls[j].index })
+		for _, call := range calls {
+			toolType := call.toolType
+			if toolType == "" {
+				toolType = ToolTypeFunction
+			}
+			choice.Message.ToolCalls = append(choice.Message.ToolCalls, ToolCall{
+				ID:   call.id,
+				Type: toolType,
+				Function: FunctionCall{
+					Name:      call.name.String(),
+					Arguments: call.arguments.String(),
+				},
+			})
+		}
+	}
+	if acc.hasLogProbs {
+		choice.LogProbs = &LogProbs{Content: append([]LogProb(nil), acc.logProbs...)}
+	}
+	return choice
+}
+
+// Accumulate reads the remainder of the stream and returns the merged
+// response. On error the response received until then is returned with it.
+func (stream *ChatCompletionStream) Accumulate() (response ChatCompletionResponse, err error) {
+	acc := NewChatCompletionAccumulator()
+	for {
+		var chunk ChatCompletionStreamResponse
+		chunk, err = stream.Recv()
+		if err != nil {
+			break
+		}
+		acc.Add(chunk)
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	response = acc.Response()
+	response.httpHeader = stream.httpHeader
+	return response, err
+}
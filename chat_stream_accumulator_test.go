@@ -0,0 +1,162 @@
+package giteeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestChatCompletionAccumulatorMultipleChoices(t *testing.T) {
+	chunks := []giteeai.ChatCompletionStreamResponse{
+		{ID: "chatcmpl-1", Created: 42, Model: "qwen", Choices: []giteeai.ChatCompletionStreamChoice{
+			{Index: 1, Delta: giteeai.ChatCompletionStreamChoiceDelta{Role: "assistant", ReasoningContent: "Think"}},
+			{Index: 0, Delta: giteeai.ChatCompletionStreamChoiceDelta{Role: "assistant", Content: "Hel"}},
+		}},
+		{ID: "chatcmpl-1", Choices: []giteeai.ChatCompletionStreamChoice{
+			{Index: 0, Delta: giteeai.ChatCompletionStreamChoiceDelta{Content: "lo"}, FinishReason: giteeai.FinishReasonStop},
+			{Index: 1, Delta: giteeai.ChatCompletionStreamChoiceDelta{ReasoningContent: "ing", Refusal: "No"}},
+		}},
+		{ID: "chatcmpl-1", Choices: []giteeai.ChatCompletionStreamChoice{
+			{Index: 1, Delta: giteeai.ChatCompletionStreamChoiceDelta{Refusal: "pe"}, FinishReason: giteeai.FinishReasonLength},
+		}},
+		{ID: "chatcmpl-1", Usage: &giteeai.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8}},
+	}
+
+	acc := giteeai.NewChatCompletionAccumulator()
+	for _, chunk := range chunks {
+		acc.Add(chunk)
+	}
+
+	expected := giteeai.ChatCompletionResponse{
+		ID:      "chatcmpl-1",
+		Object:  "chat.completion",
+		Created: 42,
+		Model:   "qwen",
+		Choices: []giteeai.ChatCompletionChoice{
+			{
+				Index:        0,
+				Message:      giteeai.ChatCompletionMessage{Role: "assistant", Content: "Hello"},
+				FinishReason: giteeai.FinishReasonStop,
+			},
+			{
+				Index: 1,
+				Message: giteeai.ChatCompletionMessage{
+					Role:             "assistant",
+					ReasoningContent: "Thinking",
+					Refusal:          "Nope",
+				},
+				FinishReason: giteeai.FinishReasonLength,
+			},
+		},
+		Usage: giteeai.Usage{PromptTokens: 3, CompletionTokens: 5, TotalTokens: 8},
+	}
+	if got := acc.Response(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected response\n got: %+v\nwant: %+v", got, expected)
+	}
+}
+
+func TestChatCompletionAccumulatorParallelToolCalls(t *testing.T) {
+	acc := giteeai.NewChatCompletionAccumulator()
+	deltas := []giteeai.ToolCall{
+		{Index: intPtr(0), ID: "call_a", Type: giteeai.ToolTypeFunction, Function: giteeai.FunctionCall{Name: "weather"}},
+		{Index: intPtr(1), ID: "call_b", Type: giteeai.ToolTypeFunction, Function: giteeai.FunctionCall{Name: "time"}},
+		{Index: intPtr(1), Function: giteeai.FunctionCall{Arguments: `{"tz":`}},
+		{Index: intPtr(0), Function: giteeai.FunctionCall{Arguments: `{"city":`}},
+		{Index: intPtr(0), Function: giteeai.FunctionCall{Arguments: `"Paris"}`}},
+		{Index: intPtr(1), Function: giteeai.FunctionCall{Arguments: `"UTC"}`}},
+	}
+	for _, delta := range deltas {
+		acc.Add(giteeai.ChatCompletionStreamResponse{Choices: []giteeai.ChatCompletionStreamChoice{
+			{Delta: giteeai.ChatCompletionStreamChoiceDelta{ToolCalls: []giteeai.ToolCall{delta}}},
+		}})
+
+		// Intermediate responses must stay consistent while the stream is consumed.
+		if calls := acc.Response().Choices[0].Message.ToolCalls; len(calls) == 0 || calls[0].ID != "call_a" {
+			t.Fatalf("unexpected intermediate tool calls %+v", calls)
+		}
+	}
+	acc.Add(giteeai.ChatCompletionStreamResponse{Choices: []giteeai.ChatCompletionStreamChoice{
+		{FinishReason: giteeai.FinishReasonToolCalls},
+	}})
+
+	choice := acc.Response().Choices[0]
+	expected := []giteeai.ToolCall{
+		{ID: "call_a", Type: giteeai.ToolTypeFunction, Function: giteeai.FunctionCall{
+			Name: "weather", Arguments: `{"city":"Paris"}`,
+		}},
+		{ID: "call_b", Type: giteeai.ToolTypeFunction, Function: giteeai.FunctionCall{
+			Name: "time", Arguments: `{"tz":"UTC"}`,
+		}},
+	}
+	if !reflect.DeepEqual(choice.Message.ToolCalls, expected) || choice.FinishReason != giteeai.FinishReasonToolCalls {
+		t.Fatalf("unexpected choice %+v", choice)
+	}
+	for _, call := range choice.Message.ToolCalls {
+		if !json.Valid([]byte(call.Function.Arguments)) {
+			t.Fatalf("arguments of %s are not valid JSON: %s", call.ID, call.Function.Arguments)
+		}
+	}
+}
+
+func TestChatCompletionAccumulatorLogprobs(t *testing.T) {
+	acc := giteeai.NewChatCompletionAccumulator()
+	for _, token := range []string{"Hi", "!"} {
+		acc.Add(giteeai.ChatCompletionStreamResponse{Choices: []giteeai.ChatCompletionStreamChoice{{
+			Delta: giteeai.ChatCompletionStreamChoiceDelta{Content: token},
+			Logprobs: &giteeai.ChatCompletionStreamChoiceLogprobs{Content: []giteeai.ChatCompletionTokenLogprob{{
+				Token:       token,
+				Logprob:     -0.5,
+				Bytes:       []int64{int64(token[0])},
+				TopLogprobs: []giteeai.ChatCompletionTokenLogprobTopLogprob{{Token: token, Logprob: -0.5}},
+			}}},
+		}}})
+	}
+
+	logprobs := acc.Response().Choices[0].LogProbs
+	if logprobs == nil || len(logprobs.Content) != 2 {
+		t.Fatalf("expected two logprobs, got %+v", logprobs)
+	}
+	if got := logprobs.Content[1]; got.Token != "!" || got.LogProb != -0.5 || string(got.Bytes) != "!" ||
+		len(got.TopLogProbs) != 1 || got.TopLogProbs[0].Token != "!" {
+		t.Fatalf("unexpected logprob %+v", got)
+	}
+}
+
+func TestChatCompletionStreamAccumulate(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("x-request-id", "req-1")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"model\":\"qwen\","+
+			"\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	response, err := stream.Accumulate()
+	checks.NoError(t, err, "Accumulate error")
+	if response.ID != "1" || response.Model != "qwen" || len(response.Choices) != 1 ||
+		response.Choices[0].Message.Content != "Hi" || response.Choices[0].FinishReason != giteeai.FinishReasonStop {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	if response.Header().Get("x-request-id") != "req-1" {
+		t.Fatal("expected the response headers of the stream")
+	}
+}
@@ -0,0 +1,173 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ChatCompletionStreamOption configures CreateChatCompletionStream.
+type ChatCompletionStreamOption func(*chatCompletionStreamOptions)
+
+type chatCompletionStreamOptions struct {
+	maxReconnects int
+	onReconnect   func(StreamReconnect)
+	continuation  func(ChatCompletionRequest, ChatCompletionMessage) ChatCompletionRequest
+}
+
+// StreamReconnect describes a reconnection of a resumable stream, reported
+// before the continuation request is sent.
+type StreamReconnect struct {
+	// Attempt is the 1-based number of the reconnection.
+	Attempt int
+	// Err is the error that interrupted the stream.
+	Err error
+	// Partial is the reply received before the interruption.
+	Partial ChatCompletionMessage
+	// Delay is the time waited before reconnecting, as requested by the
+	// server with the retry field.
+	Delay time.Duration
+}
+
+// WithStreamResume makes the stream resumable: when it is interrupted by a
+// transport error, or ends before a finish reason, the request is sent again
+// up to maxReconnects times with the partial reply as a prefix to continue.
+// The chunks of the new stream are received from the same stream, with the
+// ID and creation time of the first one.
+//
+// Streams with several choices or with tool calls are not resumed.
+func WithStreamResume(maxReconnects int) ChatCompletionStreamOption {
+	return func(o *chatCompletionStreamOptions) {
+		o.maxReconnects = maxReconnects
+	}
+}
+
+// WithReconnectCallback sets a function called before every reconnection of
+// a resumable stream.
+func WithReconnectCallback(fn func(StreamReconnect)) ChatCompletionStreamOption {
+	return func(o *chatCompletionStreamOptions) {
+		o.onReconnect = fn
+	}
+}
+
+// WithContinuation replaces how the request continuing an interrupted stream
+// is built from the original request and the partial reply. By default the
+// partial reply is appended as an assistant message with Prefix set.
+func WithContinuation(
+	fn func(request ChatCompletionRequest, partial ChatCompletionMessage) ChatCompletionRequest,
+) ChatCompletionStreamOption {
+	return func(o *chatCompletionStreamOptions) {
+		o.continuation = fn
+	}
+}
+
+// continueWithPrefix appends the partial reply to the request as a prefix.
+func continueWithPrefix(request ChatCompletionRequest, partial ChatCompletionMessage) ChatCompletionRequest {
+	request.Messages = append(append([]ChatCompletionMessage(nil), request.Messages...), ChatCompletionMessage{
+		Role:             ChatMessageRoleAssistant,
+		Content:          partial.Content,
+		ReasoningContent: partial.ReasoningContent,
+		Prefix:           true,
+	})
+	return request
+}
+
+// streamResumer reconnects an interrupted chat completion stream. As the
+// first observer of the stream, it accumulates the reply and rewrites the
+// chunks of the continuations.
+type streamResumer struct {
+	client  *Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	request ChatCompletionRequest
+	options chatCompletionStreamOptions
+
+	stream      *streamReader[ChatCompletionStreamResponse]
+	accumulator ChatCompletionAccumulator
+	id          string
+	created     int64
+	finished    bool
+	attempts    int
+}
+
+func (r *streamResumer) OnFirstByte() {}
+
+func (r *streamResumer) OnChunk(chunk any) {
+	response, ok := chunk.(*ChatCompletionStreamResponse)
+	if !ok {
+		return
+	}
+	if r.id == "" {
+		r.id, r.created = response.ID, response.Created
+	} else {
+		response.ID, response.Created = r.id, r.created
+	}
+	r.accumulator.Add(*response)
+	for _, choice := range response.Choices {
+		if choice.FinishReason != "" {
+			r.finished = true
+		}
+	}
+}
+
+// OnFinish cancels a reconnection in progress when the stream is closed.
+func (r *streamResumer) OnFinish(error) {
+	r.cancel()
+}
+
+// reconnect sends the continuation request for the interrupted stream.
+func (r *streamResumer) reconnect(err error) (*streamReader[ChatCompletionStreamResponse], error) {
+	if !r.resumable(err) {
+		return nil, nil
+	}
+	choices := r.accumulator.Response().Choices
+	if len(choices) > 1 || len(choices) == 1 && len(choices[0].Message.ToolCalls) > 0 {
+		return nil, nil
+	}
+	var partial ChatCompletionMessage
+	if len(choices) == 1 {
+		partial = choices[0].Message
+	}
+
+	r.attempts++
+	reconnect := StreamReconnect{Attempt: r.attempts, Err: err, Partial: partial, Delay: r.stream.RetryInterval()}
+	if r.options.onReconnect != nil {
+		r.options.onReconnect(reconnect)
+	}
+	if reconnect.Delay > 0 {
+		timer := time.NewTimer(reconnect.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.ctx.Done():
+			return nil, r.ctx.Err()
+		}
+	}
+
+	request := r.request
+	if len(partial.Content) > 0 || len(partial.ReasoningContent) > 0 {
+		request = r.options.continuation(request, partial)
+	}
+	return r.client.openChatCompletionStream(r.ctx, request, r.stream.LastEventID())
+}
+
+// resumable tells whether the error interrupted a stream that the server
+// did not finish.
+func (r *streamResumer) resumable(err error) bool {
+	if r.attempts >= r.options.maxReconnects || r.ctx.Err() != nil {
+		return false
+	}
+	var apiErr *APIError
+	switch {
+	case errors.Is(err, io.EOF):
+		return !r.finished
+	case errors.As(err, &apiErr),
+		errors.Is(err, ErrTooManyEmptyStreamMessages),
+		errors.Is(err, ErrStreamClosed),
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded):
+		return false
+	}
+	return true
+}
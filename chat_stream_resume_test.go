@@ -0,0 +1,146 @@
+package giteeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// interruptedHandler answers the successive requests with the given
+// contents, interrupting all the streams but the last one, which finishes.
+func interruptedHandler(requests *[]giteeai.ChatCompletionRequest, lastEventIDs *[]string,
+	contents ...[]string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request giteeai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		*requests = append(*requests, request)
+		*lastEventIDs = append(*lastEventIDs, r.Header.Get("Last-Event-ID"))
+		attempt := len(*requests)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, content := range contents[attempt-1] {
+			fmt.Fprintf(w, "id: %d.%d\ndata: {\"id\":\"chat-%d\",\"created\":%d,"+
+				"\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", attempt, i, attempt, attempt, content)
+		}
+		w.(http.Flusher).Flush()
+		if attempt < len(contents) {
+			panic(http.ErrAbortHandler)
+		}
+		fmt.Fprintf(w, "data: {\"id\":\"chat-%d\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n",
+			attempt)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+}
+
+func TestChatCompletionStreamResume(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	var (
+		requests     []giteeai.ChatCompletionRequest
+		lastEventIDs []string
+	)
+	server.RegisterHandler("/v1/chat/completions", interruptedHandler(&requests, &lastEventIDs,
+		[]string{"Hel", "lo"}, []string{}, []string{", world"}))
+
+	var reconnects []giteeai.StreamReconnect
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model:    giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "Greet"}},
+	}, giteeai.WithStreamResume(2), giteeai.WithReconnectCallback(func(reconnect giteeai.StreamReconnect) {
+		reconnects = append(reconnects, reconnect)
+	}))
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	var content string
+	for {
+		chunk, recvErr := stream.Recv()
+		if errors.Is(recvErr, io.EOF) {
+			break
+		}
+		checks.NoError(t, recvErr, "Recv error")
+		if chunk.ID != "chat-1" || chunk.Created != 1 {
+			t.Errorf("the chunk %+v is not spliced", chunk)
+		}
+		content += chunk.Choices[0].Delta.Content
+	}
+	if content != "Hello, world" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	if len(reconnects) != 2 || reconnects[0].Attempt != 1 || reconnects[0].Err == nil ||
+		reconnects[1].Partial.Content != "Hello" {
+		t.Fatalf("unexpected reconnects %+v", reconnects)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	last := requests[2].Messages[len(requests[2].Messages)-1]
+	if len(requests[2].Messages) != 2 || last.Role != giteeai.ChatMessageRoleAssistant ||
+		last.Content != "Hello" || !last.Prefix {
+		t.Errorf("unexpected continuation %+v", requests[2].Messages)
+	}
+	if lastEventIDs[0] != "" || lastEventIDs[1] != "1.1" || lastEventIDs[2] != "1.1" {
+		t.Errorf("unexpected Last-Event-ID headers %q", lastEventIDs)
+	}
+}
+
+func TestChatCompletionStreamResumeLimit(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	var (
+		requests     []giteeai.ChatCompletionRequest
+		lastEventIDs []string
+	)
+	server.RegisterHandler("/v1/chat/completions", interruptedHandler(&requests, &lastEventIDs,
+		[]string{"a"}, []string{"b"}, []string{"c"}))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model: giteeai.Qwen2_7B_Instruct,
+	}, giteeai.WithStreamResume(1), giteeai.WithContinuation(
+		func(request giteeai.ChatCompletionRequest, partial giteeai.ChatCompletionMessage) giteeai.ChatCompletionRequest {
+			request.Messages = append(request.Messages, giteeai.ChatCompletionMessage{
+				Role:    giteeai.ChatMessageRoleUser,
+				Content: "Continue after: " + partial.Content,
+			})
+			return request
+		}))
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	_, err = stream.Accumulate()
+	checks.HasError(t, err, "the stream should fail after the last reconnection")
+	if len(requests) != 2 || requests[1].Messages[0].Content != "Continue after: a" {
+		t.Errorf("unexpected requests %+v", requests)
+	}
+}
+
+func TestChatCompletionStreamResumeErrors(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	var requests int
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: error\ndata: {\"message\":\"overloaded\"}\n\n")
+	})
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model: giteeai.Qwen2_7B_Instruct,
+	}, giteeai.WithStreamResume(3))
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	_, err = stream.Recv()
+	var apiErr *giteeai.APIError
+	if !errors.As(err, &apiErr) || requests != 1 {
+		t.Errorf("API errors should not be resumed: %v after %d requests", err, requests)
+	}
+}
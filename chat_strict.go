@@ -0,0 +1,67 @@
+package giteeai
+
+import (
+	"fmt"
+
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+// strictSchemas rewrites the schemas of the strict tools and response format
+// of request with jsonschema.Strict. Only jsonschema.Definition schemas are
+// rewritten; other schemas, such as json.RawMessage, are sent as is. The
+// request given by the caller is not modified.
+func strictSchemas(request ChatCompletionRequest) (ChatCompletionRequest, error) {
+	copied := false
+	for i, tool := range request.Tools {
+		if tool.Function == nil || !tool.Function.Strict {
+			continue
+		}
+		schema, ok := definitionOf(tool.Function.Parameters)
+		if !ok {
+			continue
+		}
+		strict, err := jsonschema.Strict(schema)
+		if err != nil {
+			return request, fmt.Errorf("strict schema of tool %s: %w", tool.Function.Name, err)
+		}
+
+		if !copied {
+			request.Tools = append([]Tool(nil), request.Tools...)
+			copied = true
+		}
+		function := *tool.Function
+		function.Parameters = strict
+		request.Tools[i].Function = &function
+	}
+
+	format := request.ResponseFormat
+	if format == nil || format.JSONSchema == nil || !format.JSONSchema.Strict {
+		return request, nil
+	}
+	schema, ok := definitionOf(format.JSONSchema.Schema)
+	if !ok {
+		return request, nil
+	}
+	strict, err := jsonschema.Strict(schema)
+	if err != nil {
+		return request, fmt.Errorf("strict schema of response format %s: %w", format.JSONSchema.Name, err)
+	}
+	jsonSchema := *format.JSONSchema
+	jsonSchema.Schema = &strict
+	responseFormat := *format
+	responseFormat.JSONSchema = &jsonSchema
+	request.ResponseFormat = &responseFormat
+	return request, nil
+}
+
+func definitionOf(schema any) (jsonschema.Definition, bool) {
+	switch s := schema.(type) {
+	case jsonschema.Definition:
+		return s, true
+	case *jsonschema.Definition:
+		if s != nil {
+			return *s, true
+		}
+	}
+	return jsonschema.Definition{}, false
+}
@@ -0,0 +1,102 @@
+package giteeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+func TestCreateChatCompletionStrictSchemas(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var body map[string]any
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_ = json.NewEncoder(w).Encode(giteeai.ChatCompletionResponse{})
+	})
+
+	parameters := jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"city": {Type: jsonschema.String},
+			"unit": {Type: jsonschema.String},
+		},
+		Required: []string{"city"},
+	}
+	request := giteeai.ChatCompletionRequest{
+		Tools: []giteeai.Tool{
+			{Type: giteeai.ToolTypeFunction, Function: &giteeai.FunctionDefinition{
+				Name: "strict", Strict: true, Parameters: parameters,
+			}},
+			{Type: giteeai.ToolTypeFunction, Function: &giteeai.FunctionDefinition{
+				Name: "loose", Parameters: parameters,
+			}},
+		},
+		ResponseFormat: &giteeai.ChatCompletionResponseFormat{
+			Type: giteeai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &giteeai.ChatCompletionResponseFormatJSONSchema{
+				Name: "weather", Schema: &parameters, Strict: true,
+			},
+		},
+	}
+	_, err := client.CreateChatCompletion(context.Background(), request)
+	checks.NoError(t, err, "CreateChatCompletion error")
+
+	strict := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"city": map[string]any{"type": "string"},
+			"unit": map[string]any{"anyOf": []any{map[string]any{"type": "string"}, map[string]any{"type": "null"}}},
+		},
+		"required":             []any{"city", "unit"},
+		"additionalProperties": false,
+	}
+	tools := body["tools"].([]any)
+	if got := tools[0].(map[string]any)["function"].(map[string]any)["parameters"]; !reflect.DeepEqual(got, strict) {
+		t.Errorf("unexpected strict tool parameters %v", got)
+	}
+	if got := tools[1].(map[string]any)["function"].(map[string]any)["parameters"]; reflect.DeepEqual(got, strict) {
+		t.Error("expected the parameters of a non-strict tool to be sent as is")
+	}
+	format := body["response_format"].(map[string]any)["json_schema"].(map[string]any)
+	if !reflect.DeepEqual(format["schema"], strict) {
+		t.Errorf("unexpected strict response format schema %v", format["schema"])
+	}
+
+	if request.Tools[0].Function.Parameters.(jsonschema.Definition).AdditionalProperties != nil ||
+		request.ResponseFormat.JSONSchema.Schema != &parameters {
+		t.Error("expected the request of the caller to be left unchanged")
+	}
+}
+
+func TestCreateChatCompletionStrictSchemaError(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(http.ResponseWriter, *http.Request) {
+		t.Error("the request should not be sent")
+	})
+
+	_, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Tools: []giteeai.Tool{{Type: giteeai.ToolTypeFunction, Function: &giteeai.FunctionDefinition{
+			Name:   "labels",
+			Strict: true,
+			Parameters: jsonschema.Definition{
+				Type:                 jsonschema.Object,
+				AdditionalProperties: jsonschema.Definition{Type: jsonschema.String},
+			},
+		}}},
+	})
+	var errs jsonschema.StrictErrors
+	if !errors.As(err, &errs) || errs[0].Keyword != "additionalProperties" {
+		t.Fatalf("expected a strict schema error, got %v", err)
+	}
+}
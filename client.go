@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	utils "github.com/edmondfrank/go-giteeai/internal"
 )
@@ -17,6 +18,7 @@ type Client struct {
 
 	requestBuilder    utils.RequestBuilder
 	createFormBuilder func(io.Writer) utils.FormBuilder
+	handler           Doer
 }
 
 type Response interface {
@@ -51,28 +53,56 @@ func NewClient(authToken string) *Client {
 
 // NewClientWithConfig creates new GiteeAI API client for specified config.
 func NewClientWithConfig(config ClientConfig) *Client {
-	return &Client{
+	c := &Client{
 		config:         config,
 		requestBuilder: utils.NewRequestBuilder(),
 		createFormBuilder: func(body io.Writer) utils.FormBuilder {
 			return utils.NewFormBuilder(body)
 		},
 	}
+	c.handler = chainMiddlewares(DoerFunc(c.roundTrip), c.middlewares()...)
+	return c
 }
 
 type requestOptions struct {
-	body   any
-	header http.Header
+	body      any
+	header    http.Header
+	operation Operation
 }
 
 type requestOption func(*requestOptions)
 
+// requestInfoKey is the context key under which newRequest records the
+// details of a call that are lost once the body has been marshalled.
+type requestInfoKey struct{}
+
+type requestInfo struct {
+	body      any
+	operation Operation
+	// path and endpoint are set by doRouted when an EndpointPool is used.
+	path     string
+	endpoint *endpointState
+}
+
+func requestInfoFromContext(ctx context.Context) *requestInfo {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info
+	}
+	return &requestInfo{}
+}
+
 func withBody(body any) requestOption {
 	return func(args *requestOptions) {
 		args.body = body
 	}
 }
 
+func withOperation(op Operation) requestOption {
+	return func(args *requestOptions) {
+		args.operation = op
+	}
+}
+
 func withContentType(contentType string) requestOption {
 	return func(args *requestOptions) {
 		args.header.Set("Content-Type", contentType)
@@ -94,11 +124,17 @@ func (c *Client) newRequest(ctx context.Context, method, url string, setters ...
 	for _, setter := range setters {
 		setter(args)
 	}
+	ctx = context.WithValue(ctx, requestInfoKey{}, &requestInfo{
+		body:      args.body,
+		operation: args.operation,
+	})
 	req, err := c.requestBuilder.Build(ctx, method, url, args.body, args.header)
 	if err != nil {
 		return nil, err
 	}
-	c.setCommonHeaders(req)
+	if err = c.setCommonHeaders(req); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
@@ -112,37 +148,27 @@ func (c *Client) sendRequest(req *http.Request, v Response) error {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
-	res, err := c.config.HTTPClient.Do(req)
-	if err != nil {
-		return err
+	call := newCall(req, v)
+	err := c.handler.Do(call)
+	if call.HTTPResponse != nil && v != nil {
+		v.SetHeader(call.HTTPResponse.Header)
 	}
-
-	defer res.Body.Close()
-
-	if v != nil {
-		v.SetHeader(res.Header)
-	}
-
-	if isFailureStatusCode(res) {
-		return c.handleErrorResp(res)
-	}
-
-	return decodeResponse(res.Body, v)
+	return err
 }
 
 func (c *Client) sendRequestRaw(req *http.Request) (response RawResponse, err error) {
-	resp, err := c.config.HTTPClient.Do(req) //nolint:bodyclose // body should be closed by outer function
-	if err != nil {
-		return
+	call := newCall(req, nil)
+	call.Raw = true
+	err = c.handler.Do(call)
+	if err == nil && call.HTTPResponse == nil {
+		err = ErrMiddlewareNoResponse
 	}
-
-	if isFailureStatusCode(resp) {
-		err = c.handleErrorResp(resp)
+	if err != nil {
 		return
 	}
 
-	response.SetHeader(resp.Header)
-	response.ReadCloser = resp.Body
+	response.SetHeader(call.HTTPResponse.Header)
+	response.ReadCloser = call.HTTPResponse.Body
 	return
 }
 
@@ -152,13 +178,16 @@ func sendRequestStream[T streamable](client *Client, req *http.Request) (*stream
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Connection", "keep-alive")
 
-	resp, err := client.config.HTTPClient.Do(req) //nolint:bodyclose // body is closed in stream.Close()
+	call := newCall(req, nil)
+	call.Stream = true
+	err := client.handler.Do(call)
+	if err == nil && call.HTTPResponse == nil {
+		err = ErrMiddlewareNoResponse
+	}
 	if err != nil {
 		return new(streamReader[T]), err
 	}
-	if isFailureStatusCode(resp) {
-		return new(streamReader[T]), client.handleErrorResp(resp)
-	}
+	resp := call.HTTPResponse
 	return &streamReader[T]{
 		emptyMessagesLimit: client.config.EmptyMessagesLimit,
 		reader:             bufio.NewReader(resp.Body),
@@ -166,13 +195,96 @@ func sendRequestStream[T streamable](client *Client, req *http.Request) (*stream
 		errAccumulator:     utils.NewErrorAccumulator(),
 		unmarshaler:        &utils.JSONUnmarshaler{},
 		httpHeader:         httpHeader(resp.Header),
+		observers:          call.streamObservers,
 	}, nil
 }
 
-func (c *Client) setCommonHeaders(req *http.Request) {
-	if c.config.authToken != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.authToken))
+// do sends req with the configured HTTPClient, retrying according to the
+// client's RetryPolicy. A failure status code is converted into an error; in
+// that case the last response is returned as well, with its body consumed.
+// Requests rejected because of their API key are sent again with the next
+// key of the CredentialProvider without counting as a retry.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	policy := c.config.RetryPolicy
+	maxAttempts := policy.maxAttempts()
+	_, canRotate := c.config.CredentialProvider.(CredentialRotator)
+	if maxAttempts > 1 || canRotate || c.config.EndpointPool.canFailover() {
+		if err := rewindableBody(req); err != nil {
+			return nil, err
+		}
 	}
+
+	usedCredentials := make(map[string]bool)
+	rotations := 0
+	for send := 1; ; send++ {
+		attemptReq, err := attemptRequest(req, send)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.doRouted(attemptReq)
+		var delay time.Duration
+		if err == nil {
+			if !isFailureStatusCode(resp) {
+				return resp, nil
+			}
+			err = c.handleErrorResp(resp)
+			resp.Body.Close()
+			delay = retryAfter(resp.Header)
+		}
+
+		if canRotate && c.rotateCredential(req, err, usedCredentials) {
+			rotations++
+			continue
+		}
+		attempt := send - rotations
+		if attempt >= maxAttempts || !policy.Retryable(attempt, err) {
+			return resp, err
+		}
+		if policy.MaxRetryAfter > 0 && delay > policy.MaxRetryAfter {
+			return resp, err
+		}
+		if backoff := policy.Backoff(attempt); backoff > delay {
+			delay = backoff
+		}
+		if sleepErr := sleepContext(req.Context(), delay); sleepErr != nil {
+			return resp, err
+		}
+	}
+}
+
+// doLimited performs a single attempt, waiting for the rate limiter budget first.
+func (c *Client) doLimited(req *http.Request) (*http.Response, error) {
+	limiter := c.config.RateLimiter
+	if limiter == nil {
+		return c.config.HTTPClient.Do(req)
+	}
+
+	tokens := limiter.estimate(requestInfoFromContext(req.Context()).body)
+	if err := limiter.Wait(req.Context(), tokens); err != nil {
+		return nil, err
+	}
+	resp, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		limiter.Done(tokens, 0, nil)
+		return nil, err
+	}
+	limiter.Done(tokens, resp.StatusCode, resp.Header)
+	return resp, nil
+}
+
+func (c *Client) setCommonHeaders(req *http.Request) error {
+	token := c.config.authToken
+	if provider := c.config.CredentialProvider; provider != nil {
+		var err error
+		if token, err = provider.Token(req.Context()); err != nil {
+			return err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	return nil
 }
 
 func isFailureStatusCode(resp *http.Response) bool {
@@ -0,0 +1,64 @@
+// Command jsonschemagen generates Go types from a JSON schema file, to be
+// used with go generate:
+//
+//	//go:generate go run github.com/edmondfrank/go-giteeai/cmd/jsonschemagen -type WeatherArgs -in weather.json
+//
+// The package defaults to $GOPACKAGE and the output file to the input file
+// name with a .go extension, e.g. weather.json generates weather.go.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+func main() {
+	in := flag.String("in", "", "the JSON schema file")
+	out := flag.String("out", "", "the generated Go file, - for the standard output")
+	typeName := flag.String("type", "", "the name of the type of the root schema")
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"), "the package of the generated file")
+	flag.Parse()
+
+	if err := run(*in, *out, *typeName, *pkg); err != nil {
+		fmt.Fprintf(os.Stderr, "jsonschemagen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in, out, typeName, pkg string) error {
+	if in == "" || typeName == "" || pkg == "" {
+		return fmt.Errorf("-in, -type and -package are required")
+	}
+	content, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	var schema jsonschema.Definition
+	if err = json.Unmarshal(content, &schema); err != nil {
+		return fmt.Errorf("parsing %s: %w", in, err)
+	}
+
+	code, err := jsonschema.GenerateGoCode(schema, jsonschema.CodeOptions{
+		Package:  pkg,
+		TypeName: typeName,
+		Header:   fmt.Sprintf("Code generated by jsonschemagen from %s; DO NOT EDIT.", filepath.Base(in)),
+	})
+	if err != nil {
+		return fmt.Errorf("generating %s: %w", typeName, err)
+	}
+
+	switch out {
+	case "-":
+		_, err = os.Stdout.Write(code)
+		return err
+	case "":
+		out = strings.TrimSuffix(in, filepath.Ext(in)) + ".go"
+	}
+	return os.WriteFile(out, code, 0o644) //nolint:gosec // generated source files are world readable
+}
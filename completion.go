@@ -127,6 +127,7 @@ func (c *Client) CreateCompletion(
 		http.MethodPost,
 		c.fullURL(urlSuffix, withModel(request.Model)),
 		withBody(request),
+		withOperation(OperationCreateCompletion),
 	)
 	if err != nil {
 		return
@@ -23,6 +23,34 @@ type ClientConfig struct {
 	AssistantVersion string
 	HTTPClient       HTTPDoer
 
+	// CredentialProvider, if set, supplies the API key of every request
+	// instead of the token given to DefaultConfig. Providers implementing
+	// CredentialRotator switch to their next key when a key is rejected.
+	// The AuthToken of EndpointPool endpoints takes precedence.
+	CredentialProvider CredentialProvider
+	// RetryPolicy enables retrying transient failures. Nil disables retries.
+	RetryPolicy *RetryPolicy
+	// EndpointPool, if set, spreads requests across several backends with
+	// failover. Request URLs are rebuilt by replacing BaseURL with the base URL
+	// of the selected endpoint.
+	EndpointPool *EndpointPool
+	// RateLimiter throttles requests client-side using the rate limit headers
+	// returned by the API. It may be shared between clients. Nil disables it.
+	RateLimiter *RateLimiter
+	// Middlewares wrap every call made by the client. The first middleware is
+	// the outermost one and sees the call first.
+	Middlewares []Middleware
+	// Logger, if set, receives one structured record per call. Credentials
+	// and base64 payloads are redacted from the record.
+	Logger     Logger
+	LogOptions LogOptions
+	// Tracer, if set, creates a span for every call, including the whole
+	// lifetime of streams.
+	Tracer Tracer
+	// Metrics, if set, records request counts, errors, token usage and stream
+	// latency for every call. See NewMemoryMetrics.
+	Metrics Metrics
+
 	EmptyMessagesLimit uint
 }
 
@@ -0,0 +1,463 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// conversationSummaryPrefix starts the system message holding the summary
+	// of the trimmed messages, so that it is recognized and summarized again
+	// with the next trimmed messages.
+	conversationSummaryPrefix = "Summary of the earlier conversation:\n"
+
+	defaultSummaryPrompt = "Summarize the following conversation between a user and an assistant. " +
+		"Keep the facts, decisions, names and open questions needed to continue it. Reply with the summary only."
+)
+
+// ErrContextBudgetExceeded is returned by TokenBudget when the system messages
+// and the latest message alone exceed the budget.
+var ErrContextBudgetExceeded = errors.New("the latest messages exceed the token budget")
+
+// TrimStrategy shortens the history of a Conversation before it is sent.
+//
+// The first messages of the history with the system or developer role are
+// pinned: strategies keep them and drop the oldest of the other messages. An
+// assistant message and the tool or function messages answering its calls
+// are kept or dropped together, since the API rejects tool messages whose
+// call is missing.
+type TrimStrategy interface {
+	Trim(ctx context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error)
+}
+
+// TrimStrategyFunc is an adapter to allow the use of ordinary functions as
+// TrimStrategy.
+type TrimStrategyFunc func(ctx context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error)
+
+// Trim calls f(ctx, messages).
+func (f TrimStrategyFunc) Trim(ctx context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error) {
+	return f(ctx, messages)
+}
+
+// splitHistory returns the pinned system messages and the other messages
+// grouped into blocks that are trimmed as a whole.
+func splitHistory(messages []ChatCompletionMessage) (pinned []ChatCompletionMessage, blocks [][]ChatCompletionMessage) {
+	i := 0
+	for i < len(messages) && isSystemMessage(messages[i]) {
+		i++
+	}
+	pinned = messages[:i]
+	for _, message := range messages[i:] {
+		answer := message.Role == ChatMessageRoleTool || message.Role == ChatMessageRoleFunction
+		if answer && len(blocks) > 0 {
+			last := len(blocks) - 1
+			blocks[last] = append(blocks[last], message)
+			continue
+		}
+		blocks = append(blocks, []ChatCompletionMessage{message})
+	}
+	return pinned, blocks
+}
+
+func isSystemMessage(message ChatCompletionMessage) bool {
+	return message.Role == ChatMessageRoleSystem || message.Role == ChatMessageRoleDeveloper
+}
+
+// keepLatest returns the pinned messages followed by the latest blocks for
+// which fits returns true. fits is called with the number of messages and
+// the block considered, latest first.
+func keepLatest(
+	pinned []ChatCompletionMessage,
+	blocks [][]ChatCompletionMessage,
+	fits func(kept int, block []ChatCompletionMessage) bool,
+) []ChatCompletionMessage {
+	start, kept := len(blocks), 0
+	for start > 0 && fits(kept, blocks[start-1]) {
+		start--
+		kept += len(blocks[start])
+	}
+	trimmed := make([]ChatCompletionMessage, 0, len(pinned)+kept)
+	trimmed = append(trimmed, pinned...)
+	for _, block := range blocks[start:] {
+		trimmed = append(trimmed, block...)
+	}
+	return trimmed
+}
+
+// KeepLastMessages keeps the system messages and the last n other messages.
+// Fewer messages are kept when the n-th one answers a tool call that would
+// be dropped, but the latest message and its tool calls are always kept.
+func KeepLastMessages(n int) TrimStrategy {
+	return TrimStrategyFunc(func(_ context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error) {
+		pinned, blocks := splitHistory(messages)
+		return keepLatest(pinned, blocks, func(kept int, block []ChatCompletionMessage) bool {
+			return kept == 0 || kept+len(block) <= n
+		}), nil
+	})
+}
+
+// TokenBudget keeps the system messages and the latest other messages whose
+// tokens, counted by counter, fit in maxTokens. The budget only covers the
+// messages: it should leave room for the tools and the reply of the model.
+// ErrContextBudgetExceeded is returned when the system messages and the
+// latest message do not fit.
+func TokenBudget(counter *TokenCounter, maxTokens int) TrimStrategy {
+	return TrimStrategyFunc(func(_ context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error) {
+		pinned, blocks := splitHistory(messages)
+		tokens := counter.CountMessages(pinned)
+		trimmed := keepLatest(pinned, blocks, func(_ int, block []ChatCompletionMessage) bool {
+			blockTokens := counter.CountMessages(block)
+			if tokens+blockTokens > maxTokens {
+				return false
+			}
+			tokens += blockTokens
+			return true
+		})
+		if len(blocks) > 0 && len(trimmed) == len(pinned) {
+			return nil, fmt.Errorf("%w: %d tokens", ErrContextBudgetExceeded,
+				tokens+counter.CountMessages(blocks[len(blocks)-1]))
+		}
+		return trimmed, nil
+	})
+}
+
+// Summarizer condenses messages into a text that replaces them in the
+// history.
+type Summarizer interface {
+	Summarize(ctx context.Context, messages []ChatCompletionMessage) (string, error)
+}
+
+// SummarizerFunc is an adapter to allow the use of ordinary functions as
+// Summarizer.
+type SummarizerFunc func(ctx context.Context, messages []ChatCompletionMessage) (string, error)
+
+// Summarize calls f(ctx, messages).
+func (f SummarizerFunc) Summarize(ctx context.Context, messages []ChatCompletionMessage) (string, error) {
+	return f(ctx, messages)
+}
+
+// ChatSummarizer summarizes messages with a chat completion.
+type ChatSummarizer struct {
+	Client *Client
+	Model  string
+	// Prompt is the system message instructing the model, a generic
+	// summarization prompt by default.
+	Prompt string
+	// MaxTokens limits the length of the summary. Zero means no limit.
+	MaxTokens int
+}
+
+// NewChatSummarizer creates a ChatSummarizer with the default prompt.
+func NewChatSummarizer(client *Client, model string) *ChatSummarizer {
+	return &ChatSummarizer{Client: client, Model: model}
+}
+
+// Summarize sends the messages as a transcript to the model and returns its
+// answer.
+func (s *ChatSummarizer) Summarize(ctx context.Context, messages []ChatCompletionMessage) (string, error) {
+	prompt := s.Prompt
+	if prompt == "" {
+		prompt = defaultSummaryPrompt
+	}
+	response, err := s.Client.CreateChatCompletion(ctx, ChatCompletionRequest{
+		Model:     s.Model,
+		MaxTokens: s.MaxTokens,
+		Messages: []ChatCompletionMessage{
+			{Role: ChatMessageRoleSystem, Content: prompt},
+			{Role: ChatMessageRoleUser, Content: transcript(messages)},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(response.Choices) == 0 {
+		return "", errors.New("the summary completion has no choices")
+	}
+	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+}
+
+// transcript renders messages as "role: content" lines.
+func transcript(messages []ChatCompletionMessage) string {
+	var b strings.Builder
+	for _, message := range messages {
+		content := message.Content
+		for _, part := range message.MultiContent {
+			if part.Type == ChatMessagePartTypeText {
+				content += part.Text
+			}
+		}
+		if message.Role == ChatMessageRoleSystem && strings.HasPrefix(content, conversationSummaryPrefix) {
+			content = strings.TrimPrefix(content, conversationSummaryPrefix)
+		}
+		for _, call := range message.ToolCalls {
+			content += fmt.Sprintf("\n[call %s(%s)]", call.Function.Name, call.Function.Arguments)
+		}
+		if message.FunctionCall != nil {
+			content += fmt.Sprintf("\n[call %s(%s)]", message.FunctionCall.Name, message.FunctionCall.Arguments)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", message.Role, strings.TrimSpace(content))
+	}
+	return b.String()
+}
+
+// SummarizeOlder trims the history with window and replaces the messages it
+// drops by a system message holding their summary, placed after the system
+// messages. The summary is updated with the messages dropped by the
+// following calls. window must keep the system messages and drop the oldest
+// messages, like KeepLastMessages and TokenBudget; the summary message is
+// passed to it as a system message.
+func SummarizeOlder(summarizer Summarizer, window TrimStrategy) TrimStrategy {
+	return TrimStrategyFunc(func(ctx context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error) {
+		pinned, _ := splitHistory(messages)
+		trimmed, err := window.Trim(ctx, messages)
+		if err != nil {
+			return nil, err
+		}
+		dropped := len(messages) - len(trimmed)
+		if dropped <= 0 {
+			return trimmed, nil
+		}
+
+		if len(trimmed) < len(pinned) {
+			return nil, errors.New("the trim strategy dropped system messages")
+		}
+		kept := trimmed[len(pinned):]
+		older := messages[len(pinned) : len(pinned)+dropped]
+		if last := len(pinned) - 1; last >= 0 && strings.HasPrefix(pinned[last].Content, conversationSummaryPrefix) {
+			older = append([]ChatCompletionMessage{pinned[last]}, older...)
+			pinned = pinned[:last]
+		}
+
+		summary, err := summarizer.Summarize(ctx, older)
+		if err != nil {
+			return nil, fmt.Errorf("summarizing the conversation: %w", err)
+		}
+		result := make([]ChatCompletionMessage, 0, len(trimmed)+1)
+		result = append(result, pinned...)
+		result = append(result, ChatCompletionMessage{
+			Role:    ChatMessageRoleSystem,
+			Content: conversationSummaryPrefix + summary,
+		})
+		return append(result, kept...), nil
+	})
+}
+
+// Conversation holds the history of a chat and trims it with Strategy before
+// every completion, so that long chats stay within the context window of the
+// model. A conversation carries one exchange at a time: calls are serialized.
+type Conversation struct {
+	Client *Client
+	// Strategy trims the history before it is sent. Nil keeps every message.
+	Strategy TrimStrategy
+	// Store, when set, persists the messages of the conversation as ID. The
+	// new messages are saved by every completion and by Save.
+	Store ConversationStore
+	ID    string
+
+	mu       sync.Mutex
+	messages []ChatCompletionMessage
+	// unsaved holds the messages not saved in Store yet.
+	unsaved []StoredMessage
+}
+
+// NewConversation creates a conversation starting with messages, usually the
+// system prompt.
+func NewConversation(client *Client, strategy TrimStrategy, messages ...ChatCompletionMessage) *Conversation {
+	return &Conversation{
+		Client:   client,
+		Strategy: strategy,
+		messages: append([]ChatCompletionMessage(nil), messages...),
+	}
+}
+
+// Append adds messages to the history, e.g. the results of tool calls.
+func (c *Conversation) Append(messages ...ChatCompletionMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.append(nil, messages...)
+}
+
+// append adds messages to the history and to the messages to save. usage is
+// that of the completion which returned the messages.
+func (c *Conversation) append(usage *Usage, messages ...ChatCompletionMessage) {
+	for _, message := range messages {
+		c.messages = append(c.messages, historyMessage(message))
+	}
+	c.queue(usage, messages...)
+}
+
+// queue queues the messages to be saved in Store.
+func (c *Conversation) queue(usage *Usage, messages ...ChatCompletionMessage) {
+	if c.Store == nil {
+		return
+	}
+	for _, message := range messages {
+		c.unsaved = append(c.unsaved, StoredMessage{Message: message, Usage: usage, CreatedAt: time.Now()})
+	}
+}
+
+// Messages returns a copy of the history.
+func (c *Conversation) Messages() []ChatCompletionMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ChatCompletionMessage(nil), c.messages...)
+}
+
+// Save saves the messages appended since the last save in Store, e.g. the
+// reply of a stream.
+func (c *Conversation) Save(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.save(ctx)
+}
+
+func (c *Conversation) save(ctx context.Context) error {
+	if c.Store == nil || len(c.unsaved) == 0 {
+		return nil
+	}
+	if err := c.Store.Append(ctx, c.ID, c.unsaved...); err != nil {
+		return fmt.Errorf("saving conversation %s: %w", c.ID, err)
+	}
+	c.unsaved = nil
+	return nil
+}
+
+// Trim applies Strategy to the history.
+func (c *Conversation) Trim(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages, err := c.trim(ctx, c.messages)
+	if err != nil {
+		return err
+	}
+	c.messages = messages
+	return nil
+}
+
+func (c *Conversation) trim(ctx context.Context, messages []ChatCompletionMessage) ([]ChatCompletionMessage, error) {
+	if c.Strategy == nil {
+		return messages, nil
+	}
+	return c.Strategy.Trim(ctx, messages)
+}
+
+// prepare returns the trimmed history followed by the messages of request,
+// which are the new messages of the exchange.
+func (c *Conversation) prepare(ctx context.Context, request ChatCompletionRequest) ([]ChatCompletionMessage, error) {
+	if err := c.save(ctx); err != nil {
+		return nil, err
+	}
+	messages := make([]ChatCompletionMessage, 0, len(c.messages)+len(request.Messages))
+	messages = append(messages, c.messages...)
+	messages = append(messages, request.Messages...)
+	return c.trim(ctx, messages)
+}
+
+// commit replaces the history by the trimmed history sent with request. The
+// strategy may have dropped some of the new messages: they are saved all the
+// same, as Store keeps the whole conversation.
+func (c *Conversation) commit(request ChatCompletionRequest, newMessages []ChatCompletionMessage) {
+	c.messages = make([]ChatCompletionMessage, 0, len(request.Messages))
+	for _, message := range request.Messages {
+		c.messages = append(c.messages, historyMessage(message))
+	}
+	c.queue(nil, newMessages...)
+}
+
+// CreateChatCompletion appends the messages of request to the history, trims
+// it and sends it with the other fields of request. When the call succeeds,
+// the trimmed history and the first choice of the reply are kept and saved;
+// otherwise the history is unchanged.
+func (c *Conversation) CreateChatCompletion(
+	ctx context.Context,
+	request ChatCompletionRequest,
+) (response ChatCompletionResponse, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newMessages := request.Messages
+	if request.Messages, err = c.prepare(ctx, request); err != nil {
+		return
+	}
+	response, err = c.Client.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return
+	}
+	c.commit(request, newMessages)
+	if len(response.Choices) > 0 {
+		c.append(reportedUsage(response.Usage), response.Choices[0].Message)
+	}
+	err = c.save(ctx)
+	return
+}
+
+// CreateChatCompletionStream is the streaming counterpart of
+// CreateChatCompletion. The trimmed history is kept when the stream is
+// created, and the reply is appended when the stream is received up to its
+// end. The new messages are saved by the next completion or by Save.
+func (c *Conversation) CreateChatCompletionStream(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	opts ...ChatCompletionStreamOption,
+) (stream *ChatCompletionStream, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newMessages := request.Messages
+	if request.Messages, err = c.prepare(ctx, request); err != nil {
+		return
+	}
+	stream, err = c.Client.CreateChatCompletionStream(ctx, request, opts...)
+	if err != nil {
+		return
+	}
+	c.commit(request, newMessages)
+	stream.observers = append(stream.observers, &conversationObserver{
+		conversation: c,
+		accumulator:  NewChatCompletionAccumulator(),
+	})
+	return
+}
+
+// historyMessage returns the message as it is sent back in the history. The
+// reasoning content is dropped, as models reject it in requests.
+func historyMessage(message ChatCompletionMessage) ChatCompletionMessage {
+	message.ReasoningContent = ""
+	return message
+}
+
+// reportedUsage returns usage, or nil when the response did not report it,
+// e.g. a stream without StreamOptions.IncludeUsage.
+func reportedUsage(usage Usage) *Usage {
+	if usage.TotalTokens == 0 && usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		return nil
+	}
+	return &usage
+}
+
+// conversationObserver appends the reply of a stream to the conversation.
+type conversationObserver struct {
+	conversation *Conversation
+	accumulator  *ChatCompletionAccumulator
+}
+
+func (o *conversationObserver) OnFirstByte() {}
+
+func (o *conversationObserver) OnChunk(chunk any) {
+	if chunk, ok := chunk.(*ChatCompletionStreamResponse); ok {
+		o.accumulator.Add(*chunk)
+	}
+}
+
+func (o *conversationObserver) OnFinish(err error) {
+	if err != nil {
+		return
+	}
+	if response := o.accumulator.Response(); len(response.Choices) > 0 {
+		o.conversation.mu.Lock()
+		defer o.conversation.mu.Unlock()
+		o.conversation.append(reportedUsage(response.Usage), response.Choices[0].Message)
+	}
+}
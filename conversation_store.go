@@ -0,0 +1,303 @@
+package giteeai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const jsonlExtension = ".jsonl"
+
+var (
+	// ErrConversationNotFound is returned by a ConversationStore for
+	// conversations that do not exist.
+	ErrConversationNotFound = errors.New("conversation not found")
+	// ErrConversationExists is returned by ConversationStore.Fork when the
+	// target conversation already exists.
+	ErrConversationExists = errors.New("conversation already exists")
+	// ErrInvalidConversationID is returned for empty IDs and, by JSONLStore,
+	// IDs that are not valid file names.
+	ErrInvalidConversationID = errors.New("invalid conversation ID")
+)
+
+// StoredMessage is a message of a persisted conversation.
+type StoredMessage struct {
+	Message ChatCompletionMessage `json:"message"`
+	// Usage is the usage of the completion that returned the message, for
+	// assistant messages.
+	Usage     *Usage    `json:"usage,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ConversationInfo describes a persisted conversation.
+type ConversationInfo struct {
+	ID        string
+	Messages  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ConversationStore persists the messages of conversations. Messages are only
+// appended: the trimming of a Conversation does not remove them from its store.
+type ConversationStore interface {
+	// Append adds messages to the conversation id, creating it if needed.
+	Append(ctx context.Context, id string, messages ...StoredMessage) error
+	// Load returns the messages of the conversation id, in order.
+	Load(ctx context.Context, id string) ([]StoredMessage, error)
+	// List returns the conversations, most recently updated first.
+	List(ctx context.Context) ([]ConversationInfo, error)
+	// Fork copies the first n messages of the conversation id, or all of them
+	// when n <= 0, to the new conversation newID.
+	Fork(ctx context.Context, id, newID string, n int) error
+	// Delete removes the conversation id.
+	Delete(ctx context.Context, id string) error
+}
+
+// LoadConversation creates a conversation persisted in store as id, starting
+// with the messages already stored. The conversation is empty when id has
+// no messages yet.
+func LoadConversation(
+	ctx context.Context,
+	client *Client,
+	strategy TrimStrategy,
+	store ConversationStore,
+	id string,
+) (*Conversation, error) {
+	stored, err := store.Load(ctx, id)
+	if err != nil && !errors.Is(err, ErrConversationNotFound) {
+		return nil, err
+	}
+	messages := make([]ChatCompletionMessage, len(stored))
+	for i, message := range stored {
+		messages[i] = historyMessage(message.Message)
+	}
+	conversation := NewConversation(client, strategy, messages...)
+	conversation.Store = store
+	conversation.ID = id
+	return conversation, nil
+}
+
+// JSONLStore stores every conversation in a file of its directory, with one
+// JSON encoded StoredMessage per line. Appending only writes at the end of the
+// file, and a line left incomplete by a crash is ignored when loading. It is
+// safe for concurrent use within a process.
+type JSONLStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewJSONLStore creates a JSONLStore in dir, creating the directory if needed.
+func NewJSONLStore(dir string) (*JSONLStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &JSONLStore{dir: dir}, nil
+}
+
+func (s *JSONLStore) path(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) || strings.ContainsRune(id, 0) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidConversationID, id)
+	}
+	return filepath.Join(s.dir, id+jsonlExtension), nil
+}
+
+// Append writes messages at the end of the file of the conversation.
+func (s *JSONLStore) Append(_ context.Context, id string, messages ...StoredMessage) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, message := range messages {
+		if err = encodeStoredMessage(&buf, message); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err = dropIncompleteLine(path); err != nil {
+		return err
+	}
+	return appendFile(path, buf.Bytes(), os.O_CREATE)
+}
+
+// dropIncompleteLine truncates the file after its last newline, removing the
+// message whose write was interrupted.
+func dropIncompleteLine(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err = f.ReadAt(last, info.Size()-1); err != nil || last[0] == '\n' {
+		return err
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return os.Truncate(path, int64(bytes.LastIndexByte(content, '\n')+1))
+}
+
+func encodeStoredMessage(w io.Writer, message StoredMessage) error {
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	// Encode writes a newline after every message.
+	return json.NewEncoder(w).Encode(message)
+}
+
+func appendFile(path string, content []byte, flag int) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|flag, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	_, err = f.Write(content)
+	return err
+}
+
+// Load reads the messages of the conversation.
+func (s *JSONLStore) Load(_ context.Context, id string) ([]StoredMessage, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id, path)
+}
+
+func (s *JSONLStore) load(id, path string) ([]StoredMessage, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []StoredMessage
+	for line := 1; len(content) > 0; line++ {
+		end := bytes.IndexByte(content, '\n')
+		if end < 0 {
+			// The last write was interrupted.
+			break
+		}
+		var message StoredMessage
+		if err = json.Unmarshal(content[:end], &message); err != nil {
+			return nil, fmt.Errorf("conversation %s, line %d: %w", id, line, err)
+		}
+		messages = append(messages, message)
+		content = content[end+1:]
+	}
+	return messages, nil
+}
+
+// List reads the conversations of the directory.
+func (s *JSONLStore) List(_ context.Context) ([]ConversationInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var infos []ConversationInfo
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), jsonlExtension)
+		if entry.IsDir() || id == entry.Name() {
+			continue
+		}
+		messages, err := s.load(id, filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		info := ConversationInfo{ID: id, Messages: len(messages)}
+		if len(messages) > 0 {
+			info.CreatedAt = messages[0].CreatedAt
+			info.UpdatedAt = messages[len(messages)-1].CreatedAt
+		}
+		infos = append(infos, info)
+	}
+	sortConversations(infos)
+	return infos, nil
+}
+
+func sortConversations(infos []ConversationInfo) {
+	sort.SliceStable(infos, func(i, j int) bool {
+		if !infos[i].UpdatedAt.Equal(infos[j].UpdatedAt) {
+			return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+}
+
+// Fork writes the first n messages of the conversation to a new file.
+func (s *JSONLStore) Fork(_ context.Context, id, newID string, n int) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	newPath, err := s.path(newID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages, err := s.load(id, path)
+	if err != nil {
+		return err
+	}
+	if n > 0 && n < len(messages) {
+		messages = messages[:n]
+	}
+	var buf bytes.Buffer
+	for _, message := range messages {
+		if err = encodeStoredMessage(&buf, message); err != nil {
+			return err
+		}
+	}
+	err = appendFile(newPath, buf.Bytes(), os.O_CREATE|os.O_EXCL)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", ErrConversationExists, newID)
+	}
+	return err
+}
+
+// Delete removes the file of the conversation.
+func (s *JSONLStore) Delete(_ context.Context, id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	}
+	return err
+}
@@ -0,0 +1,280 @@
+package giteeai
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConversationStoreSchema creates the tables of SQLStore. It is portable
+// across SQLite, PostgreSQL and MySQL; the timestamps are Unix times in
+// milliseconds and the messages and usages JSON documents.
+const ConversationStoreSchema = `CREATE TABLE IF NOT EXISTS giteeai_conversations (
+	id VARCHAR(255) NOT NULL PRIMARY KEY,
+	message_count INTEGER NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS giteeai_messages (
+	conversation_id VARCHAR(255) NOT NULL,
+	seq INTEGER NOT NULL,
+	role VARCHAR(32) NOT NULL,
+	message_json TEXT NOT NULL,
+	usage_json TEXT,
+	created_at BIGINT NOT NULL,
+	PRIMARY KEY (conversation_id, seq)
+);`
+
+const (
+	sqlSelectConversation = "SELECT message_count, created_at FROM giteeai_conversations WHERE id = ?"
+	sqlInsertConversation = "INSERT INTO giteeai_conversations (id, message_count, created_at, updated_at) " +
+		"VALUES (?, ?, ?, ?)"
+	sqlUpdateConversation = "UPDATE giteeai_conversations SET message_count = ?, updated_at = ? WHERE id = ?"
+	sqlListConversations  = "SELECT id, message_count, created_at, updated_at FROM giteeai_conversations " +
+		"ORDER BY updated_at DESC, id"
+	sqlDeleteConversation = "DELETE FROM giteeai_conversations WHERE id = ?"
+	sqlInsertMessage      = "INSERT INTO giteeai_messages " +
+		"(conversation_id, seq, role, message_json, usage_json, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	sqlSelectMessages = "SELECT message_json, usage_json, created_at FROM giteeai_messages " +
+		"WHERE conversation_id = ? ORDER BY seq"
+	sqlDeleteMessages = "DELETE FROM giteeai_messages WHERE conversation_id = ?"
+)
+
+// SQLPlaceholder is the bind parameter syntax of a database driver.
+type SQLPlaceholder int
+
+const (
+	// SQLPlaceholderQuestion is the "?" syntax of SQLite and MySQL.
+	SQLPlaceholderQuestion SQLPlaceholder = iota
+	// SQLPlaceholderDollar is the "$1" syntax of PostgreSQL.
+	SQLPlaceholderDollar
+)
+
+// SQLStore stores conversations in a database with any database/sql driver.
+// The tables are created by CreateTables or by running
+// ConversationStoreSchema in a migration. Concurrent appends to the same
+// conversation fail on the primary key of the messages rather than
+// interleave.
+type SQLStore struct {
+	db          *sql.DB
+	placeholder SQLPlaceholder
+}
+
+// NewSQLStore creates an SQLStore using the bind parameter syntax of the
+// driver of db.
+func NewSQLStore(db *sql.DB, placeholder SQLPlaceholder) *SQLStore {
+	return &SQLStore{db: db, placeholder: placeholder}
+}
+
+// CreateTables executes the statements of ConversationStoreSchema.
+func (s *SQLStore) CreateTables(ctx context.Context) error {
+	for _, statement := range strings.Split(ConversationStoreSchema, ";") {
+		if statement = strings.TrimSpace(statement); statement == "" {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// query rewrites the placeholders of query for the driver.
+func (s *SQLStore) query(query string) string {
+	if s.placeholder != SQLPlaceholderDollar {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds.
+func (s *SQLStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Append inserts messages after the last message of the conversation.
+func (s *SQLStore) Append(ctx context.Context, id string, messages ...StoredMessage) error {
+	if id == "" {
+		return ErrInvalidConversationID
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		return s.append(ctx, tx, id, messages)
+	})
+}
+
+func (s *SQLStore) append(ctx context.Context, tx *sql.Tx, id string, messages []StoredMessage) error {
+	var count int
+	var createdAt int64
+	err := tx.QueryRowContext(ctx, s.query(sqlSelectConversation), id).Scan(&count, &createdAt)
+	exists := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	now := time.Now()
+	for i, message := range messages {
+		if message.CreatedAt.IsZero() {
+			message.CreatedAt = now
+		}
+		content, err := json.Marshal(message.Message)
+		if err != nil {
+			return err
+		}
+		var usage sql.NullString
+		if message.Usage != nil {
+			b, err := json.Marshal(message.Usage)
+			if err != nil {
+				return err
+			}
+			usage = sql.NullString{String: string(b), Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, s.query(sqlInsertMessage),
+			id, count+i, message.Message.Role, string(content), usage, message.CreatedAt.UnixMilli())
+		if err != nil {
+			return err
+		}
+	}
+
+	count += len(messages)
+	if exists {
+		_, err = tx.ExecContext(ctx, s.query(sqlUpdateConversation), count, now.UnixMilli(), id)
+	} else {
+		_, err = tx.ExecContext(ctx, s.query(sqlInsertConversation), id, count, now.UnixMilli(), now.UnixMilli())
+	}
+	return err
+}
+
+// Load selects the messages of the conversation.
+func (s *SQLStore) Load(ctx context.Context, id string) ([]StoredMessage, error) {
+	var messages []StoredMessage
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		messages, err = s.load(ctx, tx, id)
+		return
+	})
+	return messages, err
+}
+
+func (s *SQLStore) load(ctx context.Context, tx *sql.Tx, id string) ([]StoredMessage, error) {
+	var count int
+	var createdAt int64
+	err := tx.QueryRowContext(ctx, s.query(sqlSelectConversation), id).Scan(&count, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, s.query(sqlSelectMessages), id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	messages := make([]StoredMessage, 0, count)
+	for rows.Next() {
+		var content string
+		var usage sql.NullString
+		var created int64
+		if err = rows.Scan(&content, &usage, &created); err != nil {
+			return nil, err
+		}
+		message := StoredMessage{CreatedAt: time.UnixMilli(created)}
+		if err = json.Unmarshal([]byte(content), &message.Message); err != nil {
+			return nil, fmt.Errorf("conversation %s, message %d: %w", id, len(messages), err)
+		}
+		if usage.Valid {
+			message.Usage = &Usage{}
+			if err = json.Unmarshal([]byte(usage.String), message.Usage); err != nil {
+				return nil, fmt.Errorf("conversation %s, message %d: %w", id, len(messages), err)
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// List selects the conversations.
+func (s *SQLStore) List(ctx context.Context) ([]ConversationInfo, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(sqlListConversations))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var infos []ConversationInfo
+	for rows.Next() {
+		var info ConversationInfo
+		var createdAt, updatedAt int64
+		if err = rows.Scan(&info.ID, &info.Messages, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		info.CreatedAt, info.UpdatedAt = time.UnixMilli(createdAt), time.UnixMilli(updatedAt)
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+// Fork copies the first n messages of the conversation in a transaction.
+func (s *SQLStore) Fork(ctx context.Context, id, newID string, n int) error {
+	if newID == "" {
+		return ErrInvalidConversationID
+	}
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		messages, err := s.load(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		var count int
+		var createdAt int64
+		err = tx.QueryRowContext(ctx, s.query(sqlSelectConversation), newID).Scan(&count, &createdAt)
+		if err == nil {
+			return fmt.Errorf("%w: %s", ErrConversationExists, newID)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if n > 0 && n < len(messages) {
+			messages = messages[:n]
+		}
+		return s.append(ctx, tx, newID, messages)
+	})
+}
+
+// Delete deletes the conversation and its messages.
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, s.query(sqlDeleteMessages), id); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, s.query(sqlDeleteConversation), id)
+		if err != nil {
+			return err
+		}
+		if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+			return fmt.Errorf("%w: %s", ErrConversationNotFound, id)
+		}
+		return nil
+	})
+}
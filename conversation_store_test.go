@@ -0,0 +1,331 @@
+package giteeai_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func storedMessages() []giteeai.StoredMessage {
+	created := time.UnixMilli(1700000000000).UTC()
+	return []giteeai.StoredMessage{
+		{Message: giteeai.ChatCompletionMessage{Role: giteeai.ChatMessageRoleUser, Content: "weather?"}, CreatedAt: created},
+		{
+			Message: giteeai.ChatCompletionMessage{
+				Role:             giteeai.ChatMessageRoleAssistant,
+				ReasoningContent: "I need the weather tool.",
+				ToolCalls: []giteeai.ToolCall{{
+					ID:       "call-1",
+					Type:     giteeai.ToolTypeFunction,
+					Function: giteeai.FunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`},
+				}},
+			},
+			Usage:     &giteeai.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			CreatedAt: created.Add(time.Second),
+		},
+		{
+			Message: giteeai.ChatCompletionMessage{
+				Role:       giteeai.ChatMessageRoleTool,
+				Content:    "sunny",
+				ToolCallID: "call-1",
+			},
+			CreatedAt: created.Add(2 * time.Second),
+		},
+	}
+}
+
+// inUTC converts the creation times of messages to UTC, since stores may
+// return them in another location.
+func inUTC(messages []giteeai.StoredMessage) []giteeai.StoredMessage {
+	for i := range messages {
+		messages[i].CreatedAt = messages[i].CreatedAt.UTC()
+	}
+	return messages
+}
+
+// testConversationStore checks the behavior shared by the stores.
+func testConversationStore(t *testing.T, store giteeai.ConversationStore) {
+	t.Helper()
+	ctx := context.Background()
+	messages := storedMessages()
+
+	_, err := store.Load(ctx, "chat")
+	checks.ErrorIs(t, err, giteeai.ErrConversationNotFound)
+
+	checks.NoError(t, store.Append(ctx, "chat", messages[:2]...), "Append error")
+	checks.NoError(t, store.Append(ctx, "chat", messages[2]), "Append error")
+	loaded, err := store.Load(ctx, "chat")
+	checks.NoError(t, err, "Load error")
+	if !reflect.DeepEqual(inUTC(loaded), messages) {
+		t.Fatalf("unexpected messages %+v", loaded)
+	}
+
+	checks.NoError(t, store.Fork(ctx, "chat", "fork", 1), "Fork error")
+	forked, err := store.Load(ctx, "fork")
+	checks.NoError(t, err, "Load error")
+	if !reflect.DeepEqual(inUTC(forked), messages[:1]) {
+		t.Fatalf("unexpected forked messages %+v", forked)
+	}
+	checks.ErrorIs(t, store.Fork(ctx, "chat", "fork", 0), giteeai.ErrConversationExists)
+	checks.ErrorIs(t, store.Fork(ctx, "missing", "other", 0), giteeai.ErrConversationNotFound)
+
+	infos, err := store.List(ctx)
+	checks.NoError(t, err, "List error")
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	if len(infos) != 2 || infos[0].ID != "chat" || infos[0].Messages != 3 || infos[1].ID != "fork" ||
+		infos[1].Messages != 1 || infos[0].CreatedAt.IsZero() || infos[0].UpdatedAt.IsZero() {
+		t.Fatalf("unexpected conversations %+v", infos)
+	}
+
+	checks.NoError(t, store.Delete(ctx, "chat"), "Delete error")
+	_, err = store.Load(ctx, "chat")
+	checks.ErrorIs(t, err, giteeai.ErrConversationNotFound)
+	checks.ErrorIs(t, store.Delete(ctx, "chat"), giteeai.ErrConversationNotFound)
+	checks.ErrorIs(t, store.Append(ctx, "", messages...), giteeai.ErrInvalidConversationID)
+}
+
+func TestJSONLStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "conversations")
+	store, err := giteeai.NewJSONLStore(dir)
+	checks.NoError(t, err, "NewJSONLStore error")
+	testConversationStore(t, store)
+
+	checks.ErrorIs(t, store.Append(context.Background(), "../chat"), giteeai.ErrInvalidConversationID)
+}
+
+func TestJSONLStoreInterruptedWrite(t *testing.T) {
+	dir := t.TempDir()
+	store, err := giteeai.NewJSONLStore(dir)
+	checks.NoError(t, err, "NewJSONLStore error")
+	ctx := context.Background()
+	messages := storedMessages()
+
+	checks.NoError(t, store.Append(ctx, "chat", messages[0]), "Append error")
+	f, err := os.OpenFile(filepath.Join(dir, "chat.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+	checks.NoError(t, err, "OpenFile error")
+	_, err = f.WriteString(`{"message":{"role":"assis`)
+	checks.NoError(t, err, "WriteString error")
+	checks.NoError(t, f.Close(), "Close error")
+
+	loaded, err := store.Load(ctx, "chat")
+	checks.NoError(t, err, "Load error")
+	if len(loaded) != 1 {
+		t.Fatalf("expected the incomplete message to be ignored, got %d messages", len(loaded))
+	}
+	checks.NoError(t, store.Append(ctx, "chat", messages[1]), "Append error")
+	loaded, err = store.Load(ctx, "chat")
+	checks.NoError(t, err, "Load error")
+	if !reflect.DeepEqual(inUTC(loaded), messages[:2]) {
+		t.Fatalf("unexpected messages %+v", loaded)
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	db, err := sql.Open("giteeai-memory", t.Name())
+	checks.NoError(t, err, "sql.Open error")
+	defer db.Close()
+
+	store := giteeai.NewSQLStore(db, giteeai.SQLPlaceholderDollar)
+	checks.NoError(t, store.CreateTables(context.Background()), "CreateTables error")
+	testConversationStore(t, store)
+}
+
+func TestConversationStore(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: "Hello", ReasoningContent: "greet back"}))
+
+	store, err := giteeai.NewJSONLStore(t.TempDir())
+	checks.NoError(t, err, "NewJSONLStore error")
+	ctx := context.Background()
+	conversation, err := giteeai.LoadConversation(ctx, client, nil, store, "chat")
+	checks.NoError(t, err, "LoadConversation error")
+	conversation.Append(giteeai.ChatCompletionMessage{Role: giteeai.ChatMessageRoleSystem, Content: "be nice"})
+	_, err = conversation.CreateChatCompletion(ctx, giteeai.ChatCompletionRequest{
+		Model:    giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "Hi"}},
+	})
+	checks.NoError(t, err, "CreateChatCompletion error")
+
+	stored, err := store.Load(ctx, "chat")
+	checks.NoError(t, err, "Load error")
+	if got, want := contents(storedContents(stored)), []string{"be nice", "Hi", "Hello"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected stored messages %v, want %v", got, want)
+	}
+	if stored[2].Message.ReasoningContent != "greet back" {
+		t.Error("expected the reasoning content to be stored")
+	}
+
+	resumed, err := giteeai.LoadConversation(ctx, client, nil, store, "chat")
+	checks.NoError(t, err, "LoadConversation error")
+	history := resumed.Messages()
+	if !reflect.DeepEqual(contents(history), []string{"be nice", "Hi", "Hello"}) || history[2].ReasoningContent != "" {
+		t.Errorf("unexpected resumed history %+v", history)
+	}
+}
+
+func storedContents(stored []giteeai.StoredMessage) []giteeai.ChatCompletionMessage {
+	messages := make([]giteeai.ChatCompletionMessage, len(stored))
+	for i, message := range stored {
+		messages[i] = message.Message
+	}
+	return messages
+}
+
+// memoryDriver is a database/sql driver understanding the statements of
+// SQLStore, with tables kept in memory per data source name.
+type memoryDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*memoryDB
+}
+
+type memoryDB struct {
+	conversations map[string][]driver.Value
+	messages      map[string][][]driver.Value
+}
+
+func init() {
+	sql.Register("giteeai-memory", &memoryDriver{dbs: make(map[string]*memoryDB)})
+}
+
+func (d *memoryDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dbs[name] == nil {
+		d.dbs[name] = &memoryDB{
+			conversations: make(map[string][]driver.Value),
+			messages:      make(map[string][][]driver.Value),
+		}
+	}
+	return &memoryConn{driver: d, db: d.dbs[name]}, nil
+}
+
+type memoryConn struct {
+	driver *memoryDriver
+	db     *memoryDB
+}
+
+func (c *memoryConn) Prepare(query string) (driver.Stmt, error) {
+	return &memoryStmt{conn: c, query: query}, nil
+}
+func (c *memoryConn) Close() error              { return nil }
+func (c *memoryConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *memoryConn) Commit() error             { return nil }
+func (c *memoryConn) Rollback() error           { return nil }
+
+type memoryStmt struct {
+	conn  *memoryConn
+	query string
+}
+
+var dollarPlaceholder = regexp.MustCompile(`\$\d+`)
+
+func (s *memoryStmt) Close() error  { return nil }
+func (s *memoryStmt) NumInput() int { return -1 }
+
+func (s *memoryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, affected, err := s.run(args)
+	return driver.RowsAffected(affected), err
+}
+
+func (s *memoryStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, _, err := s.run(args)
+	return &memoryRows{rows: rows}, err
+}
+
+func (s *memoryStmt) run(args []driver.Value) ([][]driver.Value, int64, error) {
+	if strings.Contains(s.query, "?") {
+		return nil, 0, errors.New("expected dollar placeholders")
+	}
+	query := dollarPlaceholder.ReplaceAllString(s.query, "?")
+	s.conn.driver.mu.Lock()
+	defer s.conn.driver.mu.Unlock()
+	db := s.conn.db
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE"):
+		return nil, 0, nil
+	case strings.HasPrefix(query, "SELECT message_count"):
+		if row, ok := db.conversations[args[0].(string)]; ok {
+			return [][]driver.Value{{row[1], row[2]}}, 0, nil
+		}
+		return nil, 0, nil
+	case strings.HasPrefix(query, "INSERT INTO giteeai_conversations"):
+		db.conversations[args[0].(string)] = args
+		return nil, 1, nil
+	case strings.HasPrefix(query, "UPDATE giteeai_conversations"):
+		row := db.conversations[args[2].(string)]
+		row[1], row[3] = args[0], args[1]
+		return nil, 1, nil
+	case strings.HasPrefix(query, "SELECT id"):
+		var rows [][]driver.Value
+		for _, row := range db.conversations {
+			rows = append(rows, row)
+		}
+		return rows, 0, nil
+	case strings.HasPrefix(query, "DELETE FROM giteeai_conversations"):
+		if _, ok := db.conversations[args[0].(string)]; !ok {
+			return nil, 0, nil
+		}
+		delete(db.conversations, args[0].(string))
+		return nil, 1, nil
+	case strings.HasPrefix(query, "INSERT INTO giteeai_messages"):
+		id := args[0].(string)
+		if int(args[1].(int64)) != len(db.messages[id]) {
+			return nil, 0, fmt.Errorf("duplicate key %v", args[1])
+		}
+		db.messages[id] = append(db.messages[id], args)
+		return nil, 1, nil
+	case strings.HasPrefix(query, "SELECT message_json"):
+		var rows [][]driver.Value
+		for _, row := range db.messages[args[0].(string)] {
+			rows = append(rows, []driver.Value{row[3], row[4], row[5]})
+		}
+		return rows, 0, nil
+	case strings.HasPrefix(query, "DELETE FROM giteeai_messages"):
+		affected := int64(len(db.messages[args[0].(string)]))
+		delete(db.messages, args[0].(string))
+		return nil, affected, nil
+	default:
+		return nil, 0, fmt.Errorf("unexpected query %q", query)
+	}
+}
+
+type memoryRows struct {
+	rows [][]driver.Value
+}
+
+func (r *memoryRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(r.rows[0]))
+}
+
+func (r *memoryRows) Close() error { return nil }
+
+func (r *memoryRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,260 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func contents(messages []giteeai.ChatCompletionMessage) []string {
+	result := make([]string, len(messages))
+	for i, message := range messages {
+		result[i] = message.Content
+	}
+	return result
+}
+
+// toolHistory is a history in which "call" is answered by two tool messages.
+func toolHistory() []giteeai.ChatCompletionMessage {
+	return []giteeai.ChatCompletionMessage{
+		{Role: giteeai.ChatMessageRoleSystem, Content: "system"},
+		{Role: giteeai.ChatMessageRoleUser, Content: "u1"},
+		{Role: giteeai.ChatMessageRoleAssistant, Content: "call", ToolCalls: []giteeai.ToolCall{{ID: "1"}, {ID: "2"}}},
+		{Role: giteeai.ChatMessageRoleTool, Content: "r1", ToolCallID: "1"},
+		{Role: giteeai.ChatMessageRoleTool, Content: "r2", ToolCallID: "2"},
+		{Role: giteeai.ChatMessageRoleAssistant, Content: "a1"},
+		{Role: giteeai.ChatMessageRoleUser, Content: "u2"},
+	}
+}
+
+func TestKeepLastMessages(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{2, []string{"system", "a1", "u2"}},
+		// The tool messages are not kept without their call.
+		{4, []string{"system", "a1", "u2"}},
+		{5, []string{"system", "call", "r1", "r2", "a1", "u2"}},
+		{0, []string{"system", "u2"}},
+		{10, []string{"system", "u1", "call", "r1", "r2", "a1", "u2"}},
+	}
+	for _, tt := range tests {
+		trimmed, err := giteeai.KeepLastMessages(tt.n).Trim(context.Background(), toolHistory())
+		checks.NoError(t, err, "Trim error")
+		if got := contents(trimmed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("KeepLastMessages(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestTokenBudget(t *testing.T) {
+	// Every message costs one token per word of its content.
+	counter := &giteeai.TokenCounter{Tokenizer: wordTokenizer}
+	strategy := giteeai.TokenBudget(counter, 5)
+
+	trimmed, err := strategy.Trim(context.Background(), toolHistory())
+	checks.NoError(t, err, "Trim error")
+	if got, want := contents(trimmed), []string{"system", "a1", "u2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected messages %v, want %v", got, want)
+	}
+
+	history := append(toolHistory(), giteeai.ChatCompletionMessage{
+		Role:    giteeai.ChatMessageRoleUser,
+		Content: "a question far too long for the budget",
+	})
+	_, err = strategy.Trim(context.Background(), history)
+	checks.ErrorIs(t, err, giteeai.ErrContextBudgetExceeded)
+}
+
+func TestSummarizeOlder(t *testing.T) {
+	var summarized [][]string
+	summarizer := giteeai.SummarizerFunc(func(
+		_ context.Context,
+		messages []giteeai.ChatCompletionMessage,
+	) (string, error) {
+		summarized = append(summarized, contents(messages))
+		return fmt.Sprintf("summary %d", len(summarized)), nil
+	})
+	strategy := giteeai.SummarizeOlder(summarizer, giteeai.KeepLastMessages(2))
+
+	trimmed, err := strategy.Trim(context.Background(), toolHistory())
+	checks.NoError(t, err, "Trim error")
+	want := []string{"system", "Summary of the earlier conversation:\nsummary 1", "a1", "u2"}
+	if got := contents(trimmed); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected messages %v, want %v", got, want)
+	}
+
+	// The previous summary is summarized with the newly dropped messages.
+	trimmed = append(trimmed,
+		giteeai.ChatCompletionMessage{Role: giteeai.ChatMessageRoleAssistant, Content: "a2"},
+		giteeai.ChatCompletionMessage{Role: giteeai.ChatMessageRoleUser, Content: "u3"},
+	)
+	trimmed, err = strategy.Trim(context.Background(), trimmed)
+	checks.NoError(t, err, "Trim error")
+	want = []string{"system", "Summary of the earlier conversation:\nsummary 2", "a2", "u3"}
+	if got := contents(trimmed); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected messages %v, want %v", got, want)
+	}
+	wantSummarized := [][]string{
+		{"u1", "call", "r1", "r2"},
+		{"Summary of the earlier conversation:\nsummary 1", "a1", "u2"},
+	}
+	if !reflect.DeepEqual(summarized, wantSummarized) {
+		t.Errorf("unexpected summarized messages %v", summarized)
+	}
+
+	// Nothing is summarized when the window keeps every message.
+	_, err = strategy.Trim(context.Background(), trimmed)
+	checks.NoError(t, err, "Trim error")
+	if len(summarized) != 2 {
+		t.Errorf("expected no summary, got %d", len(summarized))
+	}
+
+	failing := giteeai.SummarizeOlder(giteeai.SummarizerFunc(
+		func(context.Context, []giteeai.ChatCompletionMessage) (string, error) {
+			return "", errors.New("unavailable")
+		}), giteeai.KeepLastMessages(1))
+	_, err = failing.Trim(context.Background(), toolHistory())
+	checks.HasError(t, err, "expected the summarizer error")
+}
+
+func TestChatSummarizer(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: " The user said hello. "}))
+
+	summary, err := giteeai.NewChatSummarizer(client, giteeai.Qwen2_7B_Instruct).Summarize(context.Background(),
+		toolHistory()[1:3])
+	checks.NoError(t, err, "Summarize error")
+	if summary != "The user said hello." {
+		t.Errorf("unexpected summary %q", summary)
+	}
+	transcript := requests[0].Messages[1].Content
+	if requests[0].Messages[0].Role != giteeai.ChatMessageRoleSystem ||
+		!strings.Contains(transcript, "user: u1\n") || !strings.Contains(transcript, "assistant: call\n[call (") {
+		t.Errorf("unexpected summary request %+v", requests[0].Messages)
+	}
+}
+
+func TestConversationCreateChatCompletion(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: "a1", ReasoningContent: "thinking"},
+		giteeai.ChatCompletionMessage{Content: "a2"},
+	))
+
+	conversation := giteeai.NewConversation(client, giteeai.KeepLastMessages(2),
+		giteeai.ChatCompletionMessage{Role: giteeai.ChatMessageRoleSystem, Content: "system"})
+	for _, question := range []string{"u1", "u2"} {
+		_, err := conversation.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{
+			Model:    giteeai.Qwen2_7B_Instruct,
+			Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: question}},
+		})
+		checks.NoError(t, err, "CreateChatCompletion error")
+	}
+
+	if got, want := contents(requests[1].Messages), []string{"system", "a1", "u2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected request messages %v, want %v", got, want)
+	}
+	history := conversation.Messages()
+	if got, want := contents(history), []string{"system", "a1", "u2", "a2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected history %v, want %v", got, want)
+	}
+	if history[1].ReasoningContent != "" {
+		t.Error("expected the reasoning content to be dropped from the history")
+	}
+
+	// A failed call leaves the history unchanged.
+	_, err := conversation.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{
+		Model:    giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "u3"}},
+		Stream:   true,
+	})
+	checks.ErrorIs(t, err, giteeai.ErrChatCompletionStreamNotSupported)
+	if got := conversation.Messages(); !reflect.DeepEqual(got, history) {
+		t.Errorf("expected the history to be unchanged, got %v", contents(got))
+	}
+}
+
+func TestConversationTrimsNewMessages(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: "a1"}))
+
+	store, err := giteeai.NewJSONLStore(t.TempDir())
+	checks.NoError(t, err, "NewJSONLStore error")
+	ctx := context.Background()
+	conversation, err := giteeai.LoadConversation(ctx, client, giteeai.KeepLastMessages(1), store, "chat")
+	checks.NoError(t, err, "LoadConversation error")
+	// The strategy drops the first of the new messages.
+	_, err = conversation.CreateChatCompletion(ctx, giteeai.ChatCompletionRequest{
+		Model: giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{
+			{Role: giteeai.ChatMessageRoleUser, Content: "u1"},
+			{Role: giteeai.ChatMessageRoleUser, Content: "u2"},
+		},
+	})
+	checks.NoError(t, err, "CreateChatCompletion error")
+
+	if got, want := contents(requests[0].Messages), []string{"u2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected request messages %v, want %v", got, want)
+	}
+	if got, want := contents(conversation.Messages()), []string{"u2", "a1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected history %v, want %v", got, want)
+	}
+	stored, err := store.Load(ctx, "chat")
+	checks.NoError(t, err, "Load error")
+	if got, want := contents(storedContents(stored)), []string{"u1", "u2", "a1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected stored messages %v, want %v", got, want)
+	}
+}
+
+func TestConversationCreateChatCompletionStream(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"He\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"llo\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	conversation := giteeai.NewConversation(client, nil)
+	stream, err := conversation.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model:    giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "Hi"}},
+	})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	if got, want := contents(conversation.Messages()), []string{"Hi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected history before the reply %v", got)
+	}
+	_, err = stream.Accumulate()
+	checks.NoError(t, err, "Accumulate error")
+	history := conversation.Messages()
+	if got, want := contents(history), []string{"Hi", "Hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected history %v, want %v", got, want)
+	}
+	if history[1].Role != giteeai.ChatMessageRoleAssistant {
+		t.Errorf("unexpected reply role %q", history[1].Role)
+	}
+}
@@ -0,0 +1,257 @@
+package giteeai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCredentialCheckInterval = time.Second
+
+// ErrNoCredentials is returned when a CredentialProvider has no key to offer.
+var ErrNoCredentials = errors.New("no API key available")
+
+// CredentialProvider supplies the API key of every request. It is consulted
+// each time a request is built, so keys can change without rebuilding the client.
+type CredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// CredentialRotator is implemented by providers holding several keys. When
+// the API rejects a key with a 401 or a quota exhausted error the client
+// calls Rotate with the rejected key and, if it returns true, retries the
+// request with the next one.
+type CredentialRotator interface {
+	Rotate(failed string) bool
+}
+
+// KeySet is a CredentialProvider rotating through a fixed set of keys.
+type KeySet struct {
+	mu      sync.Mutex
+	keys    []string
+	current int
+}
+
+// NewKeySet creates a KeySet starting with the first key.
+func NewKeySet(keys ...string) *KeySet {
+	k := &KeySet{}
+	k.SetKeys(keys)
+	return k
+}
+
+// Token returns the current key.
+func (k *KeySet) Token(_ context.Context) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.keys) == 0 {
+		return "", ErrNoCredentials
+	}
+	return k.keys[k.current], nil
+}
+
+// Rotate switches to the key following failed. If failed is no longer the
+// current key, a concurrent request already rotated it and the current key is
+// kept. It returns false when there is no other key to use.
+func (k *KeySet) Rotate(failed string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.keys) < 2 {
+		return false
+	}
+	if k.keys[k.current] == failed {
+		k.current = (k.current + 1) % len(k.keys)
+	}
+	return true
+}
+
+// SetKeys replaces the keys, keeping the current one if it is still part of the set.
+func (k *KeySet) SetKeys(keys []string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var current string
+	if len(k.keys) > 0 {
+		current = k.keys[k.current]
+	}
+	k.keys = make([]string, 0, len(keys))
+	k.current = 0
+	for _, key := range keys {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		if key == current {
+			k.current = len(k.keys)
+		}
+		k.keys = append(k.keys, key)
+	}
+}
+
+// EnvCredentialProvider reads the keys from an environment variable on every
+// request. The variable may hold several comma separated keys to rotate through.
+type EnvCredentialProvider struct {
+	Name string
+
+	mu    sync.Mutex
+	value string
+	keys  KeySet
+}
+
+// NewEnvCredentialProvider creates a provider reading the variable name.
+func NewEnvCredentialProvider(name string) *EnvCredentialProvider {
+	return &EnvCredentialProvider{Name: name}
+}
+
+// Token returns the current key of the variable.
+func (p *EnvCredentialProvider) Token(ctx context.Context) (string, error) {
+	p.refresh()
+	token, err := p.keys.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w in $%s", err, p.Name)
+	}
+	return token, nil
+}
+
+// Rotate implements CredentialRotator.
+func (p *EnvCredentialProvider) Rotate(failed string) bool {
+	p.refresh()
+	return p.keys.Rotate(failed)
+}
+
+func (p *EnvCredentialProvider) refresh() {
+	value := os.Getenv(p.Name)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if value == p.value {
+		return
+	}
+	p.value = value
+	p.keys.SetKeys(strings.Split(value, ","))
+}
+
+// FileCredentialProvider reads the keys from a file, one per line; empty
+// lines and lines starting with # are ignored. The file is reloaded when its
+// modification time or size changes, so rotating a key only requires
+// rewriting the file, e.g. a mounted Kubernetes secret.
+type FileCredentialProvider struct {
+	Path string
+	// CheckInterval is the minimum time between two checks of the file, 1s
+	// by default.
+	CheckInterval time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	modTime   time.Time
+	size      int64
+	loadErr   error
+	keys      KeySet
+}
+
+// NewFileCredentialProvider creates a provider reading path.
+func NewFileCredentialProvider(path string) *FileCredentialProvider {
+	return &FileCredentialProvider{Path: path}
+}
+
+// Token returns the current key of the file.
+func (p *FileCredentialProvider) Token(ctx context.Context) (string, error) {
+	if err := p.refresh(); err != nil {
+		return "", err
+	}
+	token, err := p.keys.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w in %s", err, p.Path)
+	}
+	return token, nil
+}
+
+// Rotate implements CredentialRotator.
+func (p *FileCredentialProvider) Rotate(failed string) bool {
+	if err := p.refresh(); err != nil {
+		return false
+	}
+	return p.keys.Rotate(failed)
+}
+
+func (p *FileCredentialProvider) refresh() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	interval := p.CheckInterval
+	if interval <= 0 {
+		interval = defaultCredentialCheckInterval
+	}
+	now := time.Now()
+	if !p.checkedAt.IsZero() && now.Sub(p.checkedAt) < interval {
+		return p.loadErr
+	}
+	p.checkedAt = now
+
+	info, err := os.Stat(p.Path)
+	if err != nil {
+		p.loadErr = fmt.Errorf("reading API keys: %w", err)
+		return p.loadErr
+	}
+	if p.loadErr == nil && info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return nil
+	}
+
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		p.loadErr = fmt.Errorf("reading API keys: %w", err)
+		return p.loadErr
+	}
+	var keys []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	p.keys.SetKeys(keys)
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.loadErr = nil
+	return nil
+}
+
+// isCredentialError reports whether err means the key used by the request
+// was rejected: a 401 or a quota exhausted error.
+func isCredentialError(err error) bool {
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode == http.StatusUnauthorized ||
+			apiErr.Type == "insufficient_quota" || apiErr.Code == "insufficient_quota"
+	}
+	reqErr := &RequestError{}
+	if errors.As(err, &reqErr) {
+		return reqErr.HTTPStatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// rotateCredential switches req to the next key of the provider after the
+// key it used was rejected with err. It returns false if the request should
+// not be sent again, in particular once every key has been tried.
+func (c *Client) rotateCredential(req *http.Request, err error, used map[string]bool) bool {
+	rotator, ok := c.config.CredentialProvider.(CredentialRotator)
+	if !ok || !isCredentialError(err) {
+		return false
+	}
+	failed := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	used[failed] = true
+	if !rotator.Rotate(failed) {
+		return false
+	}
+	if c.setCommonHeaders(req) != nil {
+		return false
+	}
+	return !used[strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")]
+}
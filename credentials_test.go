@@ -0,0 +1,132 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// newKeyServer starts a server accepting only the valid key, answering 401
+// or a quota error for the others.
+func newKeyServer(t *testing.T, valid string) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.Header.Get("Authorization") {
+		case "Bearer " + valid:
+			fmt.Fprint(w, `{"id":"ok"}`)
+		case "Bearer exhausted":
+			writeServerError(w, http.StatusTooManyRequests, "insufficient_quota")
+		default:
+			writeServerError(w, http.StatusUnauthorized, "invalid_request_error")
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &hits
+}
+
+func newCredentialClient(ts *httptest.Server, provider giteeai.CredentialProvider) *giteeai.Client {
+	config := giteeai.DefaultConfig("")
+	config.BaseURL = ts.URL + "/v1"
+	config.CredentialProvider = provider
+	return giteeai.NewClientWithConfig(config)
+}
+
+func TestKeySetRotation(t *testing.T) {
+	ts, hits := newKeyServer(t, "good")
+	keys := giteeai.NewKeySet("revoked", "exhausted", "good")
+	client := newCredentialClient(ts, keys)
+
+	_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletion error")
+	if atomic.LoadInt32(hits) != 3 {
+		t.Fatalf("expected every key to be tried once, got %d requests", *hits)
+	}
+	if token, _ := keys.Token(context.Background()); token != "good" {
+		t.Fatalf("expected the key set to keep the working key, got %q", token)
+	}
+
+	_, err = client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletion error")
+	if atomic.LoadInt32(hits) != 4 {
+		t.Fatalf("expected the working key to be used directly, got %d requests", *hits)
+	}
+}
+
+func TestKeySetAllKeysRejected(t *testing.T) {
+	ts, hits := newKeyServer(t, "good")
+	client := newCredentialClient(ts, giteeai.NewKeySet("a", "b"))
+
+	_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	apiErr := &giteeai.APIError{}
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected a 401 error, got %v", err)
+	}
+	if atomic.LoadInt32(hits) != 2 {
+		t.Fatalf("expected one request per key, got %d", *hits)
+	}
+}
+
+func TestEnvCredentialProvider(t *testing.T) {
+	ts, _ := newKeyServer(t, "second")
+	t.Setenv("GITEEAI_TEST_KEYS", "")
+	client := newCredentialClient(ts, giteeai.NewEnvCredentialProvider("GITEEAI_TEST_KEYS"))
+
+	_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.ErrorIs(t, err, giteeai.ErrNoCredentials, "expected ErrNoCredentials for an empty variable")
+
+	t.Setenv("GITEEAI_TEST_KEYS", "first, second")
+	_, err = client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletion error")
+}
+
+func TestFileCredentialProvider(t *testing.T) {
+	ts, _ := newKeyServer(t, "new")
+	path := filepath.Join(t.TempDir(), "keys")
+	checks.NoError(t, os.WriteFile(path, []byte("# rotated weekly\nold\n"), 0o600), "WriteFile error")
+
+	provider := giteeai.NewFileCredentialProvider(path)
+	provider.CheckInterval = time.Nanosecond
+	client := newCredentialClient(ts, provider)
+
+	_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.HasError(t, err, "the old key should be rejected")
+
+	checks.NoError(t, os.WriteFile(path, []byte("new\n"), 0o600), "WriteFile error")
+	later := time.Now().Add(time.Minute)
+	checks.NoError(t, os.Chtimes(path, later, later), "Chtimes error")
+
+	_, err = client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "the rewritten key file should be picked up")
+}
+
+func TestFileCredentialProviderMissingFile(t *testing.T) {
+	provider := giteeai.NewFileCredentialProvider(filepath.Join(t.TempDir(), "missing"))
+	_, err := provider.Token(context.Background())
+	checks.ErrorIs(t, err, os.ErrNotExist, "expected a missing file error")
+}
+
+func TestCredentialProviderErrorVectorStore(t *testing.T) {
+	ts, hits := newKeyServer(t, "good")
+	t.Setenv("GITEEAI_TEST_KEYS", "")
+	client := newCredentialClient(ts, giteeai.NewEnvCredentialProvider("GITEEAI_TEST_KEYS"))
+
+	_, err := client.RetrieveVectorStore(context.Background(), "vs_abc123")
+	checks.ErrorIs(t, err, giteeai.ErrNoCredentials, "expected ErrNoCredentials for an empty variable")
+	err = client.DeleteVectorStoreFile(context.Background(), "vs_abc123", "file_abc123")
+	checks.ErrorIs(t, err, giteeai.ErrNoCredentials, "expected ErrNoCredentials for an empty variable")
+	if atomic.LoadInt32(hits) != 0 {
+		t.Fatalf("expected no request without credentials, got %d", *hits)
+	}
+}
@@ -246,6 +246,7 @@ func (c *Client) CreateEmbeddings(
 		http.MethodPost,
 		c.fullURL("/embeddings", withModel(string(baseReq.Model))),
 		withBody(baseReq),
+		withOperation(OperationCreateEmbeddings),
 	)
 	if err != nil {
 		return
@@ -0,0 +1,421 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultEndpointCooldown         = 30 * time.Second
+	defaultEndpointFailureThreshold = 1
+	defaultEndpointStickyLimit      = 10000
+)
+
+// ErrNoEndpoints is returned when a client is configured with an empty EndpointPool.
+var ErrNoEndpoints = errors.New("endpoint pool has no endpoints")
+
+// Endpoint is one backend of an EndpointPool.
+type Endpoint struct {
+	// Name identifies the endpoint in stats. It defaults to BaseURL.
+	Name string
+	// BaseURL replaces ClientConfig.BaseURL for the requests sent to this endpoint.
+	BaseURL string
+	// AuthToken, if set, replaces the client's token for this endpoint.
+	AuthToken string
+	// Weight is the relative share of the traffic sent to the endpoint while
+	// it is healthy. Zero means 1.
+	Weight int
+}
+
+// EndpointStats is a snapshot of the state of an endpoint.
+type EndpointStats struct {
+	Name                string
+	Healthy             bool
+	ConsecutiveFailures int
+	// CooldownUntil is the time until which the endpoint is skipped, if unhealthy.
+	CooldownUntil time.Time
+	Requests      int64
+	Failures      int64
+}
+
+// EndpointPool spreads requests across several OpenAI compatible backends.
+// Requests that fail with a connection error or a 5xx status are retried
+// immediately on another endpoint, and the failing endpoint is put in
+// cooldown. Objects of the stateful APIs (assistants, threads, files, vector
+// stores, batches and fine-tuning jobs) only exist on the backend that
+// created them, so requests referencing them are always routed to it.
+//
+// The pool may be shared between clients; it is safe for concurrent use.
+type EndpointPool struct {
+	// Cooldown is how long an unhealthy endpoint is skipped, 30s by default.
+	Cooldown time.Duration
+	// FailureThreshold is the number of consecutive failures after which an
+	// endpoint is considered unhealthy, 1 by default.
+	FailureThreshold int
+	// StickyLimit caps the number of object IDs remembered for sticky
+	// routing, 10000 by default. The oldest IDs are forgotten first.
+	StickyLimit int
+
+	mu          sync.Mutex
+	endpoints   []*endpointState
+	sticky      map[string]*endpointState
+	stickyOrder []string
+}
+
+type endpointState struct {
+	Endpoint
+
+	failures      int
+	cooldownUntil time.Time
+	requests      int64
+	totalFailures int64
+}
+
+// NewEndpointPool creates a pool routing between endpoints.
+func NewEndpointPool(endpoints ...Endpoint) *EndpointPool {
+	p := &EndpointPool{sticky: make(map[string]*endpointState)}
+	for _, e := range endpoints {
+		if e.Name == "" {
+			e.Name = e.BaseURL
+		}
+		if e.Weight <= 0 {
+			e.Weight = 1
+		}
+		e.BaseURL = strings.TrimSuffix(e.BaseURL, "/")
+		p.endpoints = append(p.endpoints, &endpointState{Endpoint: e})
+	}
+	return p
+}
+
+// Stats returns the state of every endpoint, in the order they were added.
+func (p *EndpointPool) Stats() []EndpointStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]EndpointStats, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		stats = append(stats, EndpointStats{
+			Name:                e.Name,
+			Healthy:             !now.Before(e.cooldownUntil),
+			ConsecutiveFailures: e.failures,
+			CooldownUntil:       e.cooldownUntil,
+			Requests:            e.requests,
+			Failures:            e.totalFailures,
+		})
+	}
+	return stats
+}
+
+func (p *EndpointPool) canFailover() bool {
+	return p != nil && len(p.endpoints) > 1
+}
+
+func (p *EndpointPool) cooldown() time.Duration {
+	if p.Cooldown <= 0 {
+		return defaultEndpointCooldown
+	}
+	return p.Cooldown
+}
+
+func (p *EndpointPool) failureThreshold() int {
+	if p.FailureThreshold <= 0 {
+		return defaultEndpointFailureThreshold
+	}
+	return p.FailureThreshold
+}
+
+func (p *EndpointPool) stickyLimit() int {
+	if p.StickyLimit <= 0 {
+		return defaultEndpointStickyLimit
+	}
+	return p.StickyLimit
+}
+
+// pick selects an endpoint that has not been tried yet: a weighted random
+// choice among the healthy ones, or else the one leaving cooldown first.
+func (p *EndpointPool) pick(tried map[*endpointState]bool) *endpointState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var healthy []*endpointState
+	var fallback *endpointState
+	totalWeight := 0
+	for _, e := range p.endpoints {
+		if tried[e] {
+			continue
+		}
+		if !now.Before(e.cooldownUntil) {
+			healthy = append(healthy, e)
+			totalWeight += e.Weight
+			continue
+		}
+		if fallback == nil || e.cooldownUntil.Before(fallback.cooldownUntil) {
+			fallback = e
+		}
+	}
+	if len(healthy) == 0 {
+		return fallback
+	}
+
+	n := rand.Intn(totalWeight) //nolint:gosec // load balancing does not need a secure source
+	for _, e := range healthy {
+		if n < e.Weight {
+			return e
+		}
+		n -= e.Weight
+	}
+	return healthy[len(healthy)-1]
+}
+
+// report records the outcome of a request sent to e.
+func (p *EndpointPool) report(e *endpointState, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.requests++
+	if !failed {
+		e.failures = 0
+		e.cooldownUntil = time.Time{}
+		return
+	}
+	e.failures++
+	e.totalFailures++
+	if e.failures >= p.failureThreshold() {
+		e.cooldownUntil = time.Now().Add(p.cooldown())
+	}
+}
+
+// stickyEndpoint returns the endpoint owning one of the object IDs, if any.
+func (p *EndpointPool) stickyEndpoint(ids []string) *endpointState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range ids {
+		if e, ok := p.sticky[id]; ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// bind routes the requests referencing the object IDs to e.
+func (p *EndpointPool) bind(e *endpointState, ids ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sticky == nil {
+		p.sticky = make(map[string]*endpointState)
+	}
+	for _, id := range ids {
+		if id == "" {
+			continue
+		}
+		if _, ok := p.sticky[id]; !ok {
+			p.stickyOrder = append(p.stickyOrder, id)
+		}
+		p.sticky[id] = e
+	}
+	if excess := len(p.stickyOrder) - p.stickyLimit(); excess > 0 {
+		for _, id := range p.stickyOrder[:excess] {
+			delete(p.sticky, id)
+		}
+		p.stickyOrder = append([]string(nil), p.stickyOrder[excess:]...)
+	}
+}
+
+// statefulPathPrefixes are the APIs whose objects only exist on the backend
+// that created them.
+var statefulPathPrefixes = []string{
+	"/assistants",
+	"/threads",
+	"/vector_stores",
+	"/files",
+	"/batches",
+	"/fine_tuning",
+	"/fine-tunes",
+}
+
+// resourcePathSegments are the path segments of the stateful APIs that are
+// not object IDs.
+var resourcePathSegments = map[string]bool{
+	"assistants":          true,
+	"threads":             true,
+	"runs":                true,
+	"steps":               true,
+	"messages":            true,
+	"files":               true,
+	"content":             true,
+	"vector_stores":       true,
+	"file_batches":        true,
+	"batches":             true,
+	"fine_tuning":         true,
+	"fine-tunes":          true,
+	"jobs":                true,
+	"events":              true,
+	"cancel":              true,
+	"submit_tool_outputs": true,
+}
+
+func isStatefulPath(path string) bool {
+	for _, prefix := range statefulPathPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") || strings.HasPrefix(path, prefix+"?") {
+			return true
+		}
+	}
+	return false
+}
+
+// referencedIDs returns the object IDs a request refers to: the IDs in its
+// path and the *ID and *IDs string fields of its body.
+func referencedIDs(path string, body any) []string {
+	var ids []string
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" && !resourcePathSegments[segment] {
+			ids = append(ids, segment)
+		}
+	}
+
+	v := reflect.ValueOf(body)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ids
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ids
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		field := v.Field(i)
+		switch {
+		case strings.HasSuffix(name, "ID") && field.Kind() == reflect.String:
+			if id := field.String(); id != "" {
+				ids = append(ids, id)
+			}
+		case (strings.HasSuffix(name, "IDs") || strings.HasSuffix(name, "Ids")) && field.Kind() == reflect.Slice &&
+			field.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < field.Len(); j++ {
+				ids = append(ids, field.Index(j).String())
+			}
+		}
+	}
+	return ids
+}
+
+// responseID returns the ID field of a decoded response, if it has one.
+func responseID(response any) string {
+	v := reflect.ValueOf(response)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.FieldByName("ID")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// doRouted sends a single attempt through the endpoint pool, failing over to
+// the other endpoints on connection errors and 5xx responses. Without a pool
+// it sends the request as is.
+func (c *Client) doRouted(req *http.Request) (*http.Response, error) {
+	pool := c.config.EndpointPool
+	if pool == nil {
+		return c.doLimited(req)
+	}
+	if len(pool.endpoints) == 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	rawURL := req.URL.String()
+	if !strings.HasPrefix(rawURL, c.config.BaseURL) {
+		return nil, fmt.Errorf("request URL %s is not under BaseURL %s", rawURL, c.config.BaseURL)
+	}
+	suffix := strings.TrimPrefix(rawURL, c.config.BaseURL)
+
+	info := requestInfoFromContext(req.Context())
+	info.path = suffix
+	var sticky *endpointState
+	if isStatefulPath(suffix) {
+		sticky = pool.stickyEndpoint(referencedIDs(suffix, info.body))
+	}
+
+	tried := make(map[*endpointState]bool)
+	for try := 0; ; try++ {
+		endpoint := sticky
+		if endpoint == nil {
+			endpoint = pool.pick(tried)
+		}
+		endpointReq, err := endpointRequest(req, endpoint, suffix, try)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.doLimited(endpointReq)
+		failed := (err != nil && req.Context().Err() == nil) ||
+			(err == nil && resp.StatusCode >= http.StatusInternalServerError)
+		if err == nil || failed {
+			pool.report(endpoint, failed)
+		}
+		tried[endpoint] = true
+		if !failed || sticky != nil || len(tried) == len(pool.endpoints) {
+			info.endpoint = endpoint
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// endpointRequest rewrites req for endpoint. Every try after the first gets
+// a fresh copy of the body.
+func endpointRequest(req *http.Request, endpoint *endpointState, suffix string, try int) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if try > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	u, err := req.URL.Parse(endpoint.BaseURL + suffix)
+	if err != nil {
+		return nil, err
+	}
+	r.URL = u
+	r.Host = ""
+	if endpoint.AuthToken != "" {
+		r.Header.Set("Authorization", "Bearer "+endpoint.AuthToken)
+	}
+	return r, nil
+}
+
+// bindEndpoint remembers which endpoint owns the objects referenced or
+// created by a successful call to a stateful API.
+func (c *Client) bindEndpoint(ctx context.Context, response any) {
+	info := requestInfoFromContext(ctx)
+	if c.config.EndpointPool == nil || info.endpoint == nil || !isStatefulPath(info.path) {
+		return
+	}
+	ids := referencedIDs(info.path, info.body)
+	ids = append(ids, responseID(response))
+	c.config.EndpointPool.bind(info.endpoint, ids...)
+}
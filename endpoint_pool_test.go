@@ -0,0 +1,124 @@
+package giteeai_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// newEndpointServer starts a backend answering every request with an object
+// whose ID is its name, counting the requests it receives.
+func newEndpointServer(t *testing.T, name, token string, status int) (*httptest.Server, *int32) {
+	t.Helper()
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if status != http.StatusOK {
+			writeServerError(w, status, "server_error")
+			return
+		}
+		fmt.Fprintf(w, `{"id":"%s"}`, name)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &hits
+}
+
+func newPoolClient(pool *giteeai.EndpointPool) *giteeai.Client {
+	config := giteeai.DefaultConfig("unused")
+	config.EndpointPool = pool
+	return giteeai.NewClientWithConfig(config)
+}
+
+func TestEndpointPoolFailover(t *testing.T) {
+	down, downHits := newEndpointServer(t, "down", "key-a", http.StatusServiceUnavailable)
+	up, _ := newEndpointServer(t, "up", "key-b", http.StatusOK)
+	pool := giteeai.NewEndpointPool(
+		giteeai.Endpoint{Name: "down", BaseURL: down.URL + "/v1", AuthToken: "key-a", Weight: 100},
+		giteeai.Endpoint{Name: "up", BaseURL: up.URL + "/v1", AuthToken: "key-b"},
+	)
+	pool.Cooldown = time.Hour
+	client := newPoolClient(pool)
+
+	for i := 0; i < 5; i++ {
+		resp, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+		checks.NoError(t, err, "CreateChatCompletion error")
+		if resp.ID != "up" {
+			t.Fatalf("expected the healthy endpoint to answer, got %q", resp.ID)
+		}
+	}
+	if hits := atomic.LoadInt32(downHits); hits > 1 {
+		t.Fatalf("endpoint in cooldown received %d requests", hits)
+	}
+	stats := pool.Stats()
+	if stats[0].Healthy || stats[0].Failures > 1 || !stats[1].Healthy || stats[1].Requests != 5 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestEndpointPoolConnectionError(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	up, _ := newEndpointServer(t, "up", "key", http.StatusOK)
+	client := newPoolClient(giteeai.NewEndpointPool(
+		giteeai.Endpoint{BaseURL: closed.URL + "/v1", AuthToken: "key", Weight: 100},
+		giteeai.Endpoint{BaseURL: up.URL + "/v1", AuthToken: "key"},
+	))
+
+	resp, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletion error")
+	if resp.ID != "up" {
+		t.Fatalf("expected the reachable endpoint to answer, got %q", resp.ID)
+	}
+}
+
+func TestEndpointPoolAllEndpointsFail(t *testing.T) {
+	a, aHits := newEndpointServer(t, "a", "key", http.StatusInternalServerError)
+	b, bHits := newEndpointServer(t, "b", "key", http.StatusBadGateway)
+	client := newPoolClient(giteeai.NewEndpointPool(
+		giteeai.Endpoint{BaseURL: a.URL + "/v1", AuthToken: "key"},
+		giteeai.Endpoint{BaseURL: b.URL + "/v1", AuthToken: "key"},
+	))
+
+	_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.HasError(t, err, "CreateChatCompletion should fail when every endpoint fails")
+	if atomic.LoadInt32(aHits) != 1 || atomic.LoadInt32(bHits) != 1 {
+		t.Fatalf("expected one request per endpoint, got %d and %d", *aHits, *bHits)
+	}
+}
+
+func TestEndpointPoolStickyRouting(t *testing.T) {
+	a, _ := newEndpointServer(t, "a", "key-a", http.StatusOK)
+	b, _ := newEndpointServer(t, "b", "key-b", http.StatusOK)
+	client := newPoolClient(giteeai.NewEndpointPool(
+		giteeai.Endpoint{Name: "a", BaseURL: a.URL + "/v1", AuthToken: "key-a"},
+		giteeai.Endpoint{Name: "b", BaseURL: b.URL + "/v1", AuthToken: "key-b"},
+	))
+
+	thread, err := client.CreateThread(context.Background(), giteeai.ThreadRequest{})
+	checks.NoError(t, err, "CreateThread error")
+	owner := thread.ID
+
+	for i := 0; i < 20; i++ {
+		got, retrieveErr := client.RetrieveThread(context.Background(), thread.ID)
+		checks.NoError(t, retrieveErr, "RetrieveThread error")
+		if got.ID != owner {
+			t.Fatalf("thread %s was routed to another endpoint: %q", thread.ID, got.ID)
+		}
+		msg, msgErr := client.CreateMessage(context.Background(), thread.ID, giteeai.MessageRequest{})
+		checks.NoError(t, msgErr, "CreateMessage error")
+		if msg.ID != owner {
+			t.Fatalf("message of thread %s was routed to %q", thread.ID, msg.ID)
+		}
+	}
+}
@@ -26,7 +26,7 @@ type EnginesList struct {
 // ListEngines Lists the currently available engines, and provides basic
 // information about each option such as the owner and availability.
 func (c *Client) ListEngines(ctx context.Context) (engines EnginesList, err error) {
-	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL("/engines"))
+	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL("/engines"), withOperation(OperationListEngines))
 	if err != nil {
 		return
 	}
@@ -42,7 +42,7 @@ func (c *Client) GetEngine(
 	engineID string,
 ) (engine Engine, err error) {
 	urlSuffix := fmt.Sprintf("/engines/%s", engineID)
-	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL(urlSuffix))
+	req, err := c.newRequest(ctx, http.MethodGet, c.fullURL(urlSuffix), withOperation(OperationGetEngine))
 	if err != nil {
 		return
 	}
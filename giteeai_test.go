@@ -0,0 +1,28 @@
+package giteeai_test
+
+import (
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test"
+)
+
+func setupGiteeAITestServer() (client *giteeai.Client, server *test.ServerTest, teardown func()) {
+	return setupGiteeAITestServerWithConfig(nil)
+}
+
+// setupGiteeAITestServerWithConfig starts a mocked API server and returns a
+// client pointed at it. configure, if set, can adjust the client config.
+func setupGiteeAITestServerWithConfig(
+	configure func(*giteeai.ClientConfig),
+) (client *giteeai.Client, server *test.ServerTest, teardown func()) {
+	server = test.NewTestServer()
+	ts := server.OpenAITestServer()
+	ts.Start()
+	teardown = ts.Close
+	config := giteeai.DefaultConfig(test.GetTestToken())
+	config.BaseURL = ts.URL + "/v1"
+	if configure != nil {
+		configure(&config)
+	}
+	client = giteeai.NewClientWithConfig(config)
+	return
+}
@@ -76,6 +76,7 @@ func (c *Client) CreateImage(ctx context.Context, request ImageRequest) (respons
 		http.MethodPost,
 		c.fullURL(urlSuffix, withModel(request.Model)),
 		withBody(request),
+		withOperation(OperationCreateImage),
 	)
 	if err != nil {
 		return
@@ -173,6 +174,7 @@ func (c *Client) CreateEditImage(ctx context.Context, request ImageEditRequest)
 		c.fullURL("/images/edits", withModel(request.Model)),
 		withBody(body),
 		withContentType(builder.FormDataContentType()),
+		withOperation(OperationCreateEditImage),
 	)
 	if err != nil {
 		return
@@ -231,6 +233,7 @@ func (c *Client) CreateVariImage(ctx context.Context, request ImageVariRequest)
 		c.fullURL("/images/variations", withModel(request.Model)),
 		withBody(body),
 		withContentType(builder.FormDataContentType()),
+		withOperation(OperationCreateVariImage),
 	)
 	if err != nil {
 		return
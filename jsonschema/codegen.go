@@ -0,0 +1,382 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// commonInitialisms are written in upper case in Go identifiers.
+var commonInitialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// CodeOptions configures GenerateGoCode.
+type CodeOptions struct {
+	// Package is the name of the package of the generated file.
+	Package string
+	// TypeName is the name of the type generated for the root schema.
+	TypeName string
+	// Header is an optional comment written before the package clause, such
+	// as "Code generated by jsonschemagen; DO NOT EDIT.".
+	Header string
+}
+
+// GenerateGoCode generates the Go types described by schema, formatted with
+// gofmt. GenerateSchemaForType generates an equivalent schema from them.
+//
+// Objects become structs with json, description, enum, nullable and keyword
+// tags; properties that are not required are tagged omitempty. Objects with
+// an additionalProperties schema become maps, each $defs entry a named type
+// and references to objects pointers. A "date-time" string is a time.Time.
+// An anyOf union of a schema with null, as produced by Strict, becomes an
+// optional pointer field. Other combinators and schemas without a type
+// become any.
+func GenerateGoCode(schema Definition, options CodeOptions) ([]byte, error) {
+	if options.Package == "" || options.TypeName == "" {
+		return nil, fmt.Errorf("the package and type names are required")
+	}
+	g := &codeGenerator{
+		root:     schema,
+		rootName: exportedName(options.TypeName),
+		defs:     make(map[string]string),
+		used:     make(map[string]bool),
+		imports:  make(map[string]bool),
+	}
+	g.used[g.rootName] = true
+	for _, name := range sortedKeys(schema.Defs) {
+		g.defs[name] = g.typeName(exportedName(name))
+	}
+
+	if err := g.declare(g.rootName, schema); err != nil {
+		return nil, err
+	}
+	for _, name := range sortedKeys(schema.Defs) {
+		if err := g.declare(g.defs[name], schema.Defs[name]); err != nil {
+			return nil, fmt.Errorf("$defs/%s: %w", name, err)
+		}
+	}
+	return g.source(options)
+}
+
+type codeGenerator struct {
+	root     Definition
+	rootName string
+	// defs maps the names of the $defs to their Go type names.
+	defs    map[string]string
+	used    map[string]bool
+	imports map[string]bool
+	decls   []string
+}
+
+func (g *codeGenerator) source(options CodeOptions) ([]byte, error) {
+	var src bytes.Buffer
+	if options.Header != "" {
+		fmt.Fprintf(&src, "// %s\n\n", options.Header)
+	}
+	fmt.Fprintf(&src, "package %s\n\n", options.Package)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			imports = append(imports, strconv.Quote(path))
+		}
+		sort.Strings(imports)
+		fmt.Fprintf(&src, "import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	src.WriteString(strings.Join(g.decls, ""))
+	return format.Source(src.Bytes())
+}
+
+// typeName returns a type name derived from name that is not used yet.
+func (g *codeGenerator) typeName(name string) string {
+	unique := name
+	for i := 2; g.used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.used[unique] = true
+	return unique
+}
+
+// declare writes the declaration of the named type described by d.
+func (g *codeGenerator) declare(name string, d Definition) error {
+	// The nested types are declared after the type itself.
+	index := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var decl bytes.Buffer
+	if d.Description != "" {
+		for _, line := range strings.Split(d.Description, "\n") {
+			fmt.Fprintf(&decl, "// %s\n", line)
+		}
+	}
+	var expr string
+	var err error
+	if isStruct(d) {
+		expr, err = g.structType(name, d)
+	} else {
+		expr, err = g.goType(name, d)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(&decl, "type %s %s\n\n", name, expr)
+	g.decls[index] = decl.String()
+	return nil
+}
+
+func isStruct(d Definition) bool {
+	if d.Type != Object {
+		return false
+	}
+	additional, isBool := d.AdditionalProperties.(bool)
+	return len(d.Properties) > 0 || d.AdditionalProperties == nil || (isBool && !additional)
+}
+
+func (g *codeGenerator) structType(name string, d Definition) (string, error) {
+	var fields bytes.Buffer
+	fields.WriteString("struct {\n")
+	usedFields := make(map[string]bool)
+	for _, key := range sortedKeys(d.Properties) {
+		property := d.Properties[key]
+		required := contains(d.Required, key)
+		optionalPointer := false
+		if inner, ok := nullUnionMember(property); ok {
+			property, required, optionalPointer = inner, false, true
+		}
+
+		fieldName := exportedName(key)
+		for i := 2; usedFields[fieldName]; i++ {
+			fieldName = exportedName(key) + strconv.Itoa(i)
+		}
+		usedFields[fieldName] = true
+
+		fieldType, err := g.goType(name+fieldName, property)
+		if err != nil {
+			return "", fmt.Errorf("property %s: %w", key, err)
+		}
+		if (optionalPointer || property.Nullable) && !strings.HasPrefix(fieldType, "*") &&
+			!strings.HasPrefix(fieldType, "[]") && !strings.HasPrefix(fieldType, "map[") && fieldType != "any" {
+			fieldType = "*" + fieldType
+		}
+		tag, err := fieldTag(key, required, property)
+		if err != nil {
+			return "", fmt.Errorf("property %s: %w", key, err)
+		}
+		fmt.Fprintf(&fields, "%s %s %s\n", fieldName, fieldType, tag)
+	}
+	fields.WriteString("}")
+	return fields.String(), nil
+}
+
+// nullUnionMember returns X if d is an anyOf union of X and null.
+func nullUnionMember(d Definition) (Definition, bool) {
+	if d.Type != "" || d.Ref != "" || len(d.AnyOf) != 2 {
+		return Definition{}, false
+	}
+	for i, sub := range d.AnyOf {
+		if sub.Type == Null {
+			member := d.AnyOf[1-i]
+			if member.Description == "" {
+				member.Description = d.Description
+			}
+			return member, member.Type != Null
+		}
+	}
+	return Definition{}, false
+}
+
+// goType returns the Go type of d. Objects are declared as the named type
+// name.
+func (g *codeGenerator) goType(name string, d Definition) (string, error) {
+	if d.Ref != "" {
+		return g.refType(d.Ref)
+	}
+	if len(d.AnyOf) > 0 || len(d.OneOf) > 0 || len(d.AllOf) > 0 {
+		return "any", nil
+	}
+	switch d.Type {
+	case String:
+		if d.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case Integer:
+		return "int", nil
+	case Number:
+		return "float64", nil
+	case Boolean:
+		return "bool", nil
+	case Array:
+		if d.Items == nil {
+			return "[]any", nil
+		}
+		items, err := g.goType(name+"Item", *d.Items)
+		return "[]" + items, err
+	case Object:
+		return g.objectType(name, d)
+	case Null:
+		return "any", nil
+	default:
+		return "any", nil
+	}
+}
+
+func (g *codeGenerator) objectType(name string, d Definition) (string, error) {
+	if isStruct(d) {
+		name = g.typeName(name)
+		if err := g.declare(name, Definition{Type: Object, Properties: d.Properties, Required: d.Required}); err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+	values, err := definitionValue(d.AdditionalProperties)
+	if err != nil {
+		return "", err
+	}
+	valueType, err := g.goType(name+"Value", values)
+	return "map[string]" + valueType, err
+}
+
+// definitionValue converts an additionalProperties value, which is a map
+// when the schema is decoded from JSON, to a Definition.
+func definitionValue(v any) (Definition, error) {
+	switch d := v.(type) {
+	case Definition:
+		return d, nil
+	case *Definition:
+		return *d, nil
+	case bool:
+		return Definition{}, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return Definition{}, err
+		}
+		var definition Definition
+		err = json.Unmarshal(b, &definition)
+		return definition, err
+	}
+}
+
+func (g *codeGenerator) refType(ref string) (string, error) {
+	if ref == "#" {
+		return "*" + g.rootName, nil
+	}
+	name := strings.TrimPrefix(ref, defsRefPrefix)
+	typeName, ok := g.defs[name]
+	if name == ref || !ok {
+		return "", fmt.Errorf("cannot resolve reference %q", ref)
+	}
+	if isStruct(g.root.Defs[name]) {
+		return "*" + typeName, nil
+	}
+	return typeName, nil
+}
+
+// fieldTag returns the struct tag read back by GenerateSchemaForType.
+func fieldTag(key string, required bool, d Definition) (string, error) {
+	jsonTag := key
+	if !required {
+		jsonTag += ",omitempty"
+	}
+	tags := [][2]string{{"json", jsonTag}}
+	add := func(name, value string) {
+		tags = append(tags, [2]string{name, value})
+	}
+	if d.Description != "" {
+		add("description", d.Description)
+	}
+	if len(d.Enum) > 0 {
+		for _, value := range d.Enum {
+			if strings.Contains(value, ",") {
+				return "", fmt.Errorf("enum value %q contains a comma", value)
+			}
+		}
+		add("enum", strings.Join(d.Enum, ","))
+	}
+	if d.Nullable {
+		add("nullable", "true")
+	}
+	for _, bound := range []struct {
+		name  string
+		value *float64
+	}{{"minimum", d.Minimum}, {"maximum", d.Maximum}} {
+		if bound.value != nil {
+			add(bound.name, strconv.FormatFloat(*bound.value, 'g', -1, 64))
+		}
+	}
+	for _, bound := range []struct {
+		name  string
+		value *int
+	}{{"minLength", d.MinLength}, {"maxLength", d.MaxLength}, {"minItems", d.MinItems}, {"maxItems", d.MaxItems}} {
+		if bound.value != nil {
+			add(bound.name, strconv.Itoa(*bound.value))
+		}
+	}
+	if d.Pattern != "" {
+		add("pattern", d.Pattern)
+	}
+	if d.Format != "" && d.Format != "date-time" {
+		add("format", d.Format)
+	}
+	for _, value := range []struct {
+		name  string
+		value any
+	}{{"default", d.Default}, {"const", d.Const}} {
+		if value.value == nil {
+			continue
+		}
+		if s, ok := value.value.(string); ok && d.Type == String {
+			add(value.name, s)
+			continue
+		}
+		b, err := json.Marshal(value.value)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s: %w", value.name, err)
+		}
+		add(value.name, string(b))
+	}
+
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = tag[0] + ":" + strconv.Quote(tag[1])
+	}
+	tag := strings.Join(parts, " ")
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag), nil
+	}
+	return "`" + tag + "`", nil
+}
+
+// exportedName converts a property or definition name to an exported Go
+// identifier, e.g. "user_id" to "UserID".
+func exportedName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	identifier := b.String()
+	if identifier == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(identifier)[0]) {
+		return "X" + identifier
+	}
+	return identifier
+}
@@ -0,0 +1,190 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+const generatedOrderSchema = `{
+	"type": "object",
+	"properties": {
+		"id": {"type": "string", "format": "uuid"},
+		"status": {"type": "string", "enum": ["open", "paid"], "description": "Order status"},
+		"total": {"type": "number", "minimum": 0},
+		"created": {"type": "string", "format": "date-time"},
+		"items": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"properties": {
+					"sku": {"type": "string", "pattern": "^[A-Z]{3}$"},
+					"quantity": {"type": "integer", "default": 1}
+				},
+				"required": ["sku"],
+				"additionalProperties": false
+			}
+		},
+		"metadata": {"type": "object", "additionalProperties": {"type": "string"}},
+		"billing": {"$ref": "#/$defs/GeneratedAddress"},
+		"shipping": {"$ref": "#/$defs/GeneratedAddress", "nullable": true},
+		"parent": {"$ref": "#"}
+	},
+	"required": ["billing", "created", "id", "items", "shipping", "status", "total"],
+	"additionalProperties": false,
+	"$defs": {
+		"GeneratedAddress": {
+			"type": "object",
+			"properties": {"street": {"type": "string"}, "zip_code": {"type": "string", "maxLength": 10}},
+			"required": ["street", "zip_code"],
+			"additionalProperties": false
+		}
+	}
+}`
+
+// The types below are the generated code of generatedOrderSchema.
+const generatedOrderCode = "// Code generated by jsonschemagen from order.json; DO NOT EDIT.\n" + `
+package jsonschema_test
+
+import (
+	"time"
+)
+
+type GeneratedOrder struct {
+	Billing  *GeneratedAddress         ` + "`json:\"billing\"`" + `
+	Created  time.Time                 ` + "`json:\"created\"`" + `
+	ID       string                    ` + "`json:\"id\" format:\"uuid\"`" + `
+	Items    []GeneratedOrderItemsItem ` + "`json:\"items\" minItems:\"1\"`" + `
+	Metadata map[string]string         ` + "`json:\"metadata,omitempty\"`" + `
+	Parent   *GeneratedOrder           ` + "`json:\"parent,omitempty\"`" + `
+	Shipping *GeneratedAddress         ` + "`json:\"shipping\" nullable:\"true\"`" + `
+	Status   string                    ` + "`json:\"status\" description:\"Order status\" enum:\"open,paid\"`" + `
+	Total    float64                   ` + "`json:\"total\" minimum:\"0\"`" + `
+}
+
+type GeneratedOrderItemsItem struct {
+	Quantity int    ` + "`json:\"quantity,omitempty\" default:\"1\"`" + `
+	Sku      string ` + "`json:\"sku\" pattern:\"^[A-Z]{3}$\"`" + `
+}
+
+type GeneratedAddress struct {
+	Street  string ` + "`json:\"street\"`" + `
+	ZipCode string ` + "`json:\"zip_code\" maxLength:\"10\"`" + `
+}
+`
+
+type GeneratedOrder struct {
+	Billing  *GeneratedAddress         `json:"billing"`
+	Created  time.Time                 `json:"created"`
+	ID       string                    `json:"id" format:"uuid"`
+	Items    []GeneratedOrderItemsItem `json:"items" minItems:"1"`
+	Metadata map[string]string         `json:"metadata,omitempty"`
+	Parent   *GeneratedOrder           `json:"parent,omitempty"`
+	Shipping *GeneratedAddress         `json:"shipping" nullable:"true"`
+	Status   string                    `json:"status" description:"Order status" enum:"open,paid"`
+	Total    float64                   `json:"total" minimum:"0"`
+}
+
+type GeneratedOrderItemsItem struct {
+	Quantity int    `json:"quantity,omitempty" default:"1"`
+	Sku      string `json:"sku" pattern:"^[A-Z]{3}$"`
+}
+
+type GeneratedAddress struct {
+	Street  string `json:"street"`
+	ZipCode string `json:"zip_code" maxLength:"10"`
+}
+
+func TestGenerateGoCode(t *testing.T) {
+	var schema jsonschema.Definition
+	if err := json.Unmarshal([]byte(generatedOrderSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := jsonschema.GenerateGoCode(schema, jsonschema.CodeOptions{
+		Package:  "jsonschema_test",
+		TypeName: "GeneratedOrder",
+		Header:   "Code generated by jsonschemagen from order.json; DO NOT EDIT.",
+	})
+	if err != nil {
+		t.Fatalf("GenerateGoCode() error = %v", err)
+	}
+	if string(code) != generatedOrderCode {
+		t.Fatalf("GenerateGoCode() got\n%s\nwant\n%s", code, generatedOrderCode)
+	}
+
+	// The generated types must round-trip to the same schema.
+	generated, err := jsonschema.GenerateSchemaForType(GeneratedOrder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want map[string]any
+	if err = json.Unmarshal([]byte(generatedOrderSchema), &want); err != nil {
+		t.Fatal(err)
+	}
+	if got := structToMap(t, generated); !reflect.DeepEqual(got, want) {
+		t.Errorf("GenerateSchemaForType() got = %v, want %v", got, want)
+	}
+}
+
+func TestGenerateGoCodeStrictSchema(t *testing.T) {
+	schema, err := jsonschema.Strict(jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"city":  {Type: jsonschema.String, Description: "The `city` name"},
+			"units": {Type: jsonschema.Array, Items: &jsonschema.Definition{Type: jsonschema.String}},
+			"days":  {Type: jsonschema.Integer},
+		},
+		Required: []string{"city"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := jsonschema.GenerateGoCode(schema, jsonschema.CodeOptions{Package: "tools", TypeName: "weather_args"})
+	if err != nil {
+		t.Fatalf("GenerateGoCode() error = %v", err)
+	}
+	for _, field := range []string{
+		"City  string   \"json:\\\"city\\\" description:\\\"The `city` name\\\"\"",
+		"Days  *int     `json:\"days,omitempty\"`",
+		"Units []string `json:\"units,omitempty\"`",
+	} {
+		if !strings.Contains(string(code), field) {
+			t.Errorf("expected the field %s in\n%s", field, code)
+		}
+	}
+	if !strings.Contains(string(code), "type WeatherArgs struct") {
+		t.Errorf("expected the WeatherArgs type in\n%s", code)
+	}
+}
+
+func TestGenerateGoCodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  jsonschema.Definition
+		options jsonschema.CodeOptions
+	}{
+		{"missing type name", jsonschema.Definition{Type: jsonschema.Object}, jsonschema.CodeOptions{Package: "p"}},
+		{"unresolved reference", jsonschema.Definition{
+			Type:       jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{"a": {Ref: "#/$defs/missing"}},
+		}, jsonschema.CodeOptions{Package: "p", TypeName: "T"}},
+		{"enum value with a comma", jsonschema.Definition{
+			Type:       jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{"a": {Type: jsonschema.String, Enum: []string{"a,b"}}},
+		}, jsonschema.CodeOptions{Package: "p", TypeName: "T"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := jsonschema.GenerateGoCode(tt.schema, tt.options); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -5,11 +5,14 @@
 package jsonschema
 
 import (
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type DataType string
@@ -48,6 +51,50 @@ type Definition struct {
 	AdditionalProperties any `json:"additionalProperties,omitempty"`
 	// Whether the schema is nullable or not.
 	Nullable bool `json:"nullable,omitempty"`
+
+	// Ref references another schema, e.g. "#/$defs/Address". References are
+	// resolved against the $defs of the root schema.
+	Ref string `json:"$ref,omitempty"`
+	// Defs holds the reusable schemas that can be referenced with Ref.
+	Defs map[string]Definition `json:"$defs,omitempty"`
+	// AnyOf requires the value to match at least one of the schemas.
+	AnyOf []Definition `json:"anyOf,omitempty"`
+	// OneOf requires the value to match exactly one of the schemas.
+	OneOf []Definition `json:"oneOf,omitempty"`
+	// AllOf requires the value to match all the schemas.
+	AllOf []Definition `json:"allOf,omitempty"`
+	// Const restricts the value to a single value.
+	Const any `json:"const,omitempty"`
+
+	// Minimum and Maximum are inclusive bounds of a number.
+	Minimum *float64 `json:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty"`
+	// MinLength and MaxLength bound the number of characters of a string.
+	MinLength *int `json:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty"`
+	// Pattern is a regular expression a string must match. It uses the RE2
+	// syntax of the regexp package and is not anchored.
+	Pattern string `json:"pattern,omitempty"`
+	// MinItems and MaxItems bound the number of items of an array.
+	MinItems *int `json:"minItems,omitempty"`
+	MaxItems *int `json:"maxItems,omitempty"`
+	// Format is a semantic format of a string such as "date-time", "date",
+	// "time", "email", "uri", "uuid", "ipv4" or "ipv6". Unknown formats are
+	// not validated.
+	Format string `json:"format,omitempty"`
+	// Default is the value assumed when the property is absent. It is not
+	// used for validation.
+	Default any `json:"default,omitempty"`
+}
+
+// Float returns a pointer to v, to set Minimum and Maximum.
+func Float(v float64) *float64 {
+	return &v
+}
+
+// Int returns a pointer to v, to set the length and item count bounds.
+func Int(v int) *int {
+	return &v
 }
 
 func (d *Definition) MarshalJSON() ([]byte, error) {
@@ -66,11 +113,88 @@ func (d *Definition) Unmarshal(content string, v any) error {
 	return VerifySchemaAndUnmarshal(*d, []byte(content), v)
 }
 
+// SchemaProvider is implemented by types that describe their own schema.
+// GenerateSchemaForType uses the definition returned by JSONSchema instead of
+// reflecting the type.
+type SchemaProvider interface {
+	JSONSchema() Definition
+}
+
+const defsRefPrefix = "#/$defs/"
+
+var (
+	schemaProviderType = reflect.TypeOf((*SchemaProvider)(nil)).Elem()
+	jsonMarshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	timeType           = reflect.TypeOf(time.Time{})
+	rawMessageType     = reflect.TypeOf(json.RawMessage{})
+
+	defNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+)
+
+// GenerateSchemaForType generates the schema of the type of v, following the
+// rules of encoding/json: anonymous struct fields are flattened, maps become
+// objects with additionalProperties and types implementing json.Marshaler
+// accept any value unless they implement SchemaProvider. time.Time is a
+// "date-time" string and types named UUID are "uuid" strings.
+//
+// Named struct types that are recursive or used more than once are added to
+// $defs and referenced with $ref; a reference to the type of v itself is "#".
+// Other types are inlined.
 func GenerateSchemaForType(v any) (*Definition, error) {
-	return reflectSchema(reflect.TypeOf(v))
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("unsupported type: %s", reflect.Invalid)
+	}
+	g := &schemaGenerator{
+		defs:       make(map[string]Definition),
+		names:      make(map[reflect.Type]string),
+		used:       make(map[string]bool),
+		refs:       make(map[string]int),
+		recursive:  make(map[string]bool),
+		inProgress: make(map[reflect.Type]bool),
+	}
+	if root := derefType(t); root.Kind() == reflect.Struct && root.Name() != "" {
+		g.root = root
+	}
+
+	d, err := g.reflect(t)
+	if err != nil {
+		return nil, err
+	}
+	g.inline(d)
+	for name, def := range g.defs {
+		if !g.shared(name) {
+			continue
+		}
+		g.inline(&def)
+		if d.Defs == nil {
+			d.Defs = make(map[string]Definition)
+		}
+		d.Defs[name] = def
+	}
+	return d, nil
+}
+
+type schemaGenerator struct {
+	// root is the named struct type of the generated schema, if any.
+	root reflect.Type
+	// defs holds the object schema of every named struct type by name.
+	defs  map[string]Definition
+	names map[reflect.Type]string
+	used  map[string]bool
+	// refs counts the references to each definition.
+	refs       map[string]int
+	recursive  map[string]bool
+	inProgress map[reflect.Type]bool
 }
 
-func reflectSchema(t reflect.Type) (*Definition, error) {
+func (g *schemaGenerator) reflect(t reflect.Type) (*Definition, error) {
+	t = derefType(t)
+	if d, ok := reflectSpecial(t); ok {
+		return d, nil
+	}
+
 	var d Definition
 	switch t.Kind() {
 	case reflect.String:
@@ -83,60 +207,163 @@ func reflectSchema(t reflect.Type) (*Definition, error) {
 	case reflect.Bool:
 		d.Type = Boolean
 	case reflect.Slice, reflect.Array:
+		// encoding/json encodes byte slices as base64 strings.
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 && !marshalsItself(t.Elem()) {
+			d.Type = String
+			break
+		}
 		d.Type = Array
-		items, err := reflectSchema(t.Elem())
+		items, err := g.reflect(t.Elem())
 		if err != nil {
 			return nil, err
 		}
 		d.Items = items
-	case reflect.Struct:
-		d.Type = Object
-		d.AdditionalProperties = false
-		object, err := reflectSchemaObject(t)
-		if err != nil {
-			return nil, err
+	case reflect.Map:
+		if !validMapKey(t.Key()) {
+			return nil, fmt.Errorf("unsupported map key type: %s", t.Key())
 		}
-		d = *object
-	case reflect.Ptr:
-		definition, err := reflectSchema(t.Elem())
+		values, err := g.reflect(t.Elem())
 		if err != nil {
 			return nil, err
 		}
-		d = *definition
+		d.Type = Object
+		d.AdditionalProperties = *values
+	case reflect.Interface:
+		// Any value is accepted.
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.reflectObject(t)
+		}
+		return g.reflectNamed(t)
 	case reflect.Invalid, reflect.Uintptr, reflect.Complex64, reflect.Complex128,
-		reflect.Chan, reflect.Func, reflect.Interface, reflect.Map,
-		reflect.UnsafePointer:
+		reflect.Chan, reflect.Func, reflect.Ptr, reflect.UnsafePointer:
 		return nil, fmt.Errorf("unsupported type: %s", t.Kind().String())
 	default:
 	}
 	return &d, nil
 }
 
-func reflectSchemaObject(t reflect.Type) (*Definition, error) {
+// reflectSpecial returns the schema of the types that describe or marshal
+// themselves and of well-known types.
+func reflectSpecial(t reflect.Type) (*Definition, bool) {
+	switch {
+	case implements(t, schemaProviderType):
+		d := reflect.New(t).Interface().(SchemaProvider).JSONSchema()
+		return &d, true
+	case t == timeType:
+		return &Definition{Type: String, Format: "date-time"}, true
+	case t == rawMessageType, implements(t, jsonMarshalerType):
+		// The encoding is unknown, so any value is accepted.
+		return &Definition{}, true
+	case implements(t, textMarshalerType), isUUID(t) && t.Kind() == reflect.String:
+		d := &Definition{Type: String}
+		if isUUID(t) {
+			d.Format = "uuid"
+		}
+		return d, true
+	default:
+		return nil, false
+	}
+}
+
+// reflectNamed returns a reference to the definition of a named struct type.
+func (g *schemaGenerator) reflectNamed(t reflect.Type) (*Definition, error) {
+	if t == g.root && g.inProgress[t] {
+		return &Definition{Ref: "#"}, nil
+	}
+	if t == g.root {
+		g.inProgress[t] = true
+		defer delete(g.inProgress, t)
+		return g.reflectObject(t)
+	}
+
+	name := g.defName(t)
+	g.refs[name]++
+	ref := &Definition{Ref: defsRefPrefix + name}
+	if g.inProgress[t] {
+		g.recursive[name] = true
+		return ref, nil
+	}
+	if _, done := g.defs[name]; done {
+		return ref, nil
+	}
+	g.inProgress[t] = true
+	d, err := g.reflectObject(t)
+	delete(g.inProgress, t)
+	if err != nil {
+		return nil, err
+	}
+	g.defs[name] = *d
+	return ref, nil
+}
+
+func (g *schemaGenerator) defName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	base := defNameInvalidChars.ReplaceAllString(t.Name(), "_")
+	name := base
+	for i := 2; g.used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	g.names[t] = name
+	g.used[name] = true
+	return name
+}
+
+// shared reports whether the definition must be kept in $defs.
+func (g *schemaGenerator) shared(name string) bool {
+	return g.recursive[name] || g.refs[name] > 1
+}
+
+// inline replaces the references to the definitions that are not shared by
+// the definitions themselves.
+func (g *schemaGenerator) inline(d *Definition) {
+	if name := strings.TrimPrefix(d.Ref, defsRefPrefix); name != d.Ref && !g.shared(name) {
+		if def, ok := g.defs[name]; ok {
+			mergeDefinition(&def, *d)
+			*d = def
+		}
+	}
+	for key, property := range d.Properties {
+		g.inline(&property)
+		d.Properties[key] = property
+	}
+	if d.Items != nil {
+		g.inline(d.Items)
+	}
+	if additional, ok := d.AdditionalProperties.(Definition); ok {
+		g.inline(&additional)
+		d.AdditionalProperties = additional
+	}
+	for _, definitions := range [][]Definition{d.AnyOf, d.OneOf, d.AllOf} {
+		for i := range definitions {
+			g.inline(&definitions[i])
+		}
+	}
+}
+
+// mergeDefinition sets the fields of dst that are set in src, such as the
+// description of a property, except Ref.
+func mergeDefinition(dst *Definition, src Definition) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src)
+	for i := 0; i < sv.NumField(); i++ {
+		if field := sv.Field(i); !field.IsZero() && sv.Type().Field(i).Name != "Ref" {
+			dv.Field(i).Set(field)
+		}
+	}
+}
+
+func (g *schemaGenerator) reflectObject(t reflect.Type) (*Definition, error) {
 	var d = Definition{
 		Type:                 Object,
 		AdditionalProperties: false,
 	}
 	properties := make(map[string]Definition)
 	var requiredFields []string
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if !field.IsExported() {
-			continue
-		}
-		jsonTag := field.Tag.Get("json")
-		var required = true
-		switch {
-		case jsonTag == "-":
-			continue
-		case jsonTag == "":
-			jsonTag = field.Name
-		case strings.HasSuffix(jsonTag, ",omitempty"):
-			jsonTag = strings.TrimSuffix(jsonTag, ",omitempty")
-			required = false
-		}
-
-		item, err := reflectSchema(field.Type)
+	for _, field := range structFields(t) {
+		item, err := g.reflect(field.Type)
 		if err != nil {
 			return nil, err
 		}
@@ -153,17 +380,207 @@ func reflectSchemaObject(t reflect.Type) (*Definition, error) {
 			nullable, _ := strconv.ParseBool(n)
 			item.Nullable = nullable
 		}
+		if err = applyKeywordTags(item, field.Tag); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
 
-		properties[jsonTag] = *item
+		properties[field.name] = *item
 
+		required := !field.omitEmpty
 		if s := field.Tag.Get("required"); s != "" {
 			required, _ = strconv.ParseBool(s)
 		}
 		if required {
-			requiredFields = append(requiredFields, jsonTag)
+			requiredFields = append(requiredFields, field.name)
 		}
 	}
 	d.Required = requiredFields
 	d.Properties = properties
 	return &d, nil
 }
+
+// structField is a field of a struct as encoded by encoding/json.
+type structField struct {
+	reflect.StructField
+	name      string
+	omitEmpty bool
+	tagged    bool
+	depth     int
+	// seq is the position of the field among all the collected fields.
+	seq int
+}
+
+// structFields returns the fields encoded by encoding/json in their order:
+// the fields of anonymous struct fields are promoted, and a name declared by
+// several fields belongs to the shallowest one, or to the only tagged one at
+// that depth, otherwise to none.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	collectFields(t, 0, map[reflect.Type]bool{}, &fields)
+
+	byName := make(map[string][]structField)
+	for _, field := range fields {
+		byName[field.name] = append(byName[field.name], field)
+	}
+	var result []structField
+	for _, field := range fields {
+		if dominant, ok := dominantField(byName[field.name]); ok && dominant.seq == field.seq {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+func collectFields(t reflect.Type, depth int, visited map[reflect.Type]bool, fields *[]structField) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldType := derefType(field.Type)
+		if field.Anonymous {
+			if !field.IsExported() && fieldType.Kind() != reflect.Struct {
+				continue
+			}
+		} else if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" && field.Anonymous && fieldType.Kind() == reflect.Struct {
+			collectFields(fieldType, depth+1, visited, fields)
+			continue
+		}
+		f := structField{StructField: field, name: name, tagged: name != "", depth: depth, seq: len(*fields)}
+		if name == "" {
+			f.name = field.Name
+		}
+		for _, option := range strings.Split(options, ",") {
+			f.omitEmpty = f.omitEmpty || option == "omitempty"
+		}
+		*fields = append(*fields, f)
+	}
+}
+
+func dominantField(fields []structField) (structField, bool) {
+	var candidates []structField
+	for _, field := range fields {
+		switch {
+		case len(candidates) == 0 || field.depth < candidates[0].depth:
+			candidates = []structField{field}
+		case field.depth == candidates[0].depth:
+			candidates = append(candidates, field)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	var tagged []structField
+	for _, field := range candidates {
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return structField{}, false
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func implements(t, iface reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
+
+func marshalsItself(t reflect.Type) bool {
+	return implements(t, jsonMarshalerType) || implements(t, textMarshalerType)
+}
+
+func validMapKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return implements(t, textMarshalerType)
+	}
+}
+
+func isUUID(t reflect.Type) bool {
+	return strings.EqualFold(t.Name(), "uuid")
+}
+
+// applyKeywordTags sets the validation keywords given as struct tags:
+// minimum, maximum, minLength, maxLength, pattern, minItems, maxItems,
+// format, default and const.
+func applyKeywordTags(d *Definition, tag reflect.StructTag) error {
+	for _, bound := range []struct {
+		name string
+		dst  **float64
+	}{{"minimum", &d.Minimum}, {"maximum", &d.Maximum}} {
+		if v := tag.Get(bound.name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s tag %q: %w", bound.name, v, err)
+			}
+			*bound.dst = &f
+		}
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **int
+	}{{"minLength", &d.MinLength}, {"maxLength", &d.MaxLength}, {"minItems", &d.MinItems}, {"maxItems", &d.MaxItems}} {
+		if v := tag.Get(bound.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s tag %q: %w", bound.name, v, err)
+			}
+			*bound.dst = &n
+		}
+	}
+	if v := tag.Get("pattern"); v != "" {
+		if _, err := regexp.Compile(v); err != nil {
+			return fmt.Errorf("invalid pattern tag %q: %w", v, err)
+		}
+		d.Pattern = v
+	}
+	if v := tag.Get("format"); v != "" {
+		d.Format = v
+	}
+	if v, ok := tag.Lookup("default"); ok {
+		d.Default = tagValue(d.Type, v)
+	}
+	if v, ok := tag.Lookup("const"); ok {
+		d.Const = tagValue(d.Type, v)
+	}
+	return nil
+}
+
+// tagValue converts a default or const tag to a value of the schema type.
+// Non-string values are parsed as JSON and kept as strings if that fails.
+func tagValue(t DataType, v string) any {
+	if t == String {
+		return v
+	}
+	var value any
+	if err := json.Unmarshal([]byte(v), &value); err != nil {
+		return v
+	}
+	return value
+}
@@ -3,7 +3,9 @@ package jsonschema_test
 import (
 	"encoding/json"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/edmondfrank/go-giteeai/jsonschema"
 )
@@ -376,6 +378,28 @@ func TestStructToSchema(t *testing.T) {
 				"additionalProperties":false
 			}`,
 		},
+		{
+			name: "Test with validation keyword tags",
+			in: struct {
+				Age   int      `json:"age" minimum:"0" maximum:"150" default:"18"`
+				Code  string   `json:"code" minLength:"2" maxLength:"3" pattern:"^[A-Z]+$" default:"42"`
+				Email string   `json:"email" format:"email"`
+				Kind  string   `json:"kind" const:"person"`
+				Tags  []string `json:"tags" minItems:"1" maxItems:"5"`
+			}{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"age":{"type":"integer","minimum":0,"maximum":150,"default":18},
+					"code":{"type":"string","minLength":2,"maxLength":3,"pattern":"^[A-Z]+$","default":"42"},
+					"email":{"type":"string","format":"email"},
+					"kind":{"type":"string","const":"person"},
+					"tags":{"type":"array","items":{"type":"string"},"minItems":1,"maxItems":5}
+				},
+				"required":["age","code","email","kind","tags"],
+				"additionalProperties":false
+			}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -408,6 +432,294 @@ func TestStructToSchema(t *testing.T) {
 	}
 }
 
+func TestDefinition_MarshalJSONKeywords(t *testing.T) {
+	def := jsonschema.Definition{
+		Defs: map[string]jsonschema.Definition{
+			"name": {Type: jsonschema.String, MinLength: jsonschema.Int(1)},
+		},
+		AnyOf: []jsonschema.Definition{
+			{Ref: "#/$defs/name"},
+			{Type: jsonschema.Null},
+		},
+	}
+	want := map[string]any{
+		"$defs": map[string]any{"name": map[string]any{"type": "string", "minLength": float64(1)}},
+		"anyOf": []any{map[string]any{"$ref": "#/$defs/name"}, map[string]any{"type": "null"}},
+	}
+	if got := structToMap(t, &def); !reflect.DeepEqual(got, want) {
+		t.Errorf("MarshalJSON() got = %v, want %v", got, want)
+	}
+}
+
+func TestStructToSchemaInvalidTag(t *testing.T) {
+	_, err := jsonschema.GenerateSchemaForType(struct {
+		Age int `json:"age" minimum:"zero"`
+	}{})
+	if err == nil {
+		t.Error("expected an error for an invalid minimum tag")
+	}
+}
+
+type treeNode struct {
+	Name     string      `json:"name"`
+	Children []*treeNode `json:"children"`
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+type contacts struct {
+	Home address  `json:"home"`
+	Work *address `json:"work" description:"Work address" nullable:"true"`
+	Ship struct {
+		To address `json:"to"`
+	} `json:"ship"`
+}
+
+type Base struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+}
+
+type timestamps struct {
+	Created time.Time `json:"created"`
+}
+
+type embedding struct {
+	Base
+	*timestamps
+	Kind  int `json:"kind"`
+	Extra int `json:"extra,omitempty"`
+}
+
+type color int
+
+func (color) JSONSchema() jsonschema.Definition {
+	return jsonschema.Definition{Type: jsonschema.String, Enum: []string{"red", "green"}}
+}
+
+type opaque struct {
+	Secret string
+}
+
+func (opaque) MarshalJSON() ([]byte, error) {
+	return []byte(`"hidden"`), nil
+}
+
+type UUID [16]byte
+
+func (UUID) MarshalText() ([]byte, error) {
+	return []byte("00000000-0000-0000-0000-000000000000"), nil
+}
+
+func TestStructToSchemaTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		in   any
+		want string
+	}{
+		{
+			name: "recursive root type",
+			in:   treeNode{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"name":{"type":"string"},
+					"children":{"type":"array","items":{"$ref":"#"}}
+				},
+				"required":["name","children"],
+				"additionalProperties":false
+			}`,
+		},
+		{
+			name: "recursive nested type",
+			in: struct {
+				Root treeNode `json:"root"`
+			}{},
+			want: `{
+				"type":"object",
+				"properties":{"root":{"$ref":"#/$defs/treeNode"}},
+				"required":["root"],
+				"additionalProperties":false,
+				"$defs":{
+					"treeNode":{
+						"type":"object",
+						"properties":{
+							"name":{"type":"string"},
+							"children":{"type":"array","items":{"$ref":"#/$defs/treeNode"}}
+						},
+						"required":["name","children"],
+						"additionalProperties":false
+					}
+				}
+			}`,
+		},
+		{
+			name: "shared type",
+			in:   contacts{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"home":{"$ref":"#/$defs/address"},
+					"work":{"$ref":"#/$defs/address","description":"Work address","nullable":true},
+					"ship":{
+						"type":"object",
+						"properties":{"to":{"$ref":"#/$defs/address"}},
+						"required":["to"],
+						"additionalProperties":false
+					}
+				},
+				"required":["home","work","ship"],
+				"additionalProperties":false,
+				"$defs":{
+					"address":{
+						"type":"object",
+						"properties":{"city":{"type":"string"}},
+						"required":["city"],
+						"additionalProperties":false
+					}
+				}
+			}`,
+		},
+		{
+			name: "type used once is inlined",
+			in: struct {
+				Home address `json:"home" description:"Home address"`
+			}{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"home":{
+						"type":"object",
+						"description":"Home address",
+						"properties":{"city":{"type":"string"}},
+						"required":["city"],
+						"additionalProperties":false
+					}
+				},
+				"required":["home"],
+				"additionalProperties":false
+			}`,
+		},
+		{
+			name: "maps and interfaces",
+			in: struct {
+				Scores map[string]int     `json:"scores"`
+				Labels map[int]string     `json:"labels"`
+				Extra  map[string]any     `json:"extra"`
+				Nested map[string]address `json:"nested"`
+			}{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"scores":{"type":"object","additionalProperties":{"type":"integer"}},
+					"labels":{"type":"object","additionalProperties":{"type":"string"}},
+					"extra":{"type":"object","additionalProperties":{}},
+					"nested":{"type":"object","additionalProperties":{
+						"type":"object",
+						"properties":{"city":{"type":"string"}},
+						"required":["city"],
+						"additionalProperties":false
+					}}
+				},
+				"required":["scores","labels","extra","nested"],
+				"additionalProperties":false
+			}`,
+		},
+		{
+			name: "embedded structs",
+			in:   embedding{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"id":{"type":"string"},
+					"created":{"type":"string","format":"date-time"},
+					"kind":{"type":"integer"},
+					"extra":{"type":"integer"}
+				},
+				"required":["id","created","kind"],
+				"additionalProperties":false
+			}`,
+		},
+		{
+			name: "marshalers and well-known types",
+			in: struct {
+				Color   color           `json:"color"`
+				Opaque  opaque          `json:"opaque"`
+				Raw     json.RawMessage `json:"raw"`
+				Data    []byte          `json:"data"`
+				ID      UUID            `json:"id"`
+				Created *time.Time      `json:"created"`
+			}{},
+			want: `{
+				"type":"object",
+				"properties":{
+					"color":{"type":"string","enum":["red","green"]},
+					"opaque":{},
+					"raw":{},
+					"data":{"type":"string"},
+					"id":{"type":"string","format":"uuid"},
+					"created":{"type":"string","format":"date-time"}
+				},
+				"required":["color","opaque","raw","data","id","created"],
+				"additionalProperties":false
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := jsonschema.GenerateSchemaForType(tt.in)
+			if err != nil {
+				t.Fatalf("Failed to generate schema: error = %v", err)
+			}
+			var want map[string]any
+			if err = json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("Failed to Unmarshal JSON: error = %v", err)
+			}
+			if got := structToMap(t, schema); !reflect.DeepEqual(got, want) {
+				t.Errorf("GenerateSchemaForType() got = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStructToSchemaRecursiveValidation(t *testing.T) {
+	schema, err := jsonschema.GenerateSchemaForType(struct {
+		Root treeNode `json:"root"`
+	}{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: error = %v", err)
+	}
+
+	valid := `{"root":{"name":"a","children":[{"name":"b","children":[]}]}}`
+	var v struct {
+		Root treeNode `json:"root"`
+	}
+	if err = schema.Unmarshal(valid, &v); err != nil || v.Root.Children[0].Name != "b" {
+		t.Fatalf("expected the tree to be valid, got %v", err)
+	}
+
+	invalid := `{"root":{"name":"a","children":[{"name":1,"children":[]}]}}`
+	err = schema.Unmarshal(invalid, &v)
+	if err == nil || !strings.Contains(err.Error(), "/root/children/0/name: expected string, got integer") {
+		t.Fatalf("expected an error for the nested name, got %v", err)
+	}
+}
+
+func TestStructToSchemaUnsupported(t *testing.T) {
+	for _, in := range []any{
+		nil,
+		struct{ C chan int }{},
+		struct{ M map[[2]int]string }{},
+	} {
+		if _, err := jsonschema.GenerateSchemaForType(in); err == nil {
+			t.Errorf("expected an error for %T", in)
+		}
+	}
+}
+
 func structToMap(t *testing.T, v any) map[string]any {
 	t.Helper()
 	gotBytes, err := json.Marshal(v)
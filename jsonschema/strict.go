@@ -0,0 +1,178 @@
+package jsonschema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StrictError describes a construct of a schema that cannot be expressed in
+// strict mode.
+type StrictError struct {
+	// Path is the JSON Pointer (RFC 6901) of the construct within the schema,
+	// "" for the root schema.
+	Path string
+	// Keyword is the offending schema keyword, e.g. "additionalProperties".
+	Keyword string
+	// Message is a human readable description of the problem.
+	Message string
+}
+
+func (e *StrictError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// StrictErrors lists all the constructs of a schema that cannot be made strict.
+type StrictErrors []*StrictError
+
+func (e StrictErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Strict rewrites schema into the form required by strict mode, where the
+// root is an object, every property is required and no object accepts
+// additional properties:
+//
+//   - additionalProperties is set to false on every object;
+//   - optional properties become required and accept null through an
+//     anyOf union with {"type":"null"};
+//   - nullable schemas are expressed with the same union.
+//
+// Schemas accepting any value, maps, arrays without items, oneOf and allOf
+// cannot be made strict and are reported as StrictErrors. Strict is
+// idempotent and does not modify schema.
+func Strict(schema Definition) (Definition, error) {
+	s := &strictNormalizer{}
+	if schema.Type != Object {
+		s.fail("", "type", "the root schema must be an object, got %q", schema.Type)
+	}
+	strict := s.normalize(schema, "")
+	if len(s.errs) > 0 {
+		return Definition{}, s.errs
+	}
+	return strict, nil
+}
+
+type strictNormalizer struct {
+	errs StrictErrors
+}
+
+func (s *strictNormalizer) fail(path, keyword, format string, args ...any) {
+	s.errs = append(s.errs, &StrictError{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+}
+
+func (s *strictNormalizer) normalize(d Definition, path string) Definition {
+	if d.Nullable {
+		d.Nullable = false
+		return nullableUnion(s.normalize(d, path))
+	}
+
+	if d.Type == "" && d.Ref == "" && len(d.AnyOf) == 0 && len(d.OneOf) == 0 && len(d.AllOf) == 0 &&
+		len(d.Enum) == 0 && d.Const == nil && len(d.Properties) == 0 {
+		s.fail(path, "type", "a schema accepting any value is not allowed")
+	}
+	if len(d.OneOf) > 0 {
+		s.fail(path, "oneOf", "oneOf is not supported, use anyOf")
+	}
+	if len(d.AllOf) > 0 {
+		s.fail(path, "allOf", "allOf is not supported")
+	}
+
+	if d.Type == Object || len(d.Properties) > 0 {
+		d = s.normalizeObject(d, path)
+	}
+	if d.Type == Array {
+		if d.Items == nil {
+			s.fail(path, "items", "arrays must declare their items")
+		} else {
+			items := s.normalize(*d.Items, path+"/items")
+			d.Items = &items
+		}
+	}
+	if d.AnyOf != nil {
+		anyOf := make([]Definition, len(d.AnyOf))
+		for i, sub := range d.AnyOf {
+			anyOf[i] = s.normalize(sub, fmt.Sprintf("%s/anyOf/%d", path, i))
+		}
+		d.AnyOf = anyOf
+	}
+	if d.Defs != nil {
+		defs := make(map[string]Definition, len(d.Defs))
+		for _, name := range sortedKeys(d.Defs) {
+			defs[name] = s.normalize(d.Defs[name], path+"/$defs/"+pointerEscaper.Replace(name))
+		}
+		d.Defs = defs
+	}
+	return d
+}
+
+func (s *strictNormalizer) normalizeObject(d Definition, path string) Definition {
+	switch additional := d.AdditionalProperties.(type) {
+	case nil:
+	case bool:
+		if additional {
+			s.fail(path, "additionalProperties", "additional properties are not allowed")
+		}
+	default:
+		s.fail(path, "additionalProperties", "maps are not supported, declare the properties instead")
+	}
+	d.AdditionalProperties = false
+
+	required := make([]string, 0, len(d.Properties))
+	for _, name := range d.Required {
+		if _, ok := d.Properties[name]; ok {
+			required = append(required, name)
+		}
+	}
+	properties := make(map[string]Definition, len(d.Properties))
+	for _, name := range sortedKeys(d.Properties) {
+		property := s.normalize(d.Properties[name], path+"/properties/"+pointerEscaper.Replace(name))
+		if !contains(required, name) {
+			property = nullableUnion(property)
+			required = append(required, name)
+		}
+		properties[name] = property
+	}
+	d.Properties = properties
+	d.Required = required
+	return d
+}
+
+// nullableUnion returns a schema accepting the values of d and null.
+func nullableUnion(d Definition) Definition {
+	if d.Type == Null {
+		return d
+	}
+	for _, sub := range d.AnyOf {
+		if sub.Type == Null {
+			return d
+		}
+	}
+	if d.Type == "" && d.Ref == "" && len(d.AnyOf) > 0 {
+		d.AnyOf = append(append([]Definition(nil), d.AnyOf...), Definition{Type: Null})
+		return d
+	}
+	description := d.Description
+	d.Description = ""
+	return Definition{
+		Description: description,
+		AnyOf:       []Definition{d, {Type: Null}},
+	}
+}
+
+func sortedKeys(definitions map[string]Definition) []string {
+	keys := make([]string, 0, len(definitions))
+	for key := range definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,143 @@
+package jsonschema_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+func TestStrict(t *testing.T) {
+	schema := jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"name":     {Type: jsonschema.String},
+			"nickname": {Type: jsonschema.String, Description: "Optional nickname"},
+			"age":      {Type: jsonschema.Integer, Nullable: true},
+			"address": {
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"city": {Type: jsonschema.String},
+					"zip":  {Type: jsonschema.String},
+				},
+				Required: []string{"city"},
+			},
+			"friend": {Ref: "#/$defs/person"},
+		},
+		Required: []string{"name", "age", "address"},
+		Defs: map[string]jsonschema.Definition{
+			"person": {
+				Type:       jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{"name": {Type: jsonschema.String}},
+			},
+		},
+	}
+	want := `{
+		"type":"object",
+		"properties":{
+			"name":{"type":"string"},
+			"nickname":{"description":"Optional nickname","anyOf":[{"type":"string"},{"type":"null"}]},
+			"age":{"anyOf":[{"type":"integer"},{"type":"null"}]},
+			"address":{
+				"type":"object",
+				"properties":{
+					"city":{"type":"string"},
+					"zip":{"anyOf":[{"type":"string"},{"type":"null"}]}
+				},
+				"required":["city","zip"],
+				"additionalProperties":false
+			},
+			"friend":{"anyOf":[{"$ref":"#/$defs/person"},{"type":"null"}]}
+		},
+		"required":["name","age","address","friend","nickname"],
+		"additionalProperties":false,
+		"$defs":{
+			"person":{
+				"type":"object",
+				"properties":{"name":{"anyOf":[{"type":"string"},{"type":"null"}]}},
+				"required":["name"],
+				"additionalProperties":false
+			}
+		}
+	}`
+
+	strict, err := jsonschema.Strict(schema)
+	if err != nil {
+		t.Fatalf("Strict() error = %v", err)
+	}
+	var wantMap map[string]any
+	if err = json.Unmarshal([]byte(want), &wantMap); err != nil {
+		t.Fatal(err)
+	}
+	if got := structToMap(t, strict); !reflect.DeepEqual(got, wantMap) {
+		t.Errorf("Strict() got = %v, want %v", got, wantMap)
+	}
+	if _, ok := schema.Properties["nickname"]; !ok || schema.AdditionalProperties != nil || len(schema.Required) != 3 {
+		t.Error("Strict() modified its argument")
+	}
+
+	again, err := jsonschema.Strict(strict)
+	if err != nil || !reflect.DeepEqual(structToMap(t, again), wantMap) {
+		t.Errorf("Strict() is not idempotent: %v", err)
+	}
+}
+
+func TestStrictGeneratedSchema(t *testing.T) {
+	schema, err := jsonschema.GenerateSchemaForType(struct {
+		Name string `json:"name"`
+		Note string `json:"note,omitempty"`
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := jsonschema.Strict(*schema)
+	if err != nil {
+		t.Fatalf("Strict() error = %v", err)
+	}
+	if !jsonschema.Validate(strict, map[string]any{"name": "a", "note": nil}) {
+		t.Error("expected null to be accepted for an optional property")
+	}
+	if jsonschema.Validate(strict, map[string]any{"name": "a"}) {
+		t.Error("expected every property to be required")
+	}
+}
+
+func TestStrictErrors(t *testing.T) {
+	schema := jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"any":    {},
+			"labels": {Type: jsonschema.Object, AdditionalProperties: jsonschema.Definition{Type: jsonschema.String}},
+			"list":   {Type: jsonschema.Array},
+			"choice": {OneOf: []jsonschema.Definition{{Type: jsonschema.String}, {Type: jsonschema.Integer}}},
+		},
+		AdditionalProperties: true,
+	}
+
+	_, err := jsonschema.Strict(schema)
+	var errs jsonschema.StrictErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected StrictErrors, got %v", err)
+	}
+	want := []struct{ path, keyword string }{
+		{"", "additionalProperties"},
+		{"/properties/any", "type"},
+		{"/properties/choice", "oneOf"},
+		{"/properties/labels", "additionalProperties"},
+		{"/properties/list", "items"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i, w := range want {
+		if errs[i].Path != w.path || errs[i].Keyword != w.keyword {
+			t.Errorf("error %d = %+v, want %+v", i, errs[i], w)
+		}
+	}
+
+	if _, err = jsonschema.Strict(jsonschema.Definition{Type: jsonschema.String}); err == nil {
+		t.Error("expected an error for a root schema that is not an object")
+	}
+}
@@ -2,85 +2,426 @@ package jsonschema
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// ValidationError describes a value that does not conform to a schema.
+type ValidationError struct {
+	// Path is the JSON Pointer (RFC 6901) of the value, "" for the document root.
+	Path string
+	// Keyword is the schema keyword that failed, e.g. "type" or "minLength".
+	Keyword string
+	// Expected is the constraint of the keyword and Actual the offending
+	// value or property of the value.
+	Expected any
+	Actual   any
+	// Message is a human readable description of the failure.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// ValidationErrors lists all the failures found in a value.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// VerifySchemaAndUnmarshal validates content against schema and unmarshals
+// it into v. Validation failures are returned as ValidationErrors.
 func VerifySchemaAndUnmarshal(schema Definition, content []byte, v any) error {
 	var data any
 	err := json.Unmarshal(content, &data)
 	if err != nil {
 		return err
 	}
-	if !Validate(schema, data) {
-		return errors.New("data validation failed against the provided schema")
+	if errs := ValidateWithErrors(schema, data); len(errs) > 0 {
+		return fmt.Errorf("data validation failed against the provided schema: %w", errs)
 	}
 	return json.Unmarshal(content, &v)
 }
 
+// Validate reports whether data, as decoded by encoding/json, conforms to schema.
 func Validate(schema Definition, data any) bool {
+	return len(ValidateWithErrors(schema, data)) == 0
+}
+
+// ValidateWithErrors returns every failure of data, as decoded by
+// encoding/json, against schema. It returns nil if data conforms.
+func ValidateWithErrors(schema Definition, data any) ValidationErrors {
+	v := &validator{root: schema}
+	v.validate(schema, data, "")
+	return v.errs
+}
+
+type validator struct {
+	// root is used to resolve references.
+	root Definition
+	errs ValidationErrors
+}
+
+func (v *validator) fail(path, keyword string, expected, actual any, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{
+		Path:     path,
+		Keyword:  keyword,
+		Expected: expected,
+		Actual:   actual,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// matches reports whether data conforms to schema without recording errors.
+func (v *validator) matches(schema Definition, data any, path string) bool {
+	sub := &validator{root: v.root}
+	sub.validate(schema, data, path)
+	return len(sub.errs) == 0
+}
+
+func (v *validator) validate(schema Definition, data any, path string) {
+	if data == nil && schema.Nullable {
+		return
+	}
+	if schema.Ref != "" {
+		target, ok := resolveRef(v.root, schema.Ref)
+		if !ok {
+			v.fail(path, "$ref", schema.Ref, nil, "cannot resolve reference %q", schema.Ref)
+			return
+		}
+		v.validate(target, data, path)
+	}
+	if schema.Type != "" && !v.validateType(schema, data, path) {
+		return
+	}
+	v.validateEnum(schema, data, path)
+	v.validateConst(schema, data, path)
+	v.validateNumber(schema, data, path)
+	v.validateString(schema, data, path)
+	v.validateCombinators(schema, data, path)
+}
+
+// validateType checks the type of data and validates the content of
+// objects and arrays. It returns false if the type does not match.
+func (v *validator) validateType(schema Definition, data any, path string) bool {
+	var ok bool
 	switch schema.Type {
 	case Object:
-		return validateObject(schema, data)
+		var object map[string]any
+		if object, ok = data.(map[string]any); ok {
+			v.validateObject(schema, object, path)
+		}
 	case Array:
-		return validateArray(schema, data)
+		var array []any
+		if array, ok = data.([]any); ok {
+			v.validateArray(schema, array, path)
+		}
 	case String:
-		_, ok := data.(string)
-		return ok
+		_, ok = data.(string)
 	case Number: // float64 and int
-		_, ok := data.(float64)
-		if !ok {
-			_, ok = data.(int)
-		}
-		return ok
+		_, ok = toFloat(data)
 	case Boolean:
-		_, ok := data.(bool)
-		return ok
+		_, ok = data.(bool)
 	case Integer:
 		// Golang unmarshals all numbers as float64, so we need to check if the float64 is an integer
-		if num, ok := data.(float64); ok {
-			return num == float64(int64(num))
-		}
-		_, ok := data.(int)
-		return ok
+		var num float64
+		num, ok = toFloat(data)
+		ok = ok && num == float64(int64(num))
 	case Null:
-		return data == nil
-	default:
-		return false
+		ok = data == nil
 	}
-}
-
-func validateObject(schema Definition, data any) bool {
-	dataMap, ok := data.(map[string]any)
 	if !ok {
-		return false
+		actual := typeName(data)
+		v.fail(path, "type", schema.Type, actual, "expected %s, got %s", schema.Type, actual)
 	}
+	return ok
+}
+
+func (v *validator) validateObject(schema Definition, object map[string]any, path string) {
 	for _, field := range schema.Required {
-		if _, exists := dataMap[field]; !exists {
-			return false
+		if _, exists := object[field]; !exists {
+			v.fail(path, "required", field, nil, "missing required property %q", field)
 		}
 	}
-	for key, valueSchema := range schema.Properties {
-		value, exists := dataMap[key]
-		if exists && !Validate(valueSchema, value) {
-			return false
-		} else if !exists && contains(schema.Required, key) {
-			return false
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := object[key]
+		propertyPath := path + "/" + pointerEscaper.Replace(key)
+		if valueSchema, declared := schema.Properties[key]; declared {
+			v.validate(valueSchema, value, propertyPath)
+			continue
+		}
+		switch additional := schema.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				v.fail(propertyPath, "additionalProperties", false, key, "property %q is not allowed", key)
+			}
+		case Definition:
+			v.validate(additional, value, propertyPath)
+		case *Definition:
+			if additional != nil {
+				v.validate(*additional, value, propertyPath)
+			}
+		case map[string]any:
+			// The schema was decoded from JSON.
+			if valueSchema, err := definitionValue(additional); err == nil {
+				v.validate(valueSchema, value, propertyPath)
+			}
 		}
 	}
-	return true
 }
 
-func validateArray(schema Definition, data any) bool {
-	dataArray, ok := data.([]any)
+func (v *validator) validateArray(schema Definition, array []any, path string) {
+	if schema.MinItems != nil && len(array) < *schema.MinItems {
+		v.fail(path, "minItems", *schema.MinItems, len(array),
+			"expected at least %d items, got %d", *schema.MinItems, len(array))
+	}
+	if schema.MaxItems != nil && len(array) > *schema.MaxItems {
+		v.fail(path, "maxItems", *schema.MaxItems, len(array),
+			"expected at most %d items, got %d", *schema.MaxItems, len(array))
+	}
+	if schema.Items == nil {
+		return
+	}
+	for i, item := range array {
+		v.validate(*schema.Items, item, path+"/"+strconv.Itoa(i))
+	}
+}
+
+func (v *validator) validateEnum(schema Definition, data any, path string) {
+	if len(schema.Enum) == 0 {
+		return
+	}
+	value, ok := data.(string)
+	if !ok {
+		value = fmt.Sprint(data)
+	}
+	if !contains(schema.Enum, value) {
+		v.fail(path, "enum", schema.Enum, data, "expected one of %q, got %q", schema.Enum, value)
+	}
+}
+
+func (v *validator) validateConst(schema Definition, data any, path string) {
+	if schema.Const == nil {
+		return
+	}
+	if !reflect.DeepEqual(normalizeJSON(schema.Const), normalizeJSON(data)) {
+		v.fail(path, "const", schema.Const, data, "expected %s, got %s", jsonString(schema.Const), jsonString(data))
+	}
+}
+
+func (v *validator) validateNumber(schema Definition, data any, path string) {
+	num, ok := toFloat(data)
 	if !ok {
-		return false
+		return
+	}
+	if schema.Minimum != nil && num < *schema.Minimum {
+		v.fail(path, "minimum", *schema.Minimum, num, "expected a value >= %g, got %g", *schema.Minimum, num)
+	}
+	if schema.Maximum != nil && num > *schema.Maximum {
+		v.fail(path, "maximum", *schema.Maximum, num, "expected a value <= %g, got %g", *schema.Maximum, num)
+	}
+}
+
+func (v *validator) validateString(schema Definition, data any, path string) {
+	s, ok := data.(string)
+	if !ok {
+		return
+	}
+	length := utf8.RuneCountInString(s)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		v.fail(path, "minLength", *schema.MinLength, length,
+			"expected at least %d characters, got %d", *schema.MinLength, length)
 	}
-	for _, item := range dataArray {
-		if !Validate(*schema.Items, item) {
-			return false
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.fail(path, "maxLength", *schema.MaxLength, length,
+			"expected at most %d characters, got %d", *schema.MaxLength, length)
+	}
+	if schema.Pattern != "" {
+		matched, err := regexp.MatchString(schema.Pattern, s)
+		switch {
+		case err != nil:
+			v.fail(path, "pattern", schema.Pattern, s, "invalid pattern %q: %v", schema.Pattern, err)
+		case !matched:
+			v.fail(path, "pattern", schema.Pattern, s, "%q does not match pattern %q", s, schema.Pattern)
+		}
+	}
+	if !validFormat(schema.Format, s) {
+		v.fail(path, "format", schema.Format, s, "%q is not a valid %s", s, schema.Format)
+	}
+}
+
+func validFormat(format, s string) bool {
+	switch format {
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "time":
+		_, err := time.Parse("15:04:05Z07:00", s)
+		if err != nil {
+			_, err = time.Parse("15:04:05", s)
+		}
+		return err == nil
+	case "email":
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	case "uuid":
+		return uuidPattern.MatchString(s)
+	case "ipv4":
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		ip := net.ParseIP(s)
+		return ip != nil && strings.Contains(s, ":")
+	default:
+		return true
+	}
+}
+
+func (v *validator) validateCombinators(schema Definition, data any, path string) {
+	for _, sub := range schema.AllOf {
+		v.validate(sub, data, path)
+	}
+	if len(schema.AnyOf) > 0 {
+		matched := false
+		for _, sub := range schema.AnyOf {
+			if v.matches(sub, data, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "anyOf", len(schema.AnyOf), 0,
+				"value does not match any of the %d allowed schemas", len(schema.AnyOf))
 		}
 	}
-	return true
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, sub := range schema.OneOf {
+			if v.matches(sub, data, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "oneOf", 1, matches, "value must match exactly one of the %d schemas, matched %d",
+				len(schema.OneOf), matches)
+		}
+	}
+}
+
+// resolveRef resolves "#" and "#/$defs/Name" references against root.
+func resolveRef(root Definition, ref string) (Definition, bool) {
+	if ref == "#" {
+		return root, true
+	}
+	name := strings.TrimPrefix(ref, "#/$defs/")
+	if name == ref {
+		return Definition{}, false
+	}
+	target, ok := root.Defs[name]
+	return target, ok
+}
+
+// typeName returns the JSON type of a decoded value.
+func typeName(data any) string {
+	switch v := data.(type) {
+	case nil:
+		return string(Null)
+	case bool:
+		return string(Boolean)
+	case string:
+		return string(String)
+	case []any:
+		return string(Array)
+	case map[string]any:
+		return string(Object)
+	default:
+		if num, ok := toFloat(v); ok {
+			if num == float64(int64(num)) {
+				return string(Integer)
+			}
+			return string(Number)
+		}
+		return fmt.Sprintf("%T", data)
+	}
+}
+
+// toFloat converts the numeric values produced by encoding/json, and Go
+// numbers given directly, to float64.
+func toFloat(data any) (float64, bool) {
+	switch v := data.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// normalizeJSON converts v to the representation produced by encoding/json
+// so that Go values and decoded values can be compared.
+func normalizeJSON(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized any
+	if err = json.Unmarshal(b, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+func jsonString(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
 }
 
 func contains[S ~[]E, E comparable](s S, v E) bool {
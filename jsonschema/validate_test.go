@@ -1,6 +1,8 @@
 package jsonschema_test
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/edmondfrank/go-giteeai/jsonschema"
@@ -80,6 +82,87 @@ func Test_Validate(t *testing.T) {
 	}
 }
 
+func TestValidateKeywords(t *testing.T) {
+	person := jsonschema.Definition{
+		Type: jsonschema.Object,
+		Defs: map[string]jsonschema.Definition{
+			"name": {Type: jsonschema.String, MinLength: jsonschema.Int(1), MaxLength: jsonschema.Int(5)},
+			"node": {
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"children": {Type: jsonschema.Array, Items: &jsonschema.Definition{Ref: "#/$defs/node"}},
+				},
+			},
+		},
+		Properties: map[string]jsonschema.Definition{
+			"name": {Ref: "#/$defs/name"},
+			"tree": {Ref: "#/$defs/node"},
+		},
+		AdditionalProperties: false,
+	}
+	tests := []struct {
+		name   string
+		schema jsonschema.Definition
+		data   any
+		want   bool
+	}{
+		{"minimum", jsonschema.Definition{Type: jsonschema.Number, Minimum: jsonschema.Float(1)}, 0.5, false},
+		{"maximum", jsonschema.Definition{Type: jsonschema.Integer, Maximum: jsonschema.Float(10)}, 10.0, true},
+		{"maximum exceeded", jsonschema.Definition{Type: jsonschema.Integer, Maximum: jsonschema.Float(10)}, 11, false},
+		{"minLength counts runes", jsonschema.Definition{Type: jsonschema.String, MinLength: jsonschema.Int(2)}, "日本", true},
+		{"maxLength", jsonschema.Definition{Type: jsonschema.String, MaxLength: jsonschema.Int(2)}, "abc", false},
+		{"pattern", jsonschema.Definition{Type: jsonschema.String, Pattern: "^[a-z]+$"}, "abc", true},
+		{"pattern mismatch", jsonschema.Definition{Type: jsonschema.String, Pattern: "^[a-z]+$"}, "ABC", false},
+		{"minItems", jsonschema.Definition{Type: jsonschema.Array, MinItems: jsonschema.Int(1)}, []any{}, false},
+		{"maxItems", jsonschema.Definition{Type: jsonschema.Array, MaxItems: jsonschema.Int(1)}, []any{1, 2}, false},
+		{"enum", jsonschema.Definition{Type: jsonschema.String, Enum: []string{"a", "b"}}, "c", false},
+		{"const", jsonschema.Definition{Const: map[string]int{"a": 1}}, map[string]any{"a": 1.0}, true},
+		{"const mismatch", jsonschema.Definition{Const: "x"}, "y", false},
+		{"date-time", jsonschema.Definition{Type: jsonschema.String, Format: "date-time"},
+			"2024-05-01T10:00:00Z", true},
+		{"date", jsonschema.Definition{Type: jsonschema.String, Format: "date"}, "2024-13-01", false},
+		{"email", jsonschema.Definition{Type: jsonschema.String, Format: "email"}, "a@example.com", true},
+		{"uuid", jsonschema.Definition{Type: jsonschema.String, Format: "uuid"}, "not-a-uuid", false},
+		{"ipv4", jsonschema.Definition{Type: jsonschema.String, Format: "ipv4"}, "10.0.0.1", true},
+		{"unknown format", jsonschema.Definition{Type: jsonschema.String, Format: "color"}, "red", true},
+		{"anyOf", jsonschema.Definition{AnyOf: []jsonschema.Definition{
+			{Type: jsonschema.String}, {Type: jsonschema.Integer},
+		}}, 3, true},
+		{"anyOf mismatch", jsonschema.Definition{AnyOf: []jsonschema.Definition{
+			{Type: jsonschema.String}, {Type: jsonschema.Integer},
+		}}, true, false},
+		{"oneOf matching twice", jsonschema.Definition{OneOf: []jsonschema.Definition{
+			{Type: jsonschema.Number}, {Type: jsonschema.Integer},
+		}}, 3, false},
+		{"allOf", jsonschema.Definition{AllOf: []jsonschema.Definition{
+			{Type: jsonschema.Number, Minimum: jsonschema.Float(0)}, {Maximum: jsonschema.Float(5)},
+		}}, 6, false},
+		{"nullable", jsonschema.Definition{Type: jsonschema.String, Nullable: true}, nil, true},
+		{"empty schema accepts anything", jsonschema.Definition{}, []any{1}, true},
+		{"ref", person, map[string]any{"name": "Ann"}, true},
+		{"ref mismatch", person, map[string]any{"name": "Annabelle"}, false},
+		{"recursive ref", person, map[string]any{"tree": map[string]any{
+			"children": []any{map[string]any{"children": []any{}}},
+		}}, true},
+		{"recursive ref mismatch", person, map[string]any{"tree": map[string]any{
+			"children": []any{map[string]any{"children": "none"}},
+		}}, false},
+		{"unresolvable ref", jsonschema.Definition{Ref: "#/$defs/missing"}, 1, false},
+		{"additionalProperties false", person, map[string]any{"age": 3}, false},
+		{"additionalProperties schema", jsonschema.Definition{
+			Type:                 jsonschema.Object,
+			AdditionalProperties: jsonschema.Definition{Type: jsonschema.Integer},
+		}, map[string]any{"a": 1, "b": "x"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonschema.Validate(tt.schema, tt.data); got != tt.want {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestUnmarshal(t *testing.T) {
 	type args struct {
 		schema  jsonschema.Definition
@@ -162,3 +245,84 @@ func TestUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateWithErrors(t *testing.T) {
+	schema := jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"name": {Type: jsonschema.String, MinLength: jsonschema.Int(2)},
+			"tags": {Type: jsonschema.Array, Items: &jsonschema.Definition{Type: jsonschema.String}},
+			"a/b":  {Type: jsonschema.Integer, Maximum: jsonschema.Float(3)},
+			"id":   {Type: jsonschema.Integer},
+		},
+		Required:             []string{"name", "id"},
+		AdditionalProperties: false,
+	}
+	data := map[string]any{
+		"name":  "x",
+		"tags":  []any{"ok", 2.0},
+		"a/b":   4.0,
+		"extra": true,
+	}
+
+	errs := jsonschema.ValidateWithErrors(schema, data)
+	want := []struct {
+		path, keyword string
+		expected      any
+		actual        any
+	}{
+		{"", "required", "id", nil},
+		{"/a~1b", "maximum", 3.0, 4.0},
+		{"/extra", "additionalProperties", false, "extra"},
+		{"/name", "minLength", 2, 1},
+		{"/tags/1", "type", jsonschema.String, "integer"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i, w := range want {
+		e := errs[i]
+		if e.Path != w.path || e.Keyword != w.keyword || e.Expected != w.expected || e.Actual != w.actual {
+			t.Errorf("error %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if errs[4].Error() != "/tags/1: expected string, got integer" {
+		t.Errorf("unexpected message %q", errs[4].Error())
+	}
+
+	if errs := jsonschema.ValidateWithErrors(schema, map[string]any{"name": "ok", "id": 1}); errs != nil {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestValidateDecodedAdditionalProperties(t *testing.T) {
+	var schema jsonschema.Definition
+	err := json.Unmarshal([]byte(`{"type":"object","additionalProperties":{"type":"integer"}}`), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jsonschema.Validate(schema, map[string]any{"x": "not an int"}) {
+		t.Error("expected the additional property to be validated")
+	}
+	if !jsonschema.Validate(schema, map[string]any{"x": 1.0}) {
+		t.Error("expected an integer additional property to be valid")
+	}
+}
+
+func TestVerifySchemaAndUnmarshalErrors(t *testing.T) {
+	schema := jsonschema.Definition{
+		Type: jsonschema.Object,
+		Properties: map[string]jsonschema.Definition{
+			"kind": {OneOf: []jsonschema.Definition{{Const: "a"}, {Const: "b"}}},
+		},
+	}
+	var v struct {
+		Kind string `json:"kind"`
+	}
+	err := jsonschema.VerifySchemaAndUnmarshal(schema, []byte(`{"kind":"c"}`), &v)
+
+	var errs jsonschema.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != "/kind" || errs[0].Keyword != "oneOf" {
+		t.Fatalf("expected a oneOf error at /kind, got %v", err)
+	}
+}
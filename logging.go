@@ -0,0 +1,275 @@
+package giteeai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogMaxBodySize = 16 * 1024
+	// base64RedactThreshold is the length from which a base64 looking string
+	// is considered a binary payload and left out of the logs.
+	base64RedactThreshold = 256
+	redactedValue         = "[REDACTED]"
+)
+
+// Logger receives one structured record per API call. Arguments are
+// alternating key/value pairs. It is satisfied by *slog.Logger.
+type Logger interface {
+	InfoContext(ctx context.Context, msg string, args ...any)
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+// LogOptions controls what the client logs in addition to the call summary.
+type LogOptions struct {
+	// RequestBody adds the request body to the record.
+	RequestBody bool
+	// ResponseBody adds the decoded response to the record. Streaming and raw
+	// responses are never captured.
+	ResponseBody bool
+	// Headers adds the request and response headers to the record.
+	// Credentials are always redacted.
+	Headers bool
+	// MaxBodySize truncates captured bodies, 16KiB by default.
+	MaxBodySize int
+}
+
+// sensitiveHeaders are never logged in clear text.
+var sensitiveHeaders = []string{"Authorization", "Api-Key", "Cookie", "Set-Cookie"}
+
+// binaryFields hold base64 payloads that are redacted regardless of their size.
+var binaryFields = map[string]bool{
+	"b64_json":  true,
+	"embedding": true,
+}
+
+// NewLoggingMiddleware returns a middleware that emits one record per call
+// to logger with the operation, model, status, latency, token usage, rate
+// limit headers and error type. It is installed automatically when
+// ClientConfig.Logger is set.
+func NewLoggingMiddleware(logger Logger, opts LogOptions) Middleware {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultLogMaxBodySize
+	}
+	return func(next Doer) Doer {
+		return DoerFunc(func(call *Call) error {
+			start := time.Now()
+			err := next.Do(call)
+			logCall(logger, opts, call, time.Since(start), err)
+			return err
+		})
+	}
+}
+
+func logCall(logger Logger, opts LogOptions, call *Call, latency time.Duration, err error) {
+	ctx := call.Request.Context()
+	args := []any{
+		"operation", string(call.Operation),
+		"method", call.Request.Method,
+		"url", call.Request.URL.String(),
+		"latency", latency,
+	}
+	if model := requestModel(call.Body); model != "" {
+		args = append(args, "model", model)
+	}
+	if call.Stream {
+		args = append(args, "stream", true)
+	}
+	if resp := call.HTTPResponse; resp != nil {
+		args = append(args, "status", resp.StatusCode)
+		if resp.Header.Get("x-ratelimit-limit-requests") != "" || resp.Header.Get("x-ratelimit-limit-tokens") != "" {
+			rl := newRateLimitHeaders(resp.Header)
+			args = append(args,
+				"ratelimit_remaining_requests", rl.RemainingRequests,
+				"ratelimit_remaining_tokens", rl.RemainingTokens,
+				"ratelimit_reset_requests", rl.ResetRequests.String(),
+				"ratelimit_reset_tokens", rl.ResetTokens.String(),
+			)
+		}
+	}
+	if usage, ok := responseUsage(call.Response); ok && err == nil {
+		args = append(args,
+			"prompt_tokens", usage.PromptTokens,
+			"completion_tokens", usage.CompletionTokens,
+			"total_tokens", usage.TotalTokens,
+		)
+		if usage.CompletionTokensDetails != nil && usage.CompletionTokensDetails.ReasoningTokens > 0 {
+			args = append(args, "reasoning_tokens", usage.CompletionTokensDetails.ReasoningTokens)
+		}
+	}
+	if opts.Headers {
+		args = append(args, "request_headers", redactHeaders(call.Request.Header))
+		if call.HTTPResponse != nil {
+			args = append(args, "response_headers", redactHeaders(call.HTTPResponse.Header))
+		}
+	}
+	if opts.RequestBody && call.Body != nil {
+		args = append(args, "request_body", redactBody(call.Body, opts.MaxBodySize))
+	}
+	if opts.ResponseBody && err == nil && !call.Stream && !call.Raw && call.Response != nil {
+		args = append(args, "response_body", redactBody(call.Response, opts.MaxBodySize))
+	}
+
+	if err != nil {
+		args = append(args, "error", err.Error(), "error_type", errorType(err))
+		logger.ErrorContext(ctx, "giteeai request failed", args...)
+		return
+	}
+	logger.InfoContext(ctx, "giteeai request", args...)
+}
+
+// errorType classifies err for logs and metrics: the APIError type when the
+// API returned one, otherwise a coarse category.
+func errorType(err error) string {
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		if apiErr.Type != "" {
+			return apiErr.Type
+		}
+		return "api_error"
+	}
+	reqErr := &RequestError{}
+	if errors.As(err, &reqErr) {
+		return "request_error"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	return "transport_error"
+}
+
+// requestModel returns the Model field of a request body, if it has one.
+func requestModel(body any) string {
+	v := reflect.ValueOf(body)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.FieldByName("Model")
+	if !field.IsValid() || field.Kind() != reflect.String {
+		return ""
+	}
+	return field.String()
+}
+
+// responseUsage returns the Usage field of a decoded response, if it has one.
+func responseUsage(response any) (Usage, bool) {
+	v := reflect.ValueOf(response)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return Usage{}, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return Usage{}, false
+	}
+	field := v.FieldByName("Usage")
+	if !field.IsValid() {
+		return Usage{}, false
+	}
+	switch u := field.Interface().(type) {
+	case Usage:
+		return u, true
+	case *Usage:
+		if u != nil {
+			return *u, true
+		}
+	}
+	return Usage{}, false
+}
+
+func redactHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		out[key] = strings.Join(values, ", ")
+	}
+	for _, key := range sensitiveHeaders {
+		if _, ok := out[key]; ok {
+			out[key] = redactedValue
+		}
+	}
+	return out
+}
+
+// redactBody renders v as JSON with binary payloads replaced by a short
+// placeholder, truncated to maxSize bytes.
+func redactBody(v any, maxSize int) string {
+	if _, ok := v.(io.Reader); ok {
+		return "<non-JSON body omitted>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("<unserializable body: %v>", err)
+	}
+	var generic any
+	if err = json.Unmarshal(b, &generic); err == nil {
+		if b, err = json.Marshal(redactValue("", generic)); err != nil {
+			return fmt.Sprintf("<unserializable body: %v>", err)
+		}
+	}
+	if len(b) > maxSize {
+		return string(b[:maxSize]) + "...(truncated)"
+	}
+	return string(b)
+}
+
+func redactValue(key string, v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, item := range value {
+			value[k] = redactValue(k, item)
+		}
+		return value
+	case []any:
+		for i, item := range value {
+			value[i] = redactValue(key, item)
+		}
+		return value
+	case string:
+		if (binaryFields[key] && value != "") || isBase64Payload(value) {
+			return fmt.Sprintf("[base64 payload, %d bytes]", len(value))
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// isBase64Payload reports whether s is a large base64 blob or a base64 data URL.
+func isBase64Payload(s string) bool {
+	if len(s) < base64RedactThreshold {
+		return false
+	}
+	if strings.HasPrefix(s, "data:") && strings.Contains(s, ";base64,") {
+		return true
+	}
+	return looksLikeBase64(s)
+}
+
+func looksLikeBase64(s string) bool {
+	for _, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case r == '+', r == '/', r == '=', r == '-', r == '_', r == '\n', r == '\r':
+		default:
+			return false
+		}
+	}
+	return true
+}
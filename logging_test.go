@@ -0,0 +1,113 @@
+package giteeai_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+type logRecord struct {
+	level string
+	msg   string
+	attrs map[string]any
+}
+
+type recordingLogger struct {
+	records []logRecord
+}
+
+func (l *recordingLogger) record(level, msg string, args []any) {
+	attrs := make(map[string]any)
+	for i := 0; i+1 < len(args); i += 2 {
+		attrs[fmt.Sprint(args[i])] = args[i+1]
+	}
+	l.records = append(l.records, logRecord{level: level, msg: msg, attrs: attrs})
+}
+
+func (l *recordingLogger) InfoContext(_ context.Context, msg string, args ...any) {
+	l.record("info", msg, args)
+}
+
+func (l *recordingLogger) ErrorContext(_ context.Context, msg string, args ...any) {
+	l.record("error", msg, args)
+}
+
+func TestLoggingRecordsCallsWithRedaction(t *testing.T) {
+	logger := &recordingLogger{}
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Logger = logger
+		c.LogOptions = giteeai.LogOptions{RequestBody: true, ResponseBody: true, Headers: true}
+	})
+	defer teardown()
+
+	payload := strings.Repeat("QUJD", 100)
+	server.RegisterHandler("/v1/embeddings", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "60")
+		w.Header().Set("x-ratelimit-remaining-requests", "59")
+		fmt.Fprintf(w, `{"data":[{"embedding":%q,"index":0}],"usage":{"prompt_tokens":4,"total_tokens":4}}`, payload)
+	})
+
+	_, err := client.CreateEmbeddings(context.Background(), giteeai.EmbeddingRequest{
+		Input:          []string{"hello"},
+		Model:          giteeai.AdaEmbeddingV2,
+		EncodingFormat: giteeai.EmbeddingEncodingFormatBase64,
+	})
+	checks.NoError(t, err, "CreateEmbeddings error")
+
+	if len(logger.records) != 1 {
+		t.Fatalf("expected one record, got %d", len(logger.records))
+	}
+	record := logger.records[0]
+	expected := map[string]any{
+		"operation":                    "CreateEmbeddings",
+		"model":                        string(giteeai.AdaEmbeddingV2),
+		"status":                       http.StatusOK,
+		"prompt_tokens":                4,
+		"total_tokens":                 4,
+		"ratelimit_remaining_requests": 59,
+	}
+	for key, value := range expected {
+		if record.attrs[key] != value {
+			t.Errorf("attribute %s = %v, want %v", key, record.attrs[key], value)
+		}
+	}
+
+	dump := fmt.Sprint(record.attrs)
+	if strings.Contains(dump, test.GetTestToken()) {
+		t.Fatal("auth token leaked into the logs")
+	}
+	if strings.Contains(dump, payload) {
+		t.Fatal("base64 embedding leaked into the logs")
+	}
+	if !strings.Contains(fmt.Sprint(record.attrs["request_body"]), "hello") {
+		t.Fatalf("request body was not captured: %v", record.attrs["request_body"])
+	}
+}
+
+func TestLoggingRecordsErrors(t *testing.T) {
+	logger := &recordingLogger{}
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Logger = logger
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/models", func(w http.ResponseWriter, _ *http.Request) {
+		writeServerError(w, http.StatusTooManyRequests, "rate_limit_exceeded")
+	})
+
+	_, err := client.ListModels(context.Background())
+	checks.HasError(t, err, "ListModels should fail")
+
+	if len(logger.records) != 1 || logger.records[0].level != "error" {
+		t.Fatalf("expected one error record, got %+v", logger.records)
+	}
+	if got := logger.records[0].attrs["error_type"]; got != "rate_limit_exceeded" {
+		t.Fatalf("unexpected error type %v", got)
+	}
+}
@@ -0,0 +1,393 @@
+package giteeai
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives measurements about the calls made by the client.
+type Metrics interface {
+	// ObserveRequest is called once per call, when the response has been
+	// received or the call failed. For streams it is called once the stream
+	// has been established.
+	ObserveRequest(m RequestMetrics)
+	// ObserveStream is called when a stream ends.
+	ObserveStream(m StreamMetrics)
+}
+
+// RequestMetrics describes a finished call.
+type RequestMetrics struct {
+	Operation Operation
+	Model     string
+	// StatusCode is zero when no response was received.
+	StatusCode int
+	// ErrorType is empty on success, otherwise the APIError.Type or a coarse
+	// category such as "transport_error".
+	ErrorType string
+	Duration  time.Duration
+	// Usage is set when the response reported token usage.
+	Usage  *Usage
+	Stream bool
+}
+
+// StreamMetrics describes a finished stream.
+type StreamMetrics struct {
+	Operation Operation
+	Model     string
+	// TimeToFirstToken is the time between sending the request and the first
+	// content, reasoning or tool call delta. Zero if no token was received.
+	TimeToFirstToken time.Duration
+	// Duration is the time between sending the request and the end of the stream.
+	Duration time.Duration
+	// CompletionTokens is taken from the final usage chunk when available,
+	// otherwise it is the number of chunks carrying tokens.
+	CompletionTokens int
+	// TokensPerSecond is the generation rate after the first token.
+	TokensPerSecond float64
+	// Usage is set when the stream included a usage chunk.
+	Usage     *Usage
+	ErrorType string
+}
+
+// NewMetricsMiddleware returns a middleware reporting every call to m. It is
+// installed automatically when ClientConfig.Metrics is set.
+func NewMetricsMiddleware(m Metrics) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(call *Call) error {
+			start := time.Now()
+			model := requestModel(call.Body)
+			var observer *metricsStreamObserver
+			if call.Stream {
+				observer = &metricsStreamObserver{
+					metrics: m,
+					start:   start,
+					result:  StreamMetrics{Operation: call.Operation, Model: model},
+				}
+				call.ObserveStream(observer)
+			}
+
+			err := next.Do(call)
+			result := RequestMetrics{
+				Operation: call.Operation,
+				Model:     model,
+				Duration:  time.Since(start),
+				Stream:    call.Stream,
+			}
+			if call.HTTPResponse != nil {
+				result.StatusCode = call.HTTPResponse.StatusCode
+			}
+			if err != nil {
+				result.ErrorType = errorType(err)
+			} else if usage, ok := responseUsage(call.Response); ok {
+				result.Usage = &usage
+			}
+			m.ObserveRequest(result)
+			return err
+		})
+	}
+}
+
+// metricsStreamObserver measures the latency and throughput of a stream.
+type metricsStreamObserver struct {
+	metrics Metrics
+	start   time.Time
+
+	mu         sync.Mutex
+	firstToken time.Time
+	chunks     int
+	result     StreamMetrics
+}
+
+func (o *metricsStreamObserver) OnFirstByte() {}
+
+func (o *metricsStreamObserver) OnChunk(chunk any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	hasToken := false
+	switch c := chunk.(type) {
+	case *ChatCompletionStreamResponse:
+		for _, choice := range c.Choices {
+			d := choice.Delta
+			if d.Content != "" || d.ReasoningContent != "" || d.Refusal != "" || len(d.ToolCalls) > 0 {
+				hasToken = true
+			}
+		}
+		if c.Usage != nil {
+			usage := *c.Usage
+			o.result.Usage = &usage
+		}
+	case *CompletionResponse:
+		for _, choice := range c.Choices {
+			if choice.Text != "" {
+				hasToken = true
+			}
+		}
+		if c.Usage != nil {
+			usage := *c.Usage
+			o.result.Usage = &usage
+		}
+	}
+	if !hasToken {
+		return
+	}
+	o.chunks++
+	if o.firstToken.IsZero() {
+		o.firstToken = time.Now()
+	}
+}
+
+func (o *metricsStreamObserver) OnFinish(err error) {
+	o.mu.Lock()
+	result := o.result
+	now := time.Now()
+	result.Duration = now.Sub(o.start)
+	result.CompletionTokens = o.chunks
+	if result.Usage != nil && result.Usage.CompletionTokens > 0 {
+		result.CompletionTokens = result.Usage.CompletionTokens
+	}
+	if !o.firstToken.IsZero() {
+		result.TimeToFirstToken = o.firstToken.Sub(o.start)
+		if generation := now.Sub(o.firstToken).Seconds(); generation > 0 {
+			result.TokensPerSecond = float64(result.CompletionTokens) / generation
+		}
+	}
+	o.mu.Unlock()
+
+	if err != nil && !errors.Is(err, ErrStreamClosed) {
+		result.ErrorType = errorType(err)
+	}
+	o.metrics.ObserveStream(result)
+}
+
+var (
+	defaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+	defaultRateBuckets     = []float64{1, 5, 10, 20, 50, 100, 200, 500}
+)
+
+// MemoryMetrics is an in-memory Metrics implementation that renders the
+// Prometheus text exposition format. It is an http.Handler so it can be
+// mounted directly on a /metrics endpoint.
+type MemoryMetrics struct {
+	mu sync.Mutex
+
+	requests         *counterVec
+	errors           *counterVec
+	tokens           *counterVec
+	requestDuration  *histogramVec
+	timeToFirstToken *histogramVec
+	tokensPerSecond  *histogramVec
+}
+
+// NewMemoryMetrics creates an empty MemoryMetrics.
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		requests: newCounterVec("giteeai_requests_total",
+			"Total number of API calls.", "operation", "model", "status"),
+		errors: newCounterVec("giteeai_request_errors_total",
+			"Total number of failed API calls.", "operation", "type", "status"),
+		tokens: newCounterVec("giteeai_tokens_total",
+			"Total number of tokens reported by the API.", "operation", "model", "kind"),
+		requestDuration: newHistogramVec("giteeai_request_duration_seconds",
+			"Latency of API calls until the response headers were received.", defaultDurationBuckets,
+			"operation", "model"),
+		timeToFirstToken: newHistogramVec("giteeai_stream_time_to_first_token_seconds",
+			"Time from sending a streaming request to its first token.", defaultDurationBuckets,
+			"operation", "model"),
+		tokensPerSecond: newHistogramVec("giteeai_stream_tokens_per_second",
+			"Generation rate of streams after the first token.", defaultRateBuckets,
+			"operation", "model"),
+	}
+}
+
+// ObserveRequest implements Metrics.
+func (m *MemoryMetrics) ObserveRequest(r RequestMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op := string(r.Operation)
+	status := ""
+	if r.StatusCode != 0 {
+		status = strconv.Itoa(r.StatusCode)
+	}
+	m.requests.add(1, op, r.Model, status)
+	m.requestDuration.observe(r.Duration.Seconds(), op, r.Model)
+	if r.ErrorType != "" {
+		m.errors.add(1, op, r.ErrorType, status)
+	}
+	if r.Usage != nil {
+		m.addUsage(op, r.Model, *r.Usage)
+	}
+}
+
+// ObserveStream implements Metrics.
+func (m *MemoryMetrics) ObserveStream(s StreamMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op := string(s.Operation)
+	if s.TimeToFirstToken > 0 {
+		m.timeToFirstToken.observe(s.TimeToFirstToken.Seconds(), op, s.Model)
+	}
+	if s.TokensPerSecond > 0 {
+		m.tokensPerSecond.observe(s.TokensPerSecond, op, s.Model)
+	}
+	if s.ErrorType != "" {
+		m.errors.add(1, op, s.ErrorType, "")
+	}
+	if s.Usage != nil {
+		m.addUsage(op, s.Model, *s.Usage)
+	}
+}
+
+func (m *MemoryMetrics) addUsage(op, model string, usage Usage) {
+	m.tokens.add(float64(usage.PromptTokens), op, model, "prompt")
+	m.tokens.add(float64(usage.CompletionTokens), op, model, "completion")
+	if usage.CompletionTokensDetails != nil && usage.CompletionTokensDetails.ReasoningTokens > 0 {
+		m.tokens.add(float64(usage.CompletionTokensDetails.ReasoningTokens), op, model, "reasoning")
+	}
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format.
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	m.requests.write(bw)
+	m.errors.write(bw)
+	m.tokens.write(bw)
+	m.requestDuration.write(bw)
+	m.timeToFirstToken.write(bw)
+	m.tokensPerSecond.write(bw)
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *MemoryMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+const labelSeparator = "\xff"
+
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+	values map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) add(v float64, labelValues ...string) {
+	c.values[strings.Join(labelValues, labelSeparator)] += v
+}
+
+func (c *counterVec) write(w *bufio.Writer) {
+	if len(c.values) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key, ""), formatFloat(c.values[key]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	values  map[string]*histogram
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, labelSeparator)
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+func (h *histogramVec) write(w *bufio.Writer) {
+	if len(h.values) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		for i, upper := range h.buckets {
+			le := `le="` + formatFloat(upper) + `"`
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, le), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, `le="+Inf"`), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, ""), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, ""), hist.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names []string, key string, extra string) string {
+	values := strings.Split(key, labelSeparator)
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(values[i])))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
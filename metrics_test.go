@@ -0,0 +1,135 @@
+package giteeai_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func metricsOutput(t *testing.T, m *giteeai.MemoryMetrics) string {
+	t.Helper()
+	var buf bytes.Buffer
+	checks.NoError(t, m.WritePrometheus(&buf), "WritePrometheus error")
+	return buf.String()
+}
+
+func expectMetricLines(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, output)
+		}
+	}
+}
+
+func TestMetricsRequestsAndTokens(t *testing.T) {
+	metrics := giteeai.NewMemoryMetrics()
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Metrics = metrics
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"choices":[],"usage":{"prompt_tokens":2,"completion_tokens":5,"total_tokens":7,`+
+			`"completion_tokens_details":{"reasoning_tokens":3}}}`)
+	})
+	server.RegisterHandler("/v1/embeddings", func(w http.ResponseWriter, _ *http.Request) {
+		writeServerError(w, http.StatusTooManyRequests, "rate_limit_exceeded")
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{Model: "qwen"})
+		checks.NoError(t, err, "CreateChatCompletion error")
+	}
+	_, err := client.CreateEmbeddings(context.Background(), giteeai.EmbeddingRequest{Model: "bge"})
+	checks.HasError(t, err, "CreateEmbeddings should fail")
+
+	expectMetricLines(t, metricsOutput(t, metrics),
+		"# TYPE giteeai_requests_total counter",
+		`giteeai_requests_total{operation="CreateChatCompletion",model="qwen",status="200"} 2`,
+		`giteeai_requests_total{operation="CreateEmbeddings",model="bge",status="429"} 1`,
+		`giteeai_request_errors_total{operation="CreateEmbeddings",type="rate_limit_exceeded",status="429"} 1`,
+		`giteeai_tokens_total{operation="CreateChatCompletion",model="qwen",kind="prompt"} 4`,
+		`giteeai_tokens_total{operation="CreateChatCompletion",model="qwen",kind="completion"} 10`,
+		`giteeai_tokens_total{operation="CreateChatCompletion",model="qwen",kind="reasoning"} 6`,
+		"# TYPE giteeai_request_duration_seconds histogram",
+		`giteeai_request_duration_seconds_bucket{operation="CreateChatCompletion",model="qwen",le="+Inf"} 2`,
+		`giteeai_request_duration_seconds_count{operation="CreateChatCompletion",model="qwen"} 2`,
+	)
+}
+
+func TestMetricsChatCompletionStream(t *testing.T) {
+	metrics := giteeai.NewMemoryMetrics()
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Metrics = metrics
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\" there\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model: "qwen",
+	})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	for {
+		_, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		checks.NoError(t, err, "Recv error")
+	}
+	stream.Close()
+
+	expectMetricLines(t, metricsOutput(t, metrics),
+		`giteeai_requests_total{operation="CreateChatCompletionStream",model="qwen",status="200"} 1`,
+		`giteeai_tokens_total{operation="CreateChatCompletionStream",model="qwen",kind="completion"} 2`,
+		`giteeai_stream_time_to_first_token_seconds_count{operation="CreateChatCompletionStream",model="qwen"} 1`,
+	)
+}
+
+func TestMemoryMetricsExposition(t *testing.T) {
+	metrics := giteeai.NewMemoryMetrics()
+	metrics.ObserveRequest(giteeai.RequestMetrics{
+		Operation: giteeai.OperationCreateCompletion,
+		Model:     "a\"b\\c\nd",
+		ErrorType: "transport_error",
+	})
+	metrics.ObserveStream(giteeai.StreamMetrics{
+		Operation:        giteeai.OperationCreateChatCompletionStream,
+		Model:            "qwen",
+		TokensPerSecond:  15,
+		CompletionTokens: 30,
+	})
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+	expectMetricLines(t, rec.Body.String(),
+		`giteeai_requests_total{operation="CreateCompletion",model="a\"b\\c\nd",status=""} 1`,
+		`giteeai_request_errors_total{operation="CreateCompletion",type="transport_error",status=""} 1`,
+		`giteeai_stream_tokens_per_second_bucket{operation="CreateChatCompletionStream",model="qwen",le="10"} 0`,
+		`giteeai_stream_tokens_per_second_bucket{operation="CreateChatCompletionStream",model="qwen",le="20"} 1`,
+		`giteeai_stream_tokens_per_second_sum{operation="CreateChatCompletionStream",model="qwen"} 15`,
+	)
+	if strings.Contains(rec.Body.String(), "giteeai_stream_time_to_first_token_seconds") {
+		t.Fatal("empty histograms must not be rendered")
+	}
+}
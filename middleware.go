@@ -0,0 +1,119 @@
+package giteeai
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrMiddlewareNoResponse is returned when a middleware ends a raw or
+// streaming call without error but also without an HTTP response.
+var ErrMiddlewareNoResponse = errors.New("middleware returned without an HTTP response")
+
+// Call describes a single API call as it travels through the middleware chain.
+type Call struct {
+	// Operation is the client method that issued the call.
+	Operation Operation
+	// Request is the HTTP request about to be sent. Middleware may modify its
+	// headers or replace it before calling the next Doer.
+	Request *http.Request
+	// Body is the request body before marshalling, e.g. a ChatCompletionRequest.
+	// It is nil for requests without a body. Changing it has no effect on the
+	// request that is sent.
+	Body any
+	// Response is the value the response body is decoded into. It is populated
+	// once the next Doer returns without error, and is nil for raw and
+	// streaming calls.
+	Response any
+	// HTTPResponse is the last response received from the server, if any. For
+	// raw and streaming calls its body is handed over to the caller and must
+	// not be read by middleware.
+	HTTPResponse *http.Response
+	// Stream reports whether the response is a server-sent event stream.
+	Stream bool
+	// Raw reports whether the response body is returned to the caller as is.
+	Raw bool
+
+	streamObservers []StreamObserver
+}
+
+// ObserveStream registers o to be notified of the stream lifecycle. It has
+// no effect on calls that are not streaming.
+func (c *Call) ObserveStream(o StreamObserver) {
+	c.streamObservers = append(c.streamObservers, o)
+}
+
+// Doer executes a Call.
+type Doer interface {
+	Do(call *Call) error
+}
+
+// DoerFunc is an adapter to allow the use of ordinary functions as Doer.
+type DoerFunc func(call *Call) error
+
+// Do calls f(call).
+func (f DoerFunc) Do(call *Call) error {
+	return f(call)
+}
+
+// Middleware wraps a Doer to observe or alter every call made by the client,
+// e.g. for logging, header injection or metrics.
+type Middleware func(next Doer) Doer
+
+// chainMiddlewares wraps terminal with middlewares, the first one being the
+// outermost.
+func chainMiddlewares(terminal Doer, middlewares ...Middleware) Doer {
+	handler := terminal
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			handler = middlewares[i](handler)
+		}
+	}
+	return handler
+}
+
+// middlewares returns the user supplied middlewares followed by the built-in
+// ones enabled by the config.
+func (c *Client) middlewares() []Middleware {
+	middlewares := append([]Middleware(nil), c.config.Middlewares...)
+	if c.config.Tracer != nil {
+		middlewares = append(middlewares, NewTracingMiddleware(c.config.Tracer))
+	}
+	if c.config.Metrics != nil {
+		middlewares = append(middlewares, NewMetricsMiddleware(c.config.Metrics))
+	}
+	if c.config.Logger != nil {
+		middlewares = append(middlewares, NewLoggingMiddleware(c.config.Logger, c.config.LogOptions))
+	}
+	return middlewares
+}
+
+// newCall creates a Call for req from the details recorded by newRequest.
+func newCall(req *http.Request, v any) *Call {
+	info := requestInfoFromContext(req.Context())
+	return &Call{
+		Operation: info.operation,
+		Request:   req,
+		Body:      info.body,
+		Response:  v,
+	}
+}
+
+// roundTrip is the innermost Doer: it sends the request, applying retries and
+// rate limiting, and decodes the response into call.Response.
+func (c *Client) roundTrip(call *Call) error {
+	resp, err := c.do(call.Request)
+	call.HTTPResponse = resp
+	if err != nil {
+		return err
+	}
+	if call.Stream || call.Raw {
+		return nil
+	}
+
+	defer resp.Body.Close()
+	if err = decodeResponse(resp.Body, call.Response); err != nil {
+		return err
+	}
+	c.bindEndpoint(call.Request.Context(), call.Response)
+	return nil
+}
@@ -0,0 +1,123 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var order []string
+	var seen *giteeai.Call
+	tagging := func(name string) giteeai.Middleware {
+		return func(next giteeai.Doer) giteeai.Doer {
+			return giteeai.DoerFunc(func(call *giteeai.Call) error {
+				order = append(order, name)
+				call.Request.Header.Add("X-Middleware", name)
+				err := next.Do(call)
+				order = append(order, name)
+				return err
+			})
+		}
+	}
+	recording := func(next giteeai.Doer) giteeai.Doer {
+		return giteeai.DoerFunc(func(call *giteeai.Call) error {
+			err := next.Do(call)
+			seen = call
+			return err
+		})
+	}
+
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Middlewares = []giteeai.Middleware{tagging("outer"), tagging("inner"), recording}
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Values("X-Middleware"); len(got) != 2 || got[0] != "outer" || got[1] != "inner" {
+			t.Errorf("unexpected middleware headers: %v", got)
+		}
+		fmt.Fprint(w, `{"id":"chatcmpl-1","usage":{"total_tokens":3}}`)
+	})
+
+	request := giteeai.ChatCompletionRequest{Model: giteeai.Qwen2_7B_Instruct}
+	_, err := client.CreateChatCompletion(context.Background(), request)
+	checks.NoError(t, err, "CreateChatCompletion error")
+
+	if fmt.Sprint(order) != "[outer inner inner outer]" {
+		t.Fatalf("unexpected middleware order: %v", order)
+	}
+	if seen.Operation != giteeai.OperationCreateChatCompletion {
+		t.Fatalf("unexpected operation %q", seen.Operation)
+	}
+	if body, ok := seen.Body.(giteeai.ChatCompletionRequest); !ok || body.Model != request.Model {
+		t.Fatalf("unexpected body %#v", seen.Body)
+	}
+	resp, ok := seen.Response.(*giteeai.ChatCompletionResponse)
+	if !ok || resp.ID != "chatcmpl-1" || resp.Usage.TotalTokens != 3 {
+		t.Fatalf("unexpected decoded response %#v", seen.Response)
+	}
+	if seen.HTTPResponse == nil || seen.HTTPResponse.StatusCode != http.StatusOK {
+		t.Fatal("HTTP response was not recorded")
+	}
+}
+
+func TestMiddlewareSeesStreamsAndErrors(t *testing.T) {
+	var calls []*giteeai.Call
+	var errs []error
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Middlewares = []giteeai.Middleware{func(next giteeai.Doer) giteeai.Doer {
+			return giteeai.DoerFunc(func(call *giteeai.Call) error {
+				err := next.Do(call)
+				calls = append(calls, call)
+				errs = append(errs, err)
+				return err
+			})
+		}}
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	server.RegisterHandler("/v1/models/missing", func(w http.ResponseWriter, _ *http.Request) {
+		writeServerError(w, http.StatusNotFound, "invalid_request_error")
+	})
+
+	ctx := context.Background()
+	stream, err := client.CreateChatCompletionStream(ctx, giteeai.ChatCompletionRequest{Model: giteeai.Qwen2_7B_Instruct})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	stream.Close()
+
+	_, err = client.GetModel(ctx, "missing")
+	checks.HasError(t, err, "GetModel should fail")
+
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if !calls[0].Stream || calls[0].Operation != giteeai.OperationCreateChatCompletionStream {
+		t.Fatalf("unexpected stream call %+v", calls[0])
+	}
+	apiErr := &giteeai.APIError{}
+	if calls[1].Operation != giteeai.OperationGetModel || !errors.As(errs[1], &apiErr) {
+		t.Fatalf("unexpected error call %+v: %v", calls[1], errs[1])
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	client, _, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Middlewares = []giteeai.Middleware{func(giteeai.Doer) giteeai.Doer {
+			return giteeai.DoerFunc(func(*giteeai.Call) error { return nil })
+		}}
+	})
+	defer teardown()
+
+	_, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.ErrorIs(t, err, giteeai.ErrMiddlewareNoResponse, "short-circuited stream should fail")
+}
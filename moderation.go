@@ -97,6 +97,7 @@ func (c *Client) Moderations(ctx context.Context, request ModerationRequest) (re
 		http.MethodPost,
 		c.fullURL("/moderations", withModel(request.Model)),
 		withBody(&request),
+		withOperation(OperationModerations),
 	)
 	if err != nil {
 		return
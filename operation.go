@@ -0,0 +1,89 @@
+package giteeai
+
+// Operation identifies the client method that issued a request. It is
+// available to middleware through Call.Operation.
+type Operation string
+
+// Operations issued by Client methods.
+const (
+	OperationCancelBatch                  Operation = "CancelBatch"
+	OperationCancelFineTune               Operation = "CancelFineTune"
+	OperationCancelFineTuningJob          Operation = "CancelFineTuningJob"
+	OperationCancelRun                    Operation = "CancelRun"
+	OperationCancelVectorStoreFileBatch   Operation = "CancelVectorStoreFileBatch"
+	OperationCreateAssistant              Operation = "CreateAssistant"
+	OperationCreateAssistantFile          Operation = "CreateAssistantFile"
+	OperationCreateBatch                  Operation = "CreateBatch"
+	OperationCreateChatCompletion         Operation = "CreateChatCompletion"
+	OperationCreateChatCompletionStream   Operation = "CreateChatCompletionStream"
+	OperationCreateCompletion             Operation = "CreateCompletion"
+	OperationCreateCompletionStream       Operation = "CreateCompletionStream"
+	OperationCreateEditImage              Operation = "CreateEditImage"
+	OperationCreateEmbeddings             Operation = "CreateEmbeddings"
+	OperationCreateFile                   Operation = "CreateFile"
+	OperationCreateFileBytes              Operation = "CreateFileBytes"
+	OperationCreateFineTune               Operation = "CreateFineTune"
+	OperationCreateFineTuningJob          Operation = "CreateFineTuningJob"
+	OperationCreateImage                  Operation = "CreateImage"
+	OperationCreateMessage                Operation = "CreateMessage"
+	OperationCreateRun                    Operation = "CreateRun"
+	OperationCreateSpeech                 Operation = "CreateSpeech"
+	OperationCreateThread                 Operation = "CreateThread"
+	OperationCreateThreadAndRun           Operation = "CreateThreadAndRun"
+	OperationCreateTranscription          Operation = "CreateTranscription"
+	OperationCreateTranslation            Operation = "CreateTranslation"
+	OperationCreateVariImage              Operation = "CreateVariImage"
+	OperationCreateVectorStore            Operation = "CreateVectorStore"
+	OperationCreateVectorStoreFile        Operation = "CreateVectorStoreFile"
+	OperationCreateVectorStoreFileBatch   Operation = "CreateVectorStoreFileBatch"
+	OperationDeleteAssistant              Operation = "DeleteAssistant"
+	OperationDeleteAssistantFile          Operation = "DeleteAssistantFile"
+	OperationDeleteFile                   Operation = "DeleteFile"
+	OperationDeleteFineTune               Operation = "DeleteFineTune"
+	OperationDeleteFineTuneModel          Operation = "DeleteFineTuneModel"
+	OperationDeleteMessage                Operation = "DeleteMessage"
+	OperationDeleteThread                 Operation = "DeleteThread"
+	OperationDeleteVectorStore            Operation = "DeleteVectorStore"
+	OperationDeleteVectorStoreFile        Operation = "DeleteVectorStoreFile"
+	OperationEdits                        Operation = "Edits"
+	OperationGetEngine                    Operation = "GetEngine"
+	OperationGetFile                      Operation = "GetFile"
+	OperationGetFileContent               Operation = "GetFileContent"
+	OperationGetFineTune                  Operation = "GetFineTune"
+	OperationGetModel                     Operation = "GetModel"
+	OperationListAssistantFiles           Operation = "ListAssistantFiles"
+	OperationListAssistants               Operation = "ListAssistants"
+	OperationListBatch                    Operation = "ListBatch"
+	OperationListEngines                  Operation = "ListEngines"
+	OperationListFiles                    Operation = "ListFiles"
+	OperationListFineTuneEvents           Operation = "ListFineTuneEvents"
+	OperationListFineTunes                Operation = "ListFineTunes"
+	OperationListFineTuningJobEvents      Operation = "ListFineTuningJobEvents"
+	OperationListMessage                  Operation = "ListMessage"
+	OperationListMessageFiles             Operation = "ListMessageFiles"
+	OperationListModels                   Operation = "ListModels"
+	OperationListRunSteps                 Operation = "ListRunSteps"
+	OperationListRuns                     Operation = "ListRuns"
+	OperationListVectorStoreFiles         Operation = "ListVectorStoreFiles"
+	OperationListVectorStoreFilesInBatch  Operation = "ListVectorStoreFilesInBatch"
+	OperationListVectorStores             Operation = "ListVectorStores"
+	OperationModerations                  Operation = "Moderations"
+	OperationModifyAssistant              Operation = "ModifyAssistant"
+	OperationModifyMessage                Operation = "ModifyMessage"
+	OperationModifyRun                    Operation = "ModifyRun"
+	OperationModifyThread                 Operation = "ModifyThread"
+	OperationModifyVectorStore            Operation = "ModifyVectorStore"
+	OperationRetrieveAssistant            Operation = "RetrieveAssistant"
+	OperationRetrieveAssistantFile        Operation = "RetrieveAssistantFile"
+	OperationRetrieveBatch                Operation = "RetrieveBatch"
+	OperationRetrieveFineTuningJob        Operation = "RetrieveFineTuningJob"
+	OperationRetrieveMessage              Operation = "RetrieveMessage"
+	OperationRetrieveMessageFile          Operation = "RetrieveMessageFile"
+	OperationRetrieveRun                  Operation = "RetrieveRun"
+	OperationRetrieveRunStep              Operation = "RetrieveRunStep"
+	OperationRetrieveThread               Operation = "RetrieveThread"
+	OperationRetrieveVectorStore          Operation = "RetrieveVectorStore"
+	OperationRetrieveVectorStoreFile      Operation = "RetrieveVectorStoreFile"
+	OperationRetrieveVectorStoreFileBatch Operation = "RetrieveVectorStoreFileBatch"
+	OperationSubmitToolOutputs            Operation = "SubmitToolOutputs"
+)
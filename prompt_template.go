@@ -0,0 +1,507 @@
+package giteeai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const (
+	promptExtension = ".prompt"
+	// promptImageMarker delimits the index of an image in rendered text.
+	promptImageMarker = "\x00"
+)
+
+var (
+	// ErrMissingPromptVariables is returned when the data rendered by a
+	// prompt template lacks some of its variables.
+	ErrMissingPromptVariables = errors.New("missing prompt variables")
+	// ErrPromptNotFound is returned by PromptLibrary for unknown templates.
+	ErrPromptNotFound = errors.New("prompt template not found")
+
+	promptRoleLine    = regexp.MustCompile(`^-- *(system|developer|user|assistant) *--\s*$`)
+	promptVersionName = regexp.MustCompile(`^(.+)\.v(\d+)$`)
+	promptImageIndex  = regexp.MustCompile(promptImageMarker + `(\d+)` + promptImageMarker)
+)
+
+// PromptTemplate renders a list of chat messages with text/template.
+//
+// The messages are introduced by role lines such as "-- system --", "-- user --"
+// or "-- assistant --", e.g. for few-shot examples:
+//
+//	-- system --
+//	You translate {{.Source}} to {{.Target}}.
+//	-- user --
+//	{{template "example" .}}
+//	-- assistant --
+//	{{.ExampleAnswer}}
+//	-- user --
+//	{{.Text}} {{image .ImageURL}}
+//
+// The text before the first role line may only define templates. The content
+// of every message is trimmed, and messages rendering empty are omitted. As
+// the messages are split before rendering, variables cannot add messages.
+//
+// Besides the text/template functions, templates can call image, which adds
+// an image part with an URL and an optional detail, json, which encodes a
+// value as JSON, and join, which joins strings with a separator.
+type PromptTemplate struct {
+	Name    string
+	Version int
+
+	tmpl      *template.Template
+	roles     []string
+	variables []string
+}
+
+// ParsePromptTemplate parses a prompt template. partials holds templates,
+// indexed by name, that text can call with the template action.
+func ParsePromptTemplate(name, text string, partials map[string]string) (*PromptTemplate, error) {
+	t := &PromptTemplate{Name: name}
+	tmpl := template.New(name).Option("missingkey=error").Funcs(promptFuncs(nil))
+	for _, partial := range sortedStringKeys(partials) {
+		if _, err := tmpl.New(partial).Parse(partials[partial]); err != nil {
+			return nil, fmt.Errorf("prompt %s: partial %s: %w", name, partial, err)
+		}
+	}
+
+	preamble, sections, roles := splitPromptSections(text)
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("prompt %s: no message, e.g. \"-- user --\"", name)
+	}
+	if _, err := tmpl.Parse(preamble); err != nil {
+		return nil, fmt.Errorf("prompt %s: %w", name, err)
+	}
+	if tmpl.Tree != nil {
+		for _, node := range tmpl.Tree.Root.Nodes {
+			if text, ok := node.(*parse.TextNode); !ok || len(bytes.TrimSpace(text.Text)) > 0 {
+				return nil, fmt.Errorf("prompt %s: text before the first message", name)
+			}
+		}
+	}
+	for i, section := range sections {
+		if _, err := tmpl.New(promptSectionName(i)).Parse(section); err != nil {
+			return nil, fmt.Errorf("prompt %s: %s message %d: %w", name, roles[i], i+1, err)
+		}
+	}
+	t.tmpl, t.roles = tmpl, roles
+	t.variables = t.collectVariables()
+	return t, nil
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func promptSectionName(i int) string {
+	return "message " + strconv.Itoa(i)
+}
+
+// splitPromptSections splits text at the role lines.
+func splitPromptSections(text string) (preamble string, sections, roles []string) {
+	var current []string
+	lines := strings.SplitAfter(text, "\n")
+	for _, line := range lines {
+		if match := promptRoleLine.FindStringSubmatch(line); match != nil {
+			if roles == nil {
+				preamble = strings.Join(current, "")
+			} else {
+				sections = append(sections, strings.Join(current, ""))
+			}
+			roles = append(roles, match[1])
+			current = nil
+			continue
+		}
+		current = append(current, line)
+	}
+	if roles == nil {
+		return strings.Join(current, ""), nil, nil
+	}
+	return preamble, append(sections, strings.Join(current, "")), roles
+}
+
+// Variables returns the names of the variables used by the template, sorted.
+func (t *PromptTemplate) Variables() []string {
+	return append([]string(nil), t.variables...)
+}
+
+// collectVariables returns the fields of the data used by the messages,
+// including those used by the templates they call with the data.
+func (t *PromptTemplate) collectVariables() []string {
+	c := &variableCollector{tmpl: t.tmpl, found: make(map[string]bool), visited: make(map[string]bool)}
+	for i := range t.roles {
+		c.walk(t.tmpl.Lookup(promptSectionName(i)).Tree.Root, true)
+	}
+	variables := make([]string, 0, len(c.found))
+	for variable := range c.found {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+type variableCollector struct {
+	tmpl    *template.Template
+	found   map[string]bool
+	visited map[string]bool
+}
+
+// walk collects the variables of node. root tells whether dot is the data.
+func (c *variableCollector) walk(node parse.Node, root bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.walk(child, root)
+		}
+	case *parse.ActionNode:
+		c.walk(n.Pipe, root)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			c.walk(cmd, root)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			c.walk(arg, root)
+		}
+	case *parse.ChainNode:
+		c.walk(n.Node, root)
+	case *parse.FieldNode:
+		if root {
+			c.found[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			c.found[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		c.walk(n.Pipe, root)
+		c.walk(n.List, root)
+		c.walk(n.ElseList, root)
+	case *parse.RangeNode:
+		// Dot is an element in the body.
+		c.walk(n.Pipe, root)
+		c.walk(n.List, false)
+		c.walk(n.ElseList, root)
+	case *parse.WithNode:
+		c.walk(n.Pipe, root)
+		c.walk(n.List, false)
+		c.walk(n.ElseList, root)
+	case *parse.TemplateNode:
+		c.walk(n.Pipe, root)
+		if !root || n.Pipe == nil || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+			return
+		}
+		if _, isDot := n.Pipe.Cmds[0].Args[0].(*parse.DotNode); !isDot || c.visited[n.Name] {
+			return
+		}
+		c.visited[n.Name] = true
+		if called := c.tmpl.Lookup(n.Name); called != nil && called.Tree != nil {
+			c.walk(called.Tree.Root, true)
+		}
+	}
+}
+
+// checkData returns ErrMissingPromptVariables when data lacks variables.
+func (t *PromptTemplate) checkData(data any) error {
+	if len(t.variables) == 0 {
+		return nil
+	}
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+	var missing []string
+	switch {
+	case value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String:
+		for _, variable := range t.variables {
+			if !value.MapIndex(reflect.ValueOf(variable).Convert(value.Type().Key())).IsValid() {
+				missing = append(missing, variable)
+			}
+		}
+	case value.IsValid() && value.Kind() != reflect.Pointer && value.Kind() != reflect.Interface:
+		missing = missingFields(reflect.TypeOf(data), t.variables)
+	default:
+		missing = t.variables
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("prompt %s: %w: %s", t.Name, ErrMissingPromptVariables, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// missingFields returns the variables that are neither a field nor a method
+// of typ.
+func missingFields(typ reflect.Type, variables []string) []string {
+	if typ.Kind() == reflect.Map && typ.Key().Kind() == reflect.String {
+		return nil
+	}
+	var missing []string
+	for _, variable := range variables {
+		if _, ok := typ.MethodByName(variable); ok {
+			continue
+		}
+		if typ.Kind() != reflect.Pointer {
+			if _, ok := reflect.PointerTo(typ).MethodByName(variable); ok {
+				continue
+			}
+		}
+		structType := typ
+		for structType.Kind() == reflect.Pointer {
+			structType = structType.Elem()
+		}
+		if structType.Kind() == reflect.Struct {
+			if field, ok := structType.FieldByName(variable); ok && field.IsExported() {
+				continue
+			}
+		}
+		missing = append(missing, variable)
+	}
+	return missing
+}
+
+// Render renders the messages with data, a map or a struct providing every
+// variable of the template.
+func (t *PromptTemplate) Render(data any) ([]ChatCompletionMessage, error) {
+	if err := t.checkData(data); err != nil {
+		return nil, err
+	}
+	tmpl, err := t.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	var images []ChatMessageImageURL
+	tmpl.Funcs(promptFuncs(&images))
+
+	messages := make([]ChatCompletionMessage, 0, len(t.roles))
+	for i, role := range t.roles {
+		images = images[:0]
+		var buf bytes.Buffer
+		if err = tmpl.ExecuteTemplate(&buf, promptSectionName(i), data); err != nil {
+			return nil, fmt.Errorf("prompt %s: %w", t.Name, err)
+		}
+		content := strings.TrimSpace(buf.String())
+		if content == "" {
+			continue
+		}
+		message := ChatCompletionMessage{Role: role}
+		if len(images) == 0 {
+			message.Content = content
+		} else {
+			message.MultiContent = promptParts(content, images)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// promptParts splits content at the image markers.
+func promptParts(content string, images []ChatMessageImageURL) []ChatMessagePart {
+	var parts []ChatMessagePart
+	addText := func(text string) {
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, ChatMessagePart{Type: ChatMessagePartTypeText, Text: text})
+		}
+	}
+	start := 0
+	for _, loc := range promptImageIndex.FindAllStringSubmatchIndex(content, -1) {
+		addText(content[start:loc[0]])
+		index, _ := strconv.Atoi(content[loc[2]:loc[3]])
+		image := images[index]
+		parts = append(parts, ChatMessagePart{Type: ChatMessagePartTypeImageURL, ImageURL: &image})
+		start = loc[1]
+	}
+	addText(content[start:])
+	return parts
+}
+
+// RenderRequest returns a copy of request with the rendered messages
+// appended to its messages.
+func (t *PromptTemplate) RenderRequest(request ChatCompletionRequest, data any) (ChatCompletionRequest, error) {
+	messages, err := t.Render(data)
+	if err != nil {
+		return request, err
+	}
+	request.Messages = append(append([]ChatCompletionMessage(nil), request.Messages...), messages...)
+	return request, nil
+}
+
+// promptFuncs returns the template functions. The images are added to
+// images, which is nil when parsing.
+func promptFuncs(images *[]ChatMessageImageURL) template.FuncMap {
+	return template.FuncMap{
+		"image": func(url string, detail ...ImageURLDetail) (string, error) {
+			if images == nil {
+				return "", nil
+			}
+			if len(detail) > 1 {
+				return "", errors.New("image takes an URL and an optional detail")
+			}
+			image := ChatMessageImageURL{URL: url}
+			if len(detail) == 1 {
+				image.Detail = detail[0]
+			}
+			*images = append(*images, image)
+			return promptImageMarker + strconv.Itoa(len(*images)-1) + promptImageMarker, nil
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"join": func(sep string, values []string) string {
+			return strings.Join(values, sep)
+		},
+	}
+}
+
+// TypedPrompt renders a PromptTemplate with data of type T, whose fields or
+// methods are checked against the variables of the template once, when the
+// TypedPrompt is created.
+type TypedPrompt[T any] struct {
+	Template *PromptTemplate
+}
+
+// NewTypedPrompt returns ErrMissingPromptVariables when T does not provide
+// every variable of template.
+func NewTypedPrompt[T any](template *PromptTemplate) (*TypedPrompt[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if missing := missingFields(typ, template.variables); len(missing) > 0 {
+		return nil, fmt.Errorf("prompt %s: %w in %s: %s",
+			template.Name, ErrMissingPromptVariables, typ, strings.Join(missing, ", "))
+	}
+	return &TypedPrompt[T]{Template: template}, nil
+}
+
+// Render renders the messages with data.
+func (p *TypedPrompt[T]) Render(data T) ([]ChatCompletionMessage, error) {
+	return p.Template.Render(data)
+}
+
+// RenderRequest returns a copy of request with the messages rendered with
+// data appended to its messages.
+func (p *TypedPrompt[T]) RenderRequest(request ChatCompletionRequest, data T) (ChatCompletionRequest, error) {
+	return p.Template.RenderRequest(request, data)
+}
+
+// PromptLibrary holds the versions of prompt templates loaded from files.
+type PromptLibrary struct {
+	templates map[string]map[int]*PromptTemplate
+}
+
+// LoadPromptLibrary parses the ".prompt" files of fsys under dir. A file is
+// named after its template and version, e.g. "support/triage.v2.prompt" is the
+// version 2 of "support/triage", and "triage.prompt" the version 0 of
+// "triage". Files whose name starts with an underscore, e.g. "_examples.prompt",
+// are partials: every template can call them by name without the underscore
+// and extension, e.g. {{template "examples" .}}.
+func LoadPromptLibrary(fsys fs.FS, dir string) (*PromptLibrary, error) {
+	partials := make(map[string]string)
+	sources := make(map[string]string)
+	err := fs.WalkDir(fsys, dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(file) != promptExtension {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		if base := path.Base(file); strings.HasPrefix(base, "_") {
+			partials[strings.TrimSuffix(base[1:], promptExtension)] = string(content)
+		} else {
+			sources[file] = string(content)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	library := &PromptLibrary{templates: make(map[string]map[int]*PromptTemplate)}
+	for file, source := range sources {
+		name := strings.TrimSuffix(file, promptExtension)
+		if rel := strings.TrimPrefix(name, strings.TrimSuffix(dir, "/")+"/"); dir != "." {
+			name = rel
+		}
+		version := 0
+		if match := promptVersionName.FindStringSubmatch(name); match != nil {
+			name = match[1]
+			if version, err = strconv.Atoi(match[2]); err != nil {
+				return nil, fmt.Errorf("%s: invalid version: %w", file, err)
+			}
+		}
+		if _, ok := library.templates[name][version]; ok {
+			return nil, fmt.Errorf("%s: duplicate version %d of prompt %s", file, version, name)
+		}
+		t, err := ParsePromptTemplate(name, source, partials)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		t.Version = version
+		if library.templates[name] == nil {
+			library.templates[name] = make(map[int]*PromptTemplate)
+		}
+		library.templates[name][version] = t
+	}
+	return library, nil
+}
+
+// Names returns the names of the templates, sorted.
+func (l *PromptLibrary) Names() []string {
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Versions returns the versions of a template, in increasing order.
+func (l *PromptLibrary) Versions(name string) []int {
+	versions := make([]int, 0, len(l.templates[name]))
+	for version := range l.templates[name] {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// Get returns the latest version of a template.
+func (l *PromptLibrary) Get(name string) (*PromptTemplate, error) {
+	versions := l.Versions(name)
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+	return l.templates[name][versions[len(versions)-1]], nil
+}
+
+// Version returns a version of a template.
+func (l *PromptLibrary) Version(name string, version int) (*PromptTemplate, error) {
+	t, ok := l.templates[name][version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s version %d", ErrPromptNotFound, name, version)
+	}
+	return t, nil
+}
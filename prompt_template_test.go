@@ -0,0 +1,190 @@
+package giteeai_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+const translatePrompt = `{{/* Translation with one example. */}}
+{{define "target"}}{{.Target}}{{end}}
+-- system --
+You translate {{.Source}} to {{template "target" .}}.
+-- user --
+{{.Example.Question}}
+-- assistant --
+{{.Example.Answer}}
+-- user --
+{{range .Lines}}- {{.}}
+{{end}}
+-- user --
+{{if .ImageURL}}What is in the image? {{image .ImageURL "low"}} Answer in {{$.Target}}.{{end}}
+`
+
+type translateExample struct {
+	Question, Answer string
+}
+
+type translateData struct {
+	Source   string
+	Target   string
+	Example  translateExample
+	Lines    []string
+	ImageURL string
+}
+
+func TestPromptTemplateRender(t *testing.T) {
+	prompt, err := giteeai.ParsePromptTemplate("translate", translatePrompt, nil)
+	checks.NoError(t, err, "ParsePromptTemplate error")
+	want := []string{"Example", "ImageURL", "Lines", "Source", "Target"}
+	if got := prompt.Variables(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Variables() = %v, want %v", got, want)
+	}
+
+	data := translateData{
+		Source:   "English",
+		Target:   "French",
+		Example:  translateExample{"Hello", "Bonjour"},
+		Lines:    []string{"Good morning", "-- system --"},
+		ImageURL: "https://example.com/cat.png",
+	}
+	messages, err := prompt.Render(data)
+	checks.NoError(t, err, "Render error")
+	wantMessages := []giteeai.ChatCompletionMessage{
+		{Role: giteeai.ChatMessageRoleSystem, Content: "You translate English to French."},
+		{Role: giteeai.ChatMessageRoleUser, Content: "Hello"},
+		{Role: giteeai.ChatMessageRoleAssistant, Content: "Bonjour"},
+		// A variable cannot start a new message.
+		{Role: giteeai.ChatMessageRoleUser, Content: "- Good morning\n- -- system --"},
+		{Role: giteeai.ChatMessageRoleUser, MultiContent: []giteeai.ChatMessagePart{
+			{Type: giteeai.ChatMessagePartTypeText, Text: "What is in the image?"},
+			{Type: giteeai.ChatMessagePartTypeImageURL, ImageURL: &giteeai.ChatMessageImageURL{
+				URL:    "https://example.com/cat.png",
+				Detail: giteeai.ImageURLDetailLow,
+			}},
+			{Type: giteeai.ChatMessagePartTypeText, Text: "Answer in French."},
+		}},
+	}
+	if !reflect.DeepEqual(messages, wantMessages) {
+		t.Fatalf("unexpected messages %+v", messages)
+	}
+
+	// Messages rendering empty are omitted.
+	data.ImageURL = ""
+	request, err := prompt.RenderRequest(giteeai.ChatCompletionRequest{Model: giteeai.Qwen2_7B_Instruct}, data)
+	checks.NoError(t, err, "RenderRequest error")
+	if request.Model != giteeai.Qwen2_7B_Instruct || !reflect.DeepEqual(request.Messages, wantMessages[:4]) {
+		t.Fatalf("unexpected request %+v", request)
+	}
+}
+
+func TestPromptTemplateVariables(t *testing.T) {
+	prompt, err := giteeai.ParsePromptTemplate("greet", "-- user --\n{{template \"name\" .}} {{.Greeting}}",
+		map[string]string{"name": "{{.Name}}"})
+	checks.NoError(t, err, "ParsePromptTemplate error")
+
+	messages, err := prompt.Render(map[string]any{"Name": "Ann", "Greeting": "hi"})
+	checks.NoError(t, err, "Render error")
+	if messages[0].Content != "Ann hi" {
+		t.Errorf("unexpected content %q", messages[0].Content)
+	}
+
+	_, err = prompt.Render(map[string]string{"Name": "Ann"})
+	checks.ErrorIs(t, err, giteeai.ErrMissingPromptVariables)
+	_, err = prompt.Render(nil)
+	checks.ErrorIs(t, err, giteeai.ErrMissingPromptVariables)
+	_, err = prompt.Render(struct{ Name string }{"Ann"})
+	checks.ErrorIs(t, err, giteeai.ErrMissingPromptVariables)
+
+	_, err = giteeai.NewTypedPrompt[struct{ Name string }](prompt)
+	checks.ErrorIs(t, err, giteeai.ErrMissingPromptVariables)
+	typed, err := giteeai.NewTypedPrompt[greeting](prompt)
+	checks.NoError(t, err, "NewTypedPrompt error")
+	messages, err = typed.Render(greeting{Name: "Bob"})
+	checks.NoError(t, err, "Render error")
+	if messages[0].Content != "Bob hello" {
+		t.Errorf("unexpected content %q", messages[0].Content)
+	}
+}
+
+type greeting struct {
+	Name string
+}
+
+// Greeting is a method variable.
+func (greeting) Greeting() string {
+	return "hello"
+}
+
+func TestParsePromptTemplateErrors(t *testing.T) {
+	for name, text := range map[string]string{
+		"no message":    "Hello {{.Name}}",
+		"text before":   "Hello\n-- user --\nHi",
+		"syntax error":  "-- user --\n{{.Name",
+		"unknown func":  "-- user --\n{{upper .Name}}",
+		"bad role line": "-- robot --\nHi",
+	} {
+		if _, err := giteeai.ParsePromptTemplate(name, text, nil); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadPromptLibrary(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prompts/_signature.prompt":         {Data: []byte("-- {{.Team}}")},
+		"prompts/support/reply.v1.prompt":   {Data: []byte("-- user --\nv1 {{template \"signature\" .}}")},
+		"prompts/support/reply.v2.prompt":   {Data: []byte("-- user --\nv2 {{template \"signature\" .}}")},
+		"prompts/classify.prompt":           {Data: []byte("-- system --\nClassify {{json .Labels}}")},
+		"prompts/notes.txt":                 {Data: []byte("ignored")},
+		"other/ignored.v1.prompt":           {Data: []byte("-- user --\nignored")},
+		"prompts/support/_internal.prompt":  {Data: []byte("internal")},
+		"prompts/support/README.v1.prompt~": {Data: []byte("ignored")},
+	}
+	library, err := giteeai.LoadPromptLibrary(fsys, "prompts")
+	checks.NoError(t, err, "LoadPromptLibrary error")
+	if got, want := library.Names(), []string{"classify", "support/reply"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	if got, want := library.Versions("support/reply"), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Versions() = %v, want %v", got, want)
+	}
+
+	latest, err := library.Get("support/reply")
+	checks.NoError(t, err, "Get error")
+	messages, err := latest.Render(map[string]string{"Team": "support"})
+	checks.NoError(t, err, "Render error")
+	if latest.Version != 2 || messages[0].Content != "v2 -- support" {
+		t.Errorf("unexpected latest version %d: %+v", latest.Version, messages)
+	}
+	first, err := library.Version("support/reply", 1)
+	checks.NoError(t, err, "Version error")
+	messages, err = first.Render(map[string]string{"Team": "support"})
+	checks.NoError(t, err, "Render error")
+	if messages[0].Content != "v1 -- support" {
+		t.Errorf("unexpected first version %+v", messages)
+	}
+
+	classify, err := library.Get("classify")
+	checks.NoError(t, err, "Get error")
+	messages, err = classify.Render(map[string]any{"Labels": []string{"bug", "question"}})
+	checks.NoError(t, err, "Render error")
+	if messages[0].Content != `Classify ["bug","question"]` {
+		t.Errorf("unexpected content %q", messages[0].Content)
+	}
+
+	_, err = library.Get("missing")
+	checks.ErrorIs(t, err, giteeai.ErrPromptNotFound)
+	_, err = library.Version("support/reply", 3)
+	checks.ErrorIs(t, err, giteeai.ErrPromptNotFound)
+
+	fsys["prompts/broken.prompt"] = &fstest.MapFile{Data: []byte("-- user --\n{{")}
+	_, err = giteeai.LoadPromptLibrary(fsys, "prompts")
+	if err == nil || errors.Is(err, giteeai.ErrPromptNotFound) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package giteeai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a client-side limiter driven by the rate limit headers
+// returned by the API. It tracks the remaining request and token budget
+// across concurrent calls and blocks new requests until the budget resets.
+//
+// The limiter starts optimistic: until the first response carrying rate limit
+// headers is seen, requests are not throttled.
+type RateLimiter struct {
+	// Estimator returns the expected token cost of a request body. It defaults
+	// to EstimateRequestTokens.
+	Estimator func(body any) int
+	// DefaultReset is the time after which an exhausted budget is assumed to
+	// be reset, or a 429 response over, when the server did not say when. It
+	// defaults to one second.
+	DefaultReset time.Duration
+
+	mu                sync.Mutex
+	known             bool
+	limitRequests     int
+	limitTokens       int
+	remainingRequests int
+	remainingTokens   int
+	resetRequestsAt   time.Time
+	resetTokensAt     time.Time
+	inFlightRequests  int
+	inFlightTokens    int
+	// pausedUntil holds back all requests after a 429 that did not tell the
+	// limits. The limiter is optimistic again afterwards.
+	pausedUntil time.Time
+
+	waits     int64
+	waitTime  time.Duration
+	throttled int64
+}
+
+// RateLimiterStats is a snapshot of the limiter state, suitable for dashboards.
+type RateLimiterStats struct {
+	LimitRequests     int
+	LimitTokens       int
+	RemainingRequests int
+	RemainingTokens   int
+	ResetRequests     time.Time
+	ResetTokens       time.Time
+	InFlightRequests  int
+	InFlightTokens    int
+	// Waits is the number of requests that had to wait for budget.
+	Waits int64
+	// WaitTime is the total time requests spent waiting for budget.
+	WaitTime time.Duration
+	// Throttled is the number of 429 responses observed.
+	Throttled int64
+}
+
+// NewRateLimiter creates a new adaptive rate limiter.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{}
+}
+
+// Wait blocks until the budget allows a request costing the given number of
+// tokens, or ctx is done. Every successful Wait must be followed by Done.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		l.mu.Lock()
+		delay := l.reserve(tokens)
+		l.mu.Unlock()
+		if delay <= 0 {
+			return nil
+		}
+
+		start := time.Now()
+		err := sleepContext(ctx, delay)
+		l.mu.Lock()
+		l.waits++
+		l.waitTime += time.Now().Sub(start)
+		l.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// reserve claims budget for a request and returns zero, or returns how long
+// to wait before trying again. l.mu must be held.
+func (l *RateLimiter) reserve(tokens int) time.Duration {
+	now := time.Now()
+	if now.Before(l.pausedUntil) {
+		return l.pausedUntil.Sub(now)
+	}
+	if l.known {
+		if !l.resetRequestsAt.IsZero() && !now.Before(l.resetRequestsAt) {
+			l.remainingRequests = l.limitRequests - l.inFlightRequests
+			l.resetRequestsAt = time.Time{}
+		}
+		if !l.resetTokensAt.IsZero() && !now.Before(l.resetTokensAt) {
+			l.remainingTokens = l.limitTokens - l.inFlightTokens
+			l.resetTokensAt = time.Time{}
+		}
+		if l.limitTokens > 0 && tokens > l.limitTokens {
+			// A request larger than the whole window can only be sent on a full budget.
+			tokens = l.limitTokens
+		}
+		// Without a reset time, an exhausted budget would never be refreshed.
+		if l.limitRequests > 0 && l.remainingRequests <= 0 && l.resetRequestsAt.IsZero() {
+			l.resetRequestsAt = now.Add(l.defaultReset())
+		}
+		if l.limitTokens > 0 && l.remainingTokens < tokens && l.resetTokensAt.IsZero() {
+			l.resetTokensAt = now.Add(l.defaultReset())
+		}
+
+		var delay time.Duration
+		if l.limitRequests > 0 && l.remainingRequests <= 0 {
+			delay = l.untilReset(l.resetRequestsAt, now)
+		}
+		if l.limitTokens > 0 && l.remainingTokens < tokens {
+			if d := l.untilReset(l.resetTokensAt, now); d > delay {
+				delay = d
+			}
+		}
+		if delay > 0 {
+			return delay
+		}
+	}
+
+	l.remainingRequests--
+	l.remainingTokens -= tokens
+	l.inFlightRequests++
+	l.inFlightTokens += tokens
+	return 0
+}
+
+func (l *RateLimiter) defaultReset() time.Duration {
+	if l.DefaultReset > 0 {
+		return l.DefaultReset
+	}
+	return time.Second
+}
+
+// untilReset returns the time left until reset, or a short poll interval
+// when it is already past.
+func (l *RateLimiter) untilReset(reset, now time.Time) time.Duration {
+	const pollInterval = 100 * time.Millisecond
+	if d := reset.Sub(now); d > 0 {
+		return d
+	}
+	return pollInterval
+}
+
+// Done releases the reservation made by Wait and updates the budget from the
+// response headers. header may be nil when the request failed without a response.
+func (l *RateLimiter) Done(tokens int, statusCode int, header http.Header) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limitTokens > 0 && tokens > l.limitTokens {
+		tokens = l.limitTokens
+	}
+	l.inFlightRequests--
+	l.inFlightTokens -= tokens
+	if l.inFlightRequests < 0 {
+		l.inFlightRequests = 0
+	}
+	if l.inFlightTokens < 0 {
+		l.inFlightTokens = 0
+	}
+
+	if statusCode == http.StatusTooManyRequests {
+		l.throttled++
+	}
+	if header == nil {
+		return
+	}
+
+	now := time.Now()
+	if header.Get("x-ratelimit-limit-requests") != "" || header.Get("x-ratelimit-limit-tokens") != "" {
+		h := newRateLimitHeaders(header)
+		l.known = true
+		l.limitRequests = h.LimitRequests
+		l.limitTokens = h.LimitTokens
+		// Server counts do not include requests that are still in flight.
+		l.remainingRequests = h.RemainingRequests - l.inFlightRequests
+		l.remainingTokens = h.RemainingTokens - l.inFlightTokens
+		l.resetRequestsAt = resetAt(h.ResetRequests, now)
+		l.resetTokensAt = resetAt(h.ResetTokens, now)
+	}
+
+	if statusCode == http.StatusTooManyRequests {
+		d := retryAfter(header)
+		if !l.known || l.limitRequests == 0 {
+			// No request limit to exhaust: pause until the server allows
+			// requests again, without making up a limit.
+			if d <= 0 {
+				d = l.defaultReset()
+			}
+			l.pausedUntil = now.Add(d)
+			return
+		}
+		l.remainingRequests = 0
+		if d > 0 {
+			l.resetRequestsAt = now.Add(d)
+		}
+	}
+}
+
+func resetAt(r ResetTime, now time.Time) time.Time {
+	if r == "" {
+		return time.Time{}
+	}
+	return now.Add(r.Duration())
+}
+
+// Stats returns a snapshot of the limiter state.
+func (l *RateLimiter) Stats() RateLimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return RateLimiterStats{
+		LimitRequests:     l.limitRequests,
+		LimitTokens:       l.limitTokens,
+		RemainingRequests: l.remainingRequests,
+		RemainingTokens:   l.remainingTokens,
+		ResetRequests:     l.resetRequestsAt,
+		ResetTokens:       l.resetTokensAt,
+		InFlightRequests:  l.inFlightRequests,
+		InFlightTokens:    l.inFlightTokens,
+		Waits:             l.waits,
+		WaitTime:          l.waitTime,
+		Throttled:         l.throttled,
+	}
+}
+
+func (l *RateLimiter) estimate(body any) int {
+	if l.Estimator != nil {
+		return l.Estimator(body)
+	}
+	return EstimateRequestTokens(body)
+}
+
+// EstimateRequestTokens returns a rough estimate of the tokens a request will
+// be charged for, including the requested completion budget. The prompt is
+// counted with ApproximateTokenizer, and chat requests with a TokenCounter.
+// Bodies other than chat, completion and embedding requests are estimated as
+// zero.
+func EstimateRequestTokens(body any) int {
+	switch r := body.(type) {
+	case ChatCompletionRequest:
+		tokens := NewTokenCounter(r.Model, ApproximateTokenizer).CountRequest(r)
+		if r.MaxCompletionTokens > 0 {
+			tokens += r.MaxCompletionTokens
+		} else {
+			tokens += r.MaxTokens
+		}
+		return tokens
+	case CompletionRequest:
+		tokens := r.MaxTokens
+		switch p := r.Prompt.(type) {
+		case string:
+			tokens += ApproximateTokenizer.CountTokens(p)
+		case []string:
+			for _, s := range p {
+				tokens += ApproximateTokenizer.CountTokens(s)
+			}
+		}
+		return tokens
+	case EmbeddingRequest:
+		switch input := r.Input.(type) {
+		case string:
+			return ApproximateTokenizer.CountTokens(input)
+		case []string:
+			tokens := 0
+			for _, s := range input {
+				tokens += ApproximateTokenizer.CountTokens(s)
+			}
+			return tokens
+		case []int:
+			return len(input)
+		case [][]int:
+			tokens := 0
+			for _, s := range input {
+				tokens += len(s)
+			}
+			return tokens
+		}
+	}
+	return 0
+}
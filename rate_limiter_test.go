@@ -0,0 +1,161 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func TestRateLimiterWaitsForReset(t *testing.T) {
+	limiter := giteeai.NewRateLimiter()
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.RateLimiter = limiter
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/models", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ratelimit-limit-requests", "10")
+		w.Header().Set("x-ratelimit-limit-tokens", "1000")
+		w.Header().Set("x-ratelimit-remaining-requests", "0")
+		w.Header().Set("x-ratelimit-remaining-tokens", "1000")
+		w.Header().Set("x-ratelimit-reset-requests", "200ms")
+		w.Header().Set("x-ratelimit-reset-tokens", "1s")
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+
+	ctx := context.Background()
+	_, err := client.ListModels(ctx)
+	checks.NoError(t, err, "ListModels error")
+
+	stats := limiter.Stats()
+	if stats.LimitRequests != 10 || stats.RemainingRequests != 0 || stats.InFlightRequests != 0 {
+		t.Fatalf("unexpected stats after first request: %+v", stats)
+	}
+
+	start := time.Now()
+	_, err = client.ListModels(ctx)
+	checks.NoError(t, err, "ListModels error")
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("second request was not throttled, took %s", elapsed)
+	}
+	if stats = limiter.Stats(); stats.Waits == 0 || stats.WaitTime == 0 {
+		t.Fatalf("wait was not recorded: %+v", stats)
+	}
+}
+
+func TestRateLimiterWaitRespectsContext(t *testing.T) {
+	limiter := giteeai.NewRateLimiter()
+	ctx := context.Background()
+	checks.NoError(t, limiter.Wait(ctx, 10), "first wait should not block")
+
+	header := http.Header{}
+	header.Set("x-ratelimit-limit-requests", "1")
+	header.Set("x-ratelimit-limit-tokens", "100")
+	header.Set("x-ratelimit-remaining-requests", "0")
+	header.Set("x-ratelimit-remaining-tokens", "0")
+	header.Set("x-ratelimit-reset-requests", "1m")
+	header.Set("x-ratelimit-reset-tokens", "1m")
+	limiter.Done(10, http.StatusTooManyRequests, header)
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	err := limiter.Wait(ctx, 10)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if stats := limiter.Stats(); stats.Throttled != 1 {
+		t.Fatalf("expected one throttled response, got %+v", stats)
+	}
+}
+
+func TestRateLimiterRecoversWithoutResetHeaders(t *testing.T) {
+	limiter := giteeai.NewRateLimiter()
+	limiter.DefaultReset = 20 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// A 429 without any rate limit header.
+	checks.NoError(t, limiter.Wait(ctx, 10), "first wait should not block")
+	limiter.Done(10, http.StatusTooManyRequests, http.Header{})
+	checks.NoError(t, limiter.Wait(ctx, 10), "Wait should succeed after the default reset")
+
+	// Exhausted limits without reset headers.
+	header := http.Header{}
+	header.Set("x-ratelimit-limit-requests", "1")
+	header.Set("x-ratelimit-limit-tokens", "100")
+	header.Set("x-ratelimit-remaining-requests", "0")
+	header.Set("x-ratelimit-remaining-tokens", "0")
+	limiter.Done(10, http.StatusOK, header)
+	checks.NoError(t, limiter.Wait(ctx, 10), "Wait should succeed after the default reset")
+}
+
+func TestRateLimiterThroughputAfterBare429(t *testing.T) {
+	limiter := giteeai.NewRateLimiter()
+	limiter.DefaultReset = 20 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	checks.NoError(t, limiter.Wait(ctx, 10), "first wait should not block")
+	limiter.Done(10, http.StatusTooManyRequests, http.Header{})
+	checks.NoError(t, limiter.Wait(ctx, 10), "Wait should succeed after the default reset")
+	limiter.Done(10, http.StatusOK, http.Header{})
+
+	// Once the 429 is over, requests are no longer throttled.
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		checks.NoError(t, limiter.Wait(ctx, 10), "Wait error")
+		limiter.Done(10, http.StatusOK, http.Header{})
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("requests were throttled after recovery, took %s", elapsed)
+	}
+	if stats := limiter.Stats(); stats.LimitRequests != 0 || stats.Throttled != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestEstimateRequestTokens(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     any
+		expected int
+	}{
+		{"nil", nil, 0},
+		{"chat", giteeai.ChatCompletionRequest{
+			Messages: []giteeai.ChatCompletionMessage{
+				{Role: giteeai.ChatMessageRoleUser, Content: "12345678"},
+				{Role: giteeai.ChatMessageRoleUser, Content: "你好"},
+			},
+			MaxTokens: 10,
+		}, (3 + 1 + 2) + (3 + 1 + 2) + 3 + 10}, // Message tokens, role and content, then request and reply.
+		{"embedding strings", giteeai.EmbeddingRequest{Input: []string{"abcd", "abcde"}}, 3},
+		{"embedding tokens", giteeai.EmbeddingRequest{Input: [][]int{{1, 2}, {3}}}, 3},
+		{"non-ASCII", giteeai.EmbeddingRequest{Input: "привет, مرحبا"}, 12},
+		{"completion", giteeai.CompletionRequest{Prompt: "abcd", MaxTokens: 5}, 6},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := giteeai.EstimateRequestTokens(tc.body); got != tc.expected {
+				t.Fatalf("EstimateRequestTokens() = %d, want %d", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestEstimateRequestTokensMatchesTokenCounter(t *testing.T) {
+	request := giteeai.ChatCompletionRequest{
+		Model:     giteeai.Qwen2_7B_Instruct,
+		Messages:  []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "héllo wörld, 你好"}},
+		MaxTokens: 10,
+	}
+	counted := giteeai.NewTokenCounter(request.Model, nil).CountRequest(request)
+	if got := giteeai.EstimateRequestTokens(request); got != counted+request.MaxTokens {
+		t.Errorf("EstimateRequestTokens() = %d, want %d", got, counted+request.MaxTokens)
+	}
+}
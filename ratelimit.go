@@ -23,8 +23,13 @@ func (r ResetTime) String() string {
 }
 
 func (r ResetTime) Time() time.Time {
+	return time.Now().Add(r.Duration())
+}
+
+// Duration returns the time left until the limit resets.
+func (r ResetTime) Duration() time.Duration {
 	d, _ := time.ParseDuration(string(r))
-	return time.Now().Add(d)
+	return d
 }
 
 func newRateLimitHeaders(h http.Header) RateLimitHeaders {
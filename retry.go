@@ -0,0 +1,227 @@
+package giteeai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 500 * time.Millisecond
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMultiplier     = 2
+	defaultRetryJitter         = 0.2
+)
+
+// RetryPolicy controls how the client retries requests that failed with a
+// transient error. A nil policy (the default) disables retries.
+//
+// Streams are only retried while establishing the connection; once a stream
+// has been returned to the caller no further attempts are made.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values lower than 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the computed exponential delay.
+	MaxBackoff time.Duration
+	// Multiplier is applied to the delay after every attempt.
+	Multiplier float64
+	// Jitter is the fraction (0..1) of the delay that is randomized to avoid
+	// synchronized retries across clients.
+	Jitter float64
+	// MaxRetryAfter caps the delay requested by the server through the
+	// Retry-After or x-ratelimit-reset-* headers. When the server asks for a
+	// longer delay the error is returned instead. Zero means no cap.
+	MaxRetryAfter time.Duration
+
+	// StatusCodes lists the HTTP status codes that are retried.
+	StatusCodes []int
+	// ErrorTypes overrides the status code classification for APIError.Type
+	// values: true forces a retry, false prevents it.
+	ErrorTypes map[string]bool
+	// RetryTransportErrors enables retrying errors returned by HTTPClient.Do,
+	// e.g. connection resets. Context cancellation is never retried.
+	RetryTransportErrors bool
+
+	// ShouldRetry, if set, replaces the built-in classification. It receives
+	// the 1-based number of the attempt that failed and its error.
+	ShouldRetry func(attempt int, err error) bool
+}
+
+// DefaultRetryPolicy returns a retry policy suitable for most callers: three
+// attempts with exponential backoff for rate limits and server errors.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    defaultRetryMaxAttempts,
+		InitialBackoff: defaultRetryInitialBackoff,
+		MaxBackoff:     defaultRetryMaxBackoff,
+		Multiplier:     defaultRetryMultiplier,
+		Jitter:         defaultRetryJitter,
+		StatusCodes: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		ErrorTypes: map[string]bool{
+			"insufficient_quota": false,
+			"server_error":       true,
+		},
+		RetryTransportErrors: true,
+	}
+}
+
+// Retryable reports whether err, returned by the given attempt, should be retried.
+func (p *RetryPolicy) Retryable(attempt int, err error) bool {
+	if p == nil || err == nil {
+		return false
+	}
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(attempt, err)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		if retry, ok := p.ErrorTypes[apiErr.Type]; ok && apiErr.Type != "" {
+			return retry
+		}
+		return p.retryableStatus(apiErr.HTTPStatusCode)
+	}
+
+	reqErr := &RequestError{}
+	if errors.As(err, &reqErr) {
+		return p.retryableStatus(reqErr.HTTPStatusCode)
+	}
+
+	return p.RetryTransportErrors
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay before the attempt following the given one,
+// ignoring any server provided hint.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	if p == nil || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= delay * jitter * rand.Float64() //nolint:gosec // jitter does not need a secure source
+	}
+	return time.Duration(delay)
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// retryAfter extracts the delay requested by the server, if any. Retry-After
+// takes precedence; otherwise the reset time of an exhausted rate limit is used.
+func retryAfter(h http.Header) time.Duration {
+	if h == nil {
+		return 0
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(v); err == nil {
+			return time.Until(date)
+		}
+	}
+
+	var delay time.Duration
+	rateLimit := newRateLimitHeaders(h)
+	if h.Get("x-ratelimit-remaining-requests") == "0" {
+		delay = rateLimit.ResetRequests.Duration()
+	}
+	if h.Get("x-ratelimit-remaining-tokens") == "0" {
+		if d := rateLimit.ResetTokens.Duration(); d > delay {
+			delay = d
+		}
+	}
+	return delay
+}
+
+// rewindableBody makes sure req can be replayed by buffering bodies that do
+// not provide GetBody. Bodies built by the request builder (JSON and
+// multipart forms) are bytes.Buffer backed and already rewindable.
+func rewindableBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// attemptRequest returns the request to send for the given attempt, rewinding
+// the body for every attempt after the first.
+func attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.GetBody == nil {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
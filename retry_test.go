@@ -0,0 +1,190 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+func fastRetryPolicy() *giteeai.RetryPolicy {
+	policy := giteeai.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func writeServerError(w http.ResponseWriter, status int, errType string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"error":{"message":"try again","type":%q}}`, errType)
+}
+
+func TestRetryRecoversFromServerErrors(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.RetryPolicy = fastRetryPolicy()
+	})
+	defer teardown()
+
+	var attempts int
+	var bodies []string
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if attempts < 3 {
+			writeServerError(w, http.StatusServiceUnavailable, "server_error")
+			return
+		}
+		fmt.Fprint(w, `{"id":"chatcmpl-1","choices":[{"message":{"role":"assistant","content":"hi"}}]}`)
+	})
+
+	resp, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{
+		Model:    giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "hello"}},
+	})
+	checks.NoError(t, err, "CreateChatCompletion error")
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+	if resp.Choices[0].Message.Content != "hi" {
+		t.Fatalf("unexpected content %q", resp.Choices[0].Message.Content)
+	}
+	for _, b := range bodies {
+		if b != bodies[0] || !strings.Contains(b, "hello") {
+			t.Fatalf("request body was not replayed: %q", bodies)
+		}
+	}
+}
+
+func TestRetryReplaysMultipartBody(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.RetryPolicy = fastRetryPolicy()
+	})
+	defer teardown()
+
+	var attempts int
+	server.RegisterHandler("/v1/files", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("attempt %d: invalid multipart body: %v", attempts, err)
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("attempt %d: missing file: %v", attempts, err)
+		} else {
+			content, _ := io.ReadAll(file)
+			if string(content) != "hello" {
+				t.Errorf("attempt %d: unexpected file content %q", attempts, content)
+			}
+		}
+		if attempts == 1 {
+			writeServerError(w, http.StatusBadGateway, "")
+			return
+		}
+		fmt.Fprint(w, `{"id":"file-1"}`)
+	})
+
+	file, err := client.CreateFileBytes(context.Background(), giteeai.FileBytesRequest{
+		Name:    "test.jsonl",
+		Bytes:   []byte("hello"),
+		Purpose: giteeai.PurposeBatch,
+	})
+	checks.NoError(t, err, "CreateFileBytes error")
+	if attempts != 2 || file.ID != "file-1" {
+		t.Fatalf("unexpected result after %d attempts: %+v", attempts, file)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		policy := fastRetryPolicy()
+		policy.MaxRetryAfter = time.Second
+		c.RetryPolicy = policy
+	})
+	defer teardown()
+
+	var attempts int
+	server.RegisterHandler("/v1/models", func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "1")
+			writeServerError(w, http.StatusTooManyRequests, "requests")
+			return
+		}
+		w.Header().Set("Retry-After", "60")
+		writeServerError(w, http.StatusTooManyRequests, "requests")
+	})
+
+	start := time.Now()
+	_, err := client.ListModels(context.Background())
+	apiErr := &giteeai.APIError{}
+	if !errors.As(err, &apiErr) || apiErr.HTTPStatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 APIError, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("Retry-After was not honored, retried after %s", elapsed)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected to give up once Retry-After exceeds the cap, got %d attempts", attempts)
+	}
+}
+
+func TestRetrySkipsNonRetryableErrors(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.RetryPolicy = fastRetryPolicy()
+	})
+	defer teardown()
+
+	var attempts int
+	server.RegisterHandler("/v1/models", func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		writeServerError(w, http.StatusTooManyRequests, "insufficient_quota")
+	})
+
+	_, err := client.ListModels(context.Background())
+	checks.HasError(t, err, "ListModels should fail")
+	if attempts != 1 {
+		t.Fatalf("insufficient_quota must not be retried, got %d attempts", attempts)
+	}
+}
+
+func TestRetryStreamIsNotRetriedAfterFirstEvent(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.RetryPolicy = fastRetryPolicy()
+	})
+	defer teardown()
+
+	var attempts int
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		if attempts == 1 {
+			writeServerError(w, http.StatusServiceUnavailable, "server_error")
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"1\",\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"boom\",\"type\":\"server_error\"}}\n\n")
+	})
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model: giteeai.Qwen2_7B_Instruct,
+	})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	_, err = stream.Recv()
+	checks.NoError(t, err, "first event should be delivered")
+	_, err = stream.Recv()
+	checks.HasError(t, err, "stream error should be surfaced")
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
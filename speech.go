@@ -56,6 +56,7 @@ func (c *Client) CreateSpeech(ctx context.Context, request CreateSpeechRequest)
 		c.fullURL("/audio/speech", withModel(string(request.Model))),
 		withBody(request),
 		withContentType("application/json"),
+		withOperation(OperationCreateSpeech),
 	)
 	if err != nil {
 		return
@@ -0,0 +1,176 @@
+package giteeai
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// sseMessageEvent is the type of the events without an event field.
+	sseMessageEvent = "message"
+	// sseErrorEvent is the type of the events reporting an error.
+	sseErrorEvent = "error"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ServerSentEvent is an event of a Server-Sent Events stream.
+type ServerSentEvent struct {
+	// Event is the type of the event, "message" when the server did not set it.
+	Event string
+	// ID is the last event ID set by the server, which is kept by the
+	// following events.
+	ID string
+	// Data is the data of the event, whose lines are joined with "\n".
+	Data string
+}
+
+// sseDecoder decodes a Server-Sent Events stream as specified by the WHATWG
+// HTML standard. Unlike browsers, it dispatches an event that is not followed
+// by a blank line at the end of the stream, as some servers omit it.
+type sseDecoder struct {
+	reader *bufio.Reader
+	// ignored is called with the lines that are neither fields nor blank
+	// lines dispatching an event: comments, unknown fields and blank lines
+	// without data. An error stops the decoding.
+	ignored func(line []byte) error
+
+	started bool
+	// pendingCR is set when the last line ended with CR: a LF read next is
+	// the end of that line. It is not peeked at, as waiting for the byte
+	// after CR would hold back the line on a live stream.
+	pendingCR   bool
+	lastEventID string
+	retry       time.Duration
+	eventType   string
+	data        bytes.Buffer
+	hasData     bool
+}
+
+func newSSEDecoder(reader *bufio.Reader) *sseDecoder {
+	return &sseDecoder{reader: reader}
+}
+
+// readLine returns the next line without its end, which is CRLF, LF or CR.
+// It returns io.EOF when there is no more line.
+func (d *sseDecoder) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		b, err := d.reader.ReadByte()
+		if err != nil {
+			if len(line) > 0 && errors.Is(err, io.EOF) {
+				return line, nil
+			}
+			return nil, err
+		}
+		if d.pendingCR {
+			d.pendingCR = false
+			if b == '\n' {
+				continue
+			}
+		}
+		switch b {
+		case '\n':
+			return line, nil
+		case '\r':
+			d.pendingCR = true
+			return line, nil
+		}
+		// The non-empty line is not returned as nil, so that the caller can
+		// tell it from a blank line.
+		if line == nil {
+			line = make([]byte, 0, 64)
+		}
+		line = append(line, b)
+	}
+}
+
+// Next returns the next event. At the end of the stream it returns io.EOF,
+// or the read error.
+func (d *sseDecoder) Next() (ServerSentEvent, error) {
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) && d.hasData {
+				return d.dispatch(), nil
+			}
+			return ServerSentEvent{}, err
+		}
+		if !d.started {
+			d.started = true
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+
+		if len(line) == 0 {
+			if d.hasData {
+				return d.dispatch(), nil
+			}
+			d.eventType = ""
+			if err = d.ignore(line); err != nil {
+				return ServerSentEvent{}, err
+			}
+			continue
+		}
+		if err = d.processField(line); err != nil {
+			return ServerSentEvent{}, err
+		}
+	}
+}
+
+func (d *sseDecoder) processField(line []byte) error {
+	if line[0] == ':' {
+		return d.ignore(line)
+	}
+	field, value := line, []byte(nil)
+	if i := bytes.IndexByte(line, ':'); i >= 0 {
+		field, value = line[:i], line[i+1:]
+		value = bytes.TrimPrefix(value, []byte(" "))
+	}
+	switch string(field) {
+	case "event":
+		d.eventType = string(value)
+	case "data":
+		d.data.Write(value)
+		d.data.WriteByte('\n')
+		d.hasData = true
+	case "id":
+		if bytes.IndexByte(value, 0) < 0 {
+			d.lastEventID = string(value)
+		}
+	case "retry":
+		if milliseconds, err := strconv.ParseUint(string(value), 10, 63); err == nil {
+			d.retry = time.Duration(milliseconds) * time.Millisecond
+		}
+	default:
+		return d.ignore(line)
+	}
+	return nil
+}
+
+func (d *sseDecoder) ignore(line []byte) error {
+	if d.ignored == nil {
+		return nil
+	}
+	return d.ignored(line)
+}
+
+// dispatch returns the buffered event and resets the buffers.
+func (d *sseDecoder) dispatch() ServerSentEvent {
+	data := d.data.Bytes()
+	event := ServerSentEvent{
+		Event: d.eventType,
+		ID:    d.lastEventID,
+		Data:  string(data[:len(data)-1]),
+	}
+	if event.Event == "" {
+		event.Event = sseMessageEvent
+	}
+	d.data.Reset()
+	d.hasData = false
+	d.eventType = ""
+	return event
+}
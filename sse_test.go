@@ -0,0 +1,219 @@
+package giteeai //nolint:testpackage // testing the unexported decoder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	utils "github.com/edmondfrank/go-giteeai/internal"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// decodeEvents returns all the events of the stream read from r.
+func decodeEvents(r io.Reader) ([]ServerSentEvent, *sseDecoder, error) {
+	decoder := newSSEDecoder(bufio.NewReader(r))
+	var events []ServerSentEvent
+	for {
+		event, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			return events, decoder, nil
+		}
+		if err != nil {
+			return events, decoder, err
+		}
+		events = append(events, event)
+	}
+}
+
+func TestSSEDecoder(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		stream string
+		want   []ServerSentEvent
+	}{
+		{
+			name:   "multiline data",
+			stream: "data: a\ndata:  b\ndata\n\n",
+			want:   []ServerSentEvent{{Event: "message", Data: "a\n b\n"}},
+		},
+		{
+			name:   "line endings",
+			stream: "data: a\r\n\r\ndata: b\r\rdata: c\n\n",
+			want: []ServerSentEvent{
+				{Event: "message", Data: "a"},
+				{Event: "message", Data: "b"},
+				{Event: "message", Data: "c"},
+			},
+		},
+		{
+			name:   "event types and ids",
+			stream: "\xEF\xBB\xBFevent: usage\nid: 1\ndata: x\n\n: comment\ndata: y\n\nid: 2\x00\nevent: done\n\ndata: z",
+			want: []ServerSentEvent{
+				{Event: "usage", ID: "1", Data: "x"},
+				{Event: "message", ID: "1", Data: "y"},
+				{Event: "message", ID: "1", Data: "z"},
+			},
+		},
+		{
+			name:   "fields without value",
+			stream: "data:\n\nevent\ndata:\n\nunknown: x\nid\ndata:x\n\n",
+			want: []ServerSentEvent{
+				{Event: "message", Data: ""},
+				{Event: "message", Data: ""},
+				{Event: "message", Data: "x"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			events, _, err := decodeEvents(strings.NewReader(tc.stream))
+			checks.NoError(t, err, "decode error")
+			if !reflect.DeepEqual(events, tc.want) {
+				t.Fatalf("got %+v, want %+v", events, tc.want)
+			}
+		})
+	}
+}
+
+func TestSSEDecoderCROnlyLineEndings(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	decoder := newSSEDecoder(bufio.NewReader(r))
+	events := make(chan ServerSentEvent)
+	go func() {
+		for {
+			event, err := decoder.Next()
+			if err != nil {
+				close(events)
+				return
+			}
+			events <- event
+		}
+	}()
+
+	for _, data := range []string{"x", "y"} {
+		_, err := io.WriteString(w, "data: "+data+"\r\r")
+		checks.NoError(t, err, "write error")
+		select {
+		case event := <-events:
+			if event.Data != data {
+				t.Errorf("unexpected event %+v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the event was not delivered before more bytes were written")
+		}
+	}
+	_, err := io.WriteString(w, "\ndata: z\r\n\r\n")
+	checks.NoError(t, err, "write error")
+	if event := <-events; event.Data != "z" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestSSEDecoderRetry(t *testing.T) {
+	_, decoder, err := decodeEvents(strings.NewReader("retry: 1500\n\nretry: 1.5\nretry: -1\nretry: x\n\n"))
+	checks.NoError(t, err, "decode error")
+	if decoder.retry != 1500*time.Millisecond {
+		t.Errorf("unexpected retry %s", decoder.retry)
+	}
+}
+
+func newTestStreamReader(stream string) *streamReader[ChatCompletionStreamResponse] {
+	return &streamReader[ChatCompletionStreamResponse]{
+		emptyMessagesLimit: 10,
+		reader:             bufio.NewReader(strings.NewReader(stream)),
+		errAccumulator:     utils.NewErrorAccumulator(),
+		unmarshaler:        &utils.JSONUnmarshaler{},
+	}
+}
+
+func TestStreamReaderEvents(t *testing.T) {
+	stream := newTestStreamReader("retry: 3000\n\n: ping\n\nevent: usage\nid: 7\ndata: {}\n\n" +
+		"data: {\"id\":\"a\",\ndata: \"object\":\"chunk\"}\n\ndata: [DONE]\n\n")
+	response, err := stream.Recv()
+	checks.NoError(t, err, "Recv error")
+	if response.ID != "a" || response.Object != "chunk" {
+		t.Errorf("unexpected response %+v", response)
+	}
+	if stream.LastEventID() != "7" || stream.RetryInterval() != 3*time.Second {
+		t.Errorf("unexpected last event ID %q and retry %s", stream.LastEventID(), stream.RetryInterval())
+	}
+	_, err = stream.Recv()
+	checks.ErrorIs(t, err, io.EOF)
+
+	stream = newTestStreamReader("event: usage\ndata: {}\n\ndata: {}\n\n")
+	event, err := stream.RecvEvent()
+	checks.NoError(t, err, "RecvEvent error")
+	if event.Event != "usage" || event.Data != "{}" {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestStreamReaderErrorEvents(t *testing.T) {
+	for stream, message := range map[string]string{
+		"event: error\ndata: {\"error\":{\"message\":\"quota\"}}\n\n": "quota",
+		"event: error\ndata: {\"message\":\"overloaded\"}\n\n":        "overloaded",
+		"event: error\ndata: busy\n\n":                                "busy",
+		"data: {\"error\":\n\n  {\"message\":\"lines\"}}\n":           "lines",
+	} {
+		_, err := newTestStreamReader(stream).Recv()
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Message != message {
+			t.Errorf("%q: expected an API error %q, got %v", stream, message, err)
+		}
+	}
+}
+
+// encodeEvent encodes the event as a Server-Sent Events frame.
+func encodeEvent(event ServerSentEvent) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", event.Event)
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", event.ID)
+	}
+	for _, line := range strings.Split(event.Data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func FuzzSSEDecoder(f *testing.F) {
+	for _, seed := range []string{
+		"data: a\n\n",
+		"event: e\nid: 1\ndata: a\ndata: b\n\n",
+		"data: a\r\ndata: b\r\rretry: 10\n\n",
+		"\xEF\xBB\xBF: comment\ndata\nfield\n\ndata:x",
+		"data: {\"error\":\ndata:\n\n",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, stream string) {
+		events, _, err := decodeEvents(strings.NewReader(stream))
+		if err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		byteEvents, _, err := decodeEvents(iotest.OneByteReader(strings.NewReader(stream)))
+		if err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if !reflect.DeepEqual(events, byteEvents) {
+			t.Fatalf("events depend on the reads: %+v and %+v", events, byteEvents)
+		}
+
+		for _, event := range events {
+			if strings.ContainsAny(event.Data, "\r") || strings.ContainsAny(event.Event+event.ID, "\r\n") {
+				t.Fatalf("line end in event %+v", event)
+			}
+			decoded, _, err := decodeEvents(strings.NewReader(encodeEvent(event)))
+			if err != nil || len(decoded) != 1 || decoded[0] != event {
+				t.Fatalf("event %+v decoded as %+v, %v", event, decoded, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package giteeai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidServerSentEvent is returned when writing an event whose type or
+// ID contains a line break.
+var ErrInvalidServerSentEvent = errors.New("server-sent event type or ID contains a line break")
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// SSEWriter writes a Server-Sent Events stream that streamReader can read,
+// flushing every event.
+type SSEWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+// NewSSEWriter sets the headers of an event stream and writes the status.
+func NewSSEWriter(w http.ResponseWriter) *SSEWriter {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Disables the buffering of nginx.
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &SSEWriter{w: w, flusher: flusher}
+}
+
+// WriteEvent writes the event. The event field is omitted for "message"
+// events and the id field when ID is empty.
+func (s *SSEWriter) WriteEvent(event ServerSentEvent) error {
+	if strings.ContainsAny(event.Event, "\r\n") || strings.ContainsAny(event.ID, "\r\n") {
+		return ErrInvalidServerSentEvent
+	}
+	var b strings.Builder
+	if event.Event != "" && event.Event != sseMessageEvent {
+		fmt.Fprintf(&b, "event: %s\n", event.Event)
+	}
+	if event.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", event.ID)
+	}
+	data := strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(event.Data)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// WriteJSON writes a message event with v encoded in JSON.
+func (s *SSEWriter) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.WriteEvent(ServerSentEvent{Data: string(data)})
+}
+
+// WriteComment writes a comment, which clients ignore. It keeps idle
+// connections open.
+func (s *SSEWriter) WriteComment(text string) error {
+	return s.write(":" + strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(text) + "\n")
+}
+
+// WriteError writes err as an error response in a message event. An
+// *APIError is written as is, other errors as a server error.
+func (s *SSEWriter) WriteError(err error) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = &APIError{Message: err.Error(), Type: "server_error"}
+	}
+	return s.WriteJSON(ErrorResponse{Error: apiErr})
+}
+
+// WriteDone writes the [DONE] event ending the stream.
+func (s *SSEWriter) WriteDone() error {
+	return s.WriteEvent(ServerSentEvent{Data: "[DONE]"})
+}
+
+func (s *SSEWriter) write(text string) error {
+	if _, err := io.WriteString(s.w, text); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// ServeSSEOption configures ServeSSE.
+type ServeSSEOption func(*serveSSEOptions)
+
+type serveSSEOptions struct {
+	heartbeat time.Duration
+	consume   []StreamConsumeOption
+}
+
+// WithHeartbeat sets the interval of the comments written while no event is
+// received, 15 seconds by default. Zero disables them.
+func WithHeartbeat(interval time.Duration) ServeSSEOption {
+	return func(o *serveSSEOptions) {
+		o.heartbeat = interval
+	}
+}
+
+// WithServeConsumeOptions sets the options used to receive the stream, as
+// for Each.
+func WithServeConsumeOptions(opts ...StreamConsumeOption) ServeSSEOption {
+	return func(o *serveSSEOptions) {
+		o.consume = append(o.consume, opts...)
+	}
+}
+
+// ServeSSE writes the events of the stream to w as an OpenAI-compatible
+// event stream ending with [DONE]. When the stream fails, the error is
+// written as an error response instead. The stream is closed when the
+// client of r goes away. ServeSSE returns the error that ended the stream,
+// nil after [DONE].
+func (stream *streamReader[T]) ServeSSE(w http.ResponseWriter, r *http.Request, opts ...ServeSSEOption) error {
+	options := serveSSEOptions{heartbeat: defaultHeartbeatInterval}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	chunks, errs := stream.Channel(ctx, options.consume...)
+	// The channel is drained so that the stream is closed before returning.
+	defer func() {
+		cancel()
+		for range chunks {
+		}
+	}()
+
+	writer := NewSSEWriter(w)
+	heartbeat, stopHeartbeat := timerChannel(options.heartbeat)
+	defer func() { stopHeartbeat() }()
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				err := <-errs
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if err != nil {
+					_ = writer.WriteError(err)
+					return err
+				}
+				return writer.WriteDone()
+			}
+			if err := writer.WriteJSON(chunk); err != nil {
+				return err
+			}
+		case <-heartbeat:
+			if err := writer.WriteComment(" ping"); err != nil {
+				return err
+			}
+		}
+		stopHeartbeat()
+		heartbeat, stopHeartbeat = timerChannel(options.heartbeat)
+	}
+}
@@ -0,0 +1,143 @@
+package giteeai_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// setupSSEProxy starts a server relaying the chat completion streams of the
+// client with ServeSSE. The errors returned by ServeSSE are sent on the
+// returned channel.
+func setupSSEProxy(upstream *giteeai.Client, opts ...giteeai.ServeSSEOption) (*httptest.Server, <-chan error) {
+	errs := make(chan error, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream, err := upstream.CreateChatCompletionStream(r.Context(), giteeai.ChatCompletionRequest{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		errs <- stream.ServeSSE(w, r, opts...)
+	}))
+	return proxy, errs
+}
+
+func TestServeSSE(t *testing.T) {
+	upstream, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(0, false, "a", "b\nc"))
+	proxy, serveErrs := setupSSEProxy(upstream)
+	defer proxy.Close()
+
+	config := giteeai.DefaultConfig(test.GetTestToken())
+	config.BaseURL = proxy.URL + "/v1"
+	stream, err := giteeai.NewClientWithConfig(config).CreateChatCompletionStream(context.Background(),
+		giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+	if stream.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected headers %v", stream.Header())
+	}
+
+	response, err := stream.Accumulate()
+	checks.NoError(t, err, "Accumulate error")
+	if content := response.Choices[0].Message.Content; content != "ab\nc" {
+		t.Errorf("unexpected content %q", content)
+	}
+	checks.NoError(t, <-serveErrs, "ServeSSE error")
+}
+
+func TestServeSSEError(t *testing.T) {
+	upstream, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"a\"}}]}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"message\":\"overloaded\",\"type\":\"server_error\"}\n\n")
+	})
+	proxy, serveErrs := setupSSEProxy(upstream)
+	defer proxy.Close()
+
+	config := giteeai.DefaultConfig(test.GetTestToken())
+	config.BaseURL = proxy.URL + "/v1"
+	stream, err := giteeai.NewClientWithConfig(config).CreateChatCompletionStream(context.Background(),
+		giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	defer stream.Close()
+
+	_, err = stream.Recv()
+	checks.NoError(t, err, "Recv error")
+	_, err = stream.Recv()
+	var apiErr *giteeai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "overloaded" || apiErr.Type != "server_error" {
+		t.Errorf("expected the upstream error, got %v", err)
+	}
+	if err = <-serveErrs; !errors.As(err, &apiErr) {
+		t.Errorf("ServeSSE should return the upstream error, got %v", err)
+	}
+}
+
+func TestServeSSEHeartbeatAndDisconnect(t *testing.T) {
+	upstream, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	disconnected := make(chan struct{})
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		streamingHandler(0, true, "a")(w, r)
+		close(disconnected)
+	})
+	proxy, serveErrs := setupSSEProxy(upstream, giteeai.WithHeartbeat(10*time.Millisecond))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL)
+	checks.NoError(t, err, "GET error")
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 4 {
+		line, readErr := reader.ReadString('\n')
+		checks.NoError(t, readErr, "read error")
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	if !strings.HasPrefix(lines[0], "data: {") || lines[1] != "" || lines[2] != ": ping" || lines[3] != ": ping" {
+		t.Errorf("unexpected lines %q", lines)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the upstream stream was not closed when the client went away")
+	}
+	checks.ErrorIs(t, <-serveErrs, context.Canceled)
+}
+
+func TestSSEWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := giteeai.NewSSEWriter(recorder)
+	checks.NoError(t, writer.WriteEvent(giteeai.ServerSentEvent{Event: "usage", ID: "1", Data: "a\r\nb"}), "WriteEvent")
+	checks.NoError(t, writer.WriteComment("keep\nalive"), "WriteComment error")
+	checks.NoError(t, writer.WriteError(io.ErrUnexpectedEOF), "WriteError error")
+	checks.NoError(t, writer.WriteDone(), "WriteDone error")
+	checks.ErrorIs(t, writer.WriteEvent(giteeai.ServerSentEvent{ID: "1\n2"}), giteeai.ErrInvalidServerSentEvent)
+
+	want := "event: usage\nid: 1\ndata: a\ndata: b\n\n" +
+		":keep alive\n" +
+		"data: {\"error\":{\"message\":\"unexpected EOF\",\"type\":\"server_error\"}}\n\n" +
+		"data: [DONE]\n\n"
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("unexpected stream %q", got)
+	}
+	if !recorder.Flushed || recorder.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("unexpected response %+v", recorder)
+	}
+}
@@ -8,8 +8,22 @@ import (
 
 var (
 	ErrTooManyEmptyStreamMessages = errors.New("stream has sent too many empty messages")
+	ErrStreamClosed               = errors.New("stream closed before completion")
 )
 
+// StreamObserver is notified of the lifecycle of a streaming call. It is
+// registered by middleware through Call.ObserveStream.
+type StreamObserver interface {
+	// OnFirstByte is called when the first bytes of the body are received.
+	OnFirstByte()
+	// OnChunk is called for every event decoded by Recv with a
+	// *ChatCompletionStreamResponse or a *CompletionResponse.
+	OnChunk(chunk any)
+	// OnFinish is called once when the stream ends: with nil after [DONE],
+	// with ErrStreamClosed when it is closed early, or with the receive error.
+	OnFinish(err error)
+}
+
 type CompletionStream struct {
 	*streamReader[CompletionResponse]
 }
@@ -39,6 +53,7 @@ func (c *Client) CreateCompletionStream(
 		http.MethodPost,
 		c.fullURL(urlSuffix, withModel(request.Model)),
 		withBody(request),
+		withOperation(OperationCreateCompletionStream),
 	)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,252 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrSlowConsumer is returned to a subscriber using SlowConsumerError whose
+// buffer was full when an event was broadcast.
+var ErrSlowConsumer = errors.New("stream subscriber too slow")
+
+// errNoSubscribers stops a broadcast whose subscribers all left.
+var errNoSubscribers = errors.New("no stream subscribers left")
+
+// SlowConsumerPolicy tells what a broadcast does when the buffer of a
+// subscriber is full.
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerBlock waits for the subscriber, slowing down all the others.
+	SlowConsumerBlock SlowConsumerPolicy = iota
+	// SlowConsumerDrop drops the event for the subscriber.
+	SlowConsumerDrop
+	// SlowConsumerError ends the subscription with ErrSlowConsumer.
+	SlowConsumerError
+)
+
+const defaultSubscriberBufferSize = 16
+
+// SubscriberOption configures a subscriber of a StreamBroadcast.
+type SubscriberOption func(*subscriberOptions)
+
+type subscriberOptions struct {
+	bufferSize int
+	policy     SlowConsumerPolicy
+}
+
+// WithSubscriberBuffer sets the number of events buffered for the
+// subscriber, 16 by default.
+func WithSubscriberBuffer(n int) SubscriberOption {
+	return func(o *subscriberOptions) {
+		o.bufferSize = n
+	}
+}
+
+// WithSlowConsumerPolicy sets what happens when the buffer of the subscriber
+// is full, SlowConsumerBlock by default.
+func WithSlowConsumerPolicy(policy SlowConsumerPolicy) SubscriberOption {
+	return func(o *subscriberOptions) {
+		o.policy = policy
+	}
+}
+
+// StreamBroadcast reads a stream once and sends every event to each of its
+// subscribers. The stream is closed when it ends or when all the subscribers
+// are closed.
+type StreamBroadcast[T streamable] struct {
+	stream *streamReader[T]
+
+	mu          sync.Mutex
+	subscribers []*StreamSubscriber[T]
+	started     bool
+	// abandoned is set when the broadcast is stopped as no subscriber is
+	// left.
+	abandoned bool
+	finished  bool
+	err       error
+	cancel    context.CancelFunc
+}
+
+// StreamSubscriber receives the events of a StreamBroadcast.
+type StreamSubscriber[T streamable] struct {
+	// dropped is first to be aligned for the atomic operations.
+	dropped   int64
+	broadcast *StreamBroadcast[T]
+	policy    SlowConsumerPolicy
+	// chunks is closed by the broadcast after setting err.
+	chunks    chan T
+	err       error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Broadcast returns a StreamBroadcast of the stream. The stream must not be
+// received from elsewhere once the broadcast is started.
+func (stream *streamReader[T]) Broadcast() *StreamBroadcast[T] {
+	return &StreamBroadcast[T]{stream: stream}
+}
+
+// Subscribe adds a subscriber. Subscribers added after Start receive the
+// events broadcast from then on, provided that an event is not received
+// while there is no subscriber: the broadcast then stops and the stream is
+// closed. Subscribers added after the end receive its error, ErrStreamClosed
+// when the subscribers all left.
+func (b *StreamBroadcast[T]) Subscribe(opts ...SubscriberOption) *StreamSubscriber[T] {
+	options := subscriberOptions{bufferSize: defaultSubscriberBufferSize}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	s := &StreamSubscriber[T]{
+		broadcast: b,
+		policy:    options.policy,
+		chunks:    make(chan T, options.bufferSize),
+		done:      make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		s.err = b.err
+		close(s.chunks)
+		return s
+	}
+	b.subscribers = append(b.subscribers, s)
+	return s
+}
+
+// Start reads the stream in a goroutine until its end, ctx is done or all
+// the subscribers are closed. The options apply to the reading of the
+// stream, as for Each. Start must be called once.
+func (b *StreamBroadcast[T]) Start(ctx context.Context, opts ...StreamConsumeOption) {
+	ctx, cancel := context.WithCancel(ctx)
+	b.mu.Lock()
+	b.started = true
+	b.cancel = cancel
+	b.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := b.stream.Each(ctx, func(chunk T) error {
+			return b.send(ctx, chunk)
+		}, opts...)
+		if err == nil {
+			err = io.EOF
+		}
+		b.end(err)
+	}()
+}
+
+// send delivers the chunk to the subscribers according to their policy.
+func (b *StreamBroadcast[T]) send(ctx context.Context, chunk T) error {
+	b.mu.Lock()
+	subscribers := append([]*StreamSubscriber[T](nil), b.subscribers...)
+	b.mu.Unlock()
+
+	for _, s := range subscribers {
+		select {
+		case <-s.done:
+			continue
+		case s.chunks <- chunk:
+			continue
+		default:
+		}
+
+		switch s.policy {
+		case SlowConsumerDrop:
+			atomic.AddInt64(&s.dropped, 1)
+		case SlowConsumerError:
+			b.remove(s)
+			s.err = ErrSlowConsumer
+			close(s.chunks)
+		default:
+			select {
+			case s.chunks <- chunk:
+			case <-s.done:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subscribers) == 0 {
+		b.abandoned = true
+		return errNoSubscribers
+	}
+	return nil
+}
+
+// end ends the subscriptions left with err.
+func (b *StreamBroadcast[T]) end(err error) {
+	b.mu.Lock()
+	if b.abandoned {
+		err = ErrStreamClosed
+	}
+	subscribers := b.subscribers
+	b.subscribers = nil
+	b.finished = true
+	b.err = err
+	b.mu.Unlock()
+
+	for _, s := range subscribers {
+		s.err = err
+		close(s.chunks)
+	}
+}
+
+// remove removes the subscriber and stops the broadcast when it was the
+// last one.
+func (b *StreamBroadcast[T]) remove(s *StreamSubscriber[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, subscriber := range b.subscribers {
+		if subscriber == s {
+			b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+			if len(b.subscribers) == 0 && b.started {
+				b.abandoned = true
+				b.cancel()
+			}
+			return
+		}
+	}
+}
+
+// Recv returns the next event. After the last one it returns io.EOF at the
+// end of the stream, the error that ended the stream, or ErrSlowConsumer.
+// It returns ErrStreamClosed once the subscriber is closed.
+func (s *StreamSubscriber[T]) Recv() (response T, err error) {
+	select {
+	case <-s.done:
+		return response, ErrStreamClosed
+	default:
+	}
+	select {
+	case chunk, ok := <-s.chunks:
+		if !ok {
+			return response, s.err
+		}
+		return chunk, nil
+	case <-s.done:
+		return response, ErrStreamClosed
+	}
+}
+
+// Dropped returns the number of events dropped because the subscriber was
+// too slow.
+func (s *StreamSubscriber[T]) Dropped() int {
+	return int(atomic.LoadInt64(&s.dropped))
+}
+
+// Close ends the subscription. The stream is closed when all the
+// subscribers are closed.
+func (s *StreamSubscriber[T]) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.broadcast.remove(s)
+	})
+}
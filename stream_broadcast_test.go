@@ -0,0 +1,143 @@
+package giteeai_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// receiveAll returns the contents received by the subscriber and the error
+// ending the subscription.
+func receiveAll(subscriber *giteeai.StreamSubscriber[giteeai.ChatCompletionStreamResponse]) (string, error) {
+	var content string
+	for {
+		chunk, err := subscriber.Recv()
+		if err != nil {
+			return content, err
+		}
+		content += chunk.Choices[0].Delta.Content
+	}
+}
+
+func TestStreamBroadcastPolicies(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(0, false, "a", "b", "c", "d", "e"))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	broadcast := stream.Broadcast()
+	all := broadcast.Subscribe()
+	dropping := broadcast.Subscribe(giteeai.WithSubscriberBuffer(1),
+		giteeai.WithSlowConsumerPolicy(giteeai.SlowConsumerDrop))
+	failing := broadcast.Subscribe(giteeai.WithSubscriberBuffer(1),
+		giteeai.WithSlowConsumerPolicy(giteeai.SlowConsumerError))
+	broadcast.Start(context.Background())
+
+	content, err := receiveAll(all)
+	checks.ErrorIs(t, err, io.EOF)
+	if content != "abcde" {
+		t.Errorf("unexpected content %q", content)
+	}
+	content, err = receiveAll(dropping)
+	checks.ErrorIs(t, err, io.EOF)
+	if content != "a" || dropping.Dropped() != 4 {
+		t.Errorf("unexpected content %q with %d dropped events", content, dropping.Dropped())
+	}
+	content, err = receiveAll(failing)
+	checks.ErrorIs(t, err, giteeai.ErrSlowConsumer)
+	if content != "a" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	_, err = broadcast.Subscribe().Recv()
+	checks.ErrorIs(t, err, io.EOF, "late subscribers receive the end of the stream")
+}
+
+func TestStreamBroadcastBlocks(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(0, false, "a", "b", "c"))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	broadcast := stream.Broadcast()
+	subscribers := []*giteeai.StreamSubscriber[giteeai.ChatCompletionStreamResponse]{
+		broadcast.Subscribe(giteeai.WithSubscriberBuffer(0)),
+		broadcast.Subscribe(giteeai.WithSubscriberBuffer(0)),
+	}
+	broadcast.Start(context.Background())
+
+	contents := make([]string, len(subscribers))
+	var wg sync.WaitGroup
+	for i, subscriber := range subscribers {
+		wg.Add(1)
+		go func(i int, subscriber *giteeai.StreamSubscriber[giteeai.ChatCompletionStreamResponse]) {
+			defer wg.Done()
+			// The slow subscriber holds back the other one.
+			time.Sleep(time.Duration(i) * 20 * time.Millisecond)
+			contents[i], _ = receiveAll(subscriber)
+		}(i, subscriber)
+	}
+	wg.Wait()
+	for _, content := range contents {
+		if content != "abc" {
+			t.Errorf("unexpected content %q", content)
+		}
+	}
+}
+
+func TestStreamBroadcastSubscribeAfterStart(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(50*time.Millisecond, false, "a", "b"))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	broadcast := stream.Broadcast()
+	broadcast.Start(context.Background())
+	subscriber := broadcast.Subscribe()
+
+	content, err := receiveAll(subscriber)
+	checks.ErrorIs(t, err, io.EOF)
+	if content != "ab" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestStreamBroadcastClosesStream(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	disconnected := make(chan struct{})
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		streamingHandler(0, true, "a")(w, r)
+		close(disconnected)
+	})
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	broadcast := stream.Broadcast()
+	first, second := broadcast.Subscribe(), broadcast.Subscribe()
+	broadcast.Start(context.Background())
+
+	_, err = first.Recv()
+	checks.NoError(t, err, "Recv error")
+	first.Close()
+	_, err = first.Recv()
+	checks.ErrorIs(t, err, giteeai.ErrStreamClosed)
+	second.Close()
+
+	select {
+	case <-disconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the stream was not closed after the last subscriber left")
+	}
+	_, err = broadcast.Subscribe().Recv()
+	checks.ErrorIs(t, err, giteeai.ErrStreamClosed, "late subscribers receive ErrStreamClosed")
+}
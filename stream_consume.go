@@ -0,0 +1,171 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// StreamTimeoutError is returned when a stream is aborted because no event
+// was received for the idle timeout, or because it lasted longer than the
+// maximum duration.
+type StreamTimeoutError struct {
+	// Idle tells whether the idle timeout expired rather than the maximum
+	// duration.
+	Idle    bool
+	Timeout time.Duration
+}
+
+func (e *StreamTimeoutError) Error() string {
+	if e.Idle {
+		return fmt.Sprintf("stream idle for %s", e.Timeout)
+	}
+	return fmt.Sprintf("stream exceeded the maximum duration of %s", e.Timeout)
+}
+
+// StreamConsumeOption configures Each and Channel.
+type StreamConsumeOption func(*streamConsumeOptions)
+
+type streamConsumeOptions struct {
+	idleTimeout time.Duration
+	maxDuration time.Duration
+	bufferSize  int
+}
+
+// WithIdleTimeout aborts the stream when no event is received for timeout.
+// The time spent by the consumer on an event is not counted.
+func WithIdleTimeout(timeout time.Duration) StreamConsumeOption {
+	return func(o *streamConsumeOptions) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithMaxDuration aborts the stream when it is not finished after d.
+func WithMaxDuration(d time.Duration) StreamConsumeOption {
+	return func(o *streamConsumeOptions) {
+		o.maxDuration = d
+	}
+}
+
+// WithBufferSize sets the capacity of the channel returned by Channel,
+// unbuffered by default.
+func WithBufferSize(n int) StreamConsumeOption {
+	return func(o *streamConsumeOptions) {
+		o.bufferSize = n
+	}
+}
+
+type recvResult[T streamable] struct {
+	chunk T
+	err   error
+}
+
+// timerChannel returns the channel of a timer firing after d, and nil, which
+// blocks forever, when d is not positive.
+func timerChannel(d time.Duration) (<-chan time.Time, func()) {
+	if d <= 0 {
+		return nil, func() {}
+	}
+	timer := time.NewTimer(d)
+	return timer.C, func() { timer.Stop() }
+}
+
+// Each receives the events of the stream and calls fn with each of them
+// until the end of the stream. It returns nil at the end of the stream, the
+// error returned by fn, the receive error, ctx.Err() or a
+// *StreamTimeoutError. The stream is closed when Each returns.
+func (stream *streamReader[T]) Each(ctx context.Context, fn func(T) error, opts ...StreamConsumeOption) (err error) {
+	var options streamConsumeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	// Recv blocks on the body: it runs in a goroutine, stopped by closing the
+	// body when the consumption is aborted.
+	results := make(chan recvResult[T])
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			chunk, recvErr := stream.Recv()
+			select {
+			case results <- recvResult[T]{chunk, recvErr}:
+			case <-done:
+				return
+			}
+			if recvErr != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		if err != nil {
+			stream.finish(err)
+		}
+		close(done)
+		stream.Close()
+		wg.Wait()
+	}()
+
+	deadline, stopDeadline := timerChannel(options.maxDuration)
+	defer stopDeadline()
+	for {
+		idle, stopIdle := timerChannel(options.idleTimeout)
+		var result recvResult[T]
+		select {
+		case result = <-results:
+		case <-idle:
+			return &StreamTimeoutError{Idle: true, Timeout: options.idleTimeout}
+		case <-deadline:
+			stopIdle()
+			return &StreamTimeoutError{Timeout: options.maxDuration}
+		case <-ctx.Done():
+			stopIdle()
+			return ctx.Err()
+		}
+		stopIdle()
+
+		if errors.Is(result.err, io.EOF) {
+			return nil
+		}
+		if result.err != nil {
+			return result.err
+		}
+		if err = fn(result.chunk); err != nil {
+			return err
+		}
+	}
+}
+
+// Channel receives the events of the stream in a goroutine and sends them
+// on the returned channel, closed at the end of the stream. The error
+// channel then receives the error returned by Each, nil at the end of the
+// stream. The consumer must read the events until the channel is closed or
+// cancel ctx.
+func (stream *streamReader[T]) Channel(ctx context.Context, opts ...StreamConsumeOption) (<-chan T, <-chan error) {
+	var options streamConsumeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	chunks := make(chan T, options.bufferSize)
+	errs := make(chan error, 1)
+	go func() {
+		err := stream.Each(ctx, func(chunk T) error {
+			select {
+			case chunks <- chunk:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, opts...)
+		close(chunks)
+		errs <- err
+		close(errs)
+	}()
+	return chunks, errs
+}
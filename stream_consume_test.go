@@ -0,0 +1,149 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+// streamingHandler writes the chat completion chunks with the given
+// contents, waiting delay before each of them, then [DONE] unless stall is
+// set, in which case it waits for the client to go away.
+func streamingHandler(delay time.Duration, stall bool, contents ...string) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher, _ := w.(http.Flusher)
+		for _, content := range contents {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q},\"text\":%q}]}\n\n",
+				content, content)
+			flusher.Flush()
+		}
+		if stall {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}
+}
+
+func TestStreamEach(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(0, false, "a", "b", "c"))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	var content string
+	err = stream.Each(context.Background(), func(chunk giteeai.ChatCompletionStreamResponse) error {
+		content += chunk.Choices[0].Delta.Content
+		// The time spent on an event is not counted as idle.
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}, giteeai.WithIdleTimeout(20*time.Millisecond))
+	checks.NoError(t, err, "Each error")
+	if content != "abc" {
+		t.Errorf("unexpected content %q", content)
+	}
+}
+
+func TestStreamEachStops(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(0, true, "a", "b"))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	errStop := errors.New("stop")
+	err = stream.Each(context.Background(), func(giteeai.ChatCompletionStreamResponse) error {
+		return errStop
+	})
+	checks.ErrorIs(t, err, errStop)
+
+	stream, err = client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = stream.Each(ctx, func(giteeai.ChatCompletionStreamResponse) error { return nil })
+	checks.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/chat/completions", streamingHandler(0, true, "a"))
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+	received := 0
+	start := time.Now()
+	err = stream.Each(context.Background(), func(giteeai.ChatCompletionStreamResponse) error {
+		received++
+		return nil
+	}, giteeai.WithIdleTimeout(50*time.Millisecond), giteeai.WithMaxDuration(5*time.Second))
+
+	var timeoutErr *giteeai.StreamTimeoutError
+	if !errors.As(err, &timeoutErr) || !timeoutErr.Idle || timeoutErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("expected an idle timeout, got %v", err)
+	}
+	if received != 1 || time.Since(start) > 2*time.Second {
+		t.Errorf("unexpected consumption: %d events in %s", received, time.Since(start))
+	}
+	if _, err = stream.Recv(); err == nil {
+		t.Error("expected the stream to be closed")
+	}
+}
+
+func TestStreamMaxDuration(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/completions", streamingHandler(20*time.Millisecond, false,
+		"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p"))
+
+	stream, err := client.CreateCompletionStream(context.Background(), giteeai.CompletionRequest{Prompt: "x"})
+	checks.NoError(t, err, "CreateCompletionStream error")
+	chunks, errs := stream.Channel(context.Background(),
+		giteeai.WithIdleTimeout(time.Second), giteeai.WithMaxDuration(100*time.Millisecond), giteeai.WithBufferSize(4))
+	received := 0
+	for range chunks {
+		received++
+	}
+	var timeoutErr *giteeai.StreamTimeoutError
+	if err = <-errs; !errors.As(err, &timeoutErr) || timeoutErr.Idle {
+		t.Fatalf("expected the maximum duration to be exceeded, got %v", err)
+	}
+	if received == 0 || received >= 16 {
+		t.Errorf("unexpected number of events %d", received)
+	}
+}
+
+func TestStreamChannel(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+	server.RegisterHandler("/v1/completions", streamingHandler(0, false, "a", "b"))
+
+	stream, err := client.CreateCompletionStream(context.Background(), giteeai.CompletionRequest{Prompt: "x"})
+	checks.NoError(t, err, "CreateCompletionStream error")
+	chunks, errs := stream.Channel(context.Background())
+	var text string
+	for chunk := range chunks {
+		text += chunk.Choices[0].Text
+	}
+	checks.NoError(t, <-errs, "Channel error")
+	if text != "ab" {
+		t.Errorf("unexpected text %q", text)
+	}
+}
@@ -6,16 +6,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	utils "github.com/edmondfrank/go-giteeai/internal"
 )
 
-var (
-	headerData  = regexp.MustCompile(`^data:\s*`)
-	errorPrefix = regexp.MustCompile(`^data:\s*{"error":`)
-)
-
 type streamable interface {
 	ChatCompletionStreamResponse | CompletionResponse
 }
@@ -23,8 +20,20 @@ type streamable interface {
 type streamReader[T streamable] struct {
 	emptyMessagesLimit uint
 	isFinished         bool
+	hasFirstByte       bool
+	finishOnce         sync.Once
+	observers          []StreamObserver
+
+	// reconnect, if set, is called when the stream is interrupted. It returns
+	// a stream continuing it, or nil when it cannot be resumed.
+	reconnect func(err error) (*streamReader[T], error)
+	// mu guards response and closed, as Close may be called while the
+	// stream is being resumed.
+	mu     sync.Mutex
+	closed bool
 
 	reader         *bufio.Reader
+	decoder        *sseDecoder
 	response       *http.Response
 	errAccumulator utils.ErrorAccumulator
 	unmarshaler    utils.Unmarshaler
@@ -40,8 +49,12 @@ func (stream *streamReader[T]) Recv() (response T, err error) {
 
 	err = stream.unmarshaler.Unmarshal(rawLine, &response)
 	if err != nil {
+		stream.finish(err)
 		return
 	}
+	for _, o := range stream.observers {
+		o.OnChunk(&response)
+	}
 	return response, nil
 }
 
@@ -50,54 +63,178 @@ func (stream *streamReader[T]) RecvRaw() ([]byte, error) {
 		return nil, io.EOF
 	}
 
-	return stream.processLines()
+	line, err := stream.processLines()
+	for err != nil && stream.canResume() {
+		if err = stream.resume(err); err == nil {
+			line, err = stream.processLines()
+		}
+	}
+	if err != nil {
+		if stream.isFinished {
+			stream.finish(nil)
+		} else {
+			stream.finish(err)
+		}
+	}
+	return line, err
+}
+
+// finish notifies the observers that the stream ended, at most once. It may
+// be called concurrently with Recv when the stream is aborted.
+func (stream *streamReader[T]) finish(err error) {
+	stream.finishOnce.Do(func() {
+		stream.mu.Lock()
+		observers := stream.observers
+		stream.mu.Unlock()
+		for _, o := range observers {
+			o.OnFinish(err)
+		}
+	})
+}
+
+// RecvEvent returns the next event of the stream, whatever its type, without
+// decoding its data. Like RecvRaw, it returns io.EOF after the [DONE] event
+// and an error for the events reporting an error.
+func (stream *streamReader[T]) RecvEvent() (ServerSentEvent, error) {
+	if stream.isFinished {
+		return ServerSentEvent{}, io.EOF
+	}
+
+	event, err := stream.processEvent()
+	for err != nil && stream.canResume() {
+		if err = stream.resume(err); err == nil {
+			event, err = stream.processEvent()
+		}
+	}
+	if err != nil {
+		if stream.isFinished {
+			stream.finish(nil)
+		} else {
+			stream.finish(err)
+		}
+	}
+	return event, err
 }
 
-//nolint:gocognit
+// LastEventID returns the last event ID set by the server, which is sent in
+// the Last-Event-ID header to resume the stream.
+func (stream *streamReader[T]) LastEventID() string {
+	if stream.decoder == nil {
+		return ""
+	}
+	return stream.decoder.lastEventID
+}
+
+// RetryInterval returns the reconnection time set by the server, 0 when it
+// did not set it.
+func (stream *streamReader[T]) RetryInterval() time.Duration {
+	if stream.decoder == nil {
+		return 0
+	}
+	return stream.decoder.retry
+}
+
+// processLines returns the data of the next message event, skipping the
+// events of the other types.
 func (stream *streamReader[T]) processLines() ([]byte, error) {
+	for {
+		event, err := stream.processEvent()
+		if err != nil {
+			return nil, err
+		}
+		if event.Event == sseMessageEvent {
+			return []byte(event.Data), nil
+		}
+	}
+}
+
+// processEvent returns the next event, turning the [DONE] event into io.EOF
+// and the errors reported by the server into errors.
+func (stream *streamReader[T]) processEvent() (ServerSentEvent, error) {
+	if stream.decoder == nil {
+		stream.decoder = newSSEDecoder(stream.reader)
+	}
+	if !stream.hasFirstByte {
+		if _, err := stream.reader.Peek(1); err == nil {
+			stream.hasFirstByte = true
+			for _, o := range stream.observers {
+				o.OnFirstByte()
+			}
+		}
+	}
+
+	// The lines that are not part of an event are accumulated, as they may
+	// be an error response that is not an event stream.
 	var (
 		emptyMessagesCount uint
+		ignoreErr          error
 		hasErrorPrefix     bool
 	)
-
-	for {
-		rawLine, readErr := stream.reader.ReadBytes('\n')
-		if readErr != nil || hasErrorPrefix {
-			respErr := stream.unmarshalError()
-			if respErr != nil {
-				return nil, fmt.Errorf("error, %w", respErr.Error)
-			}
-			return nil, readErr
+	stream.decoder.ignored = func(line []byte) error {
+		if ignoreErr = stream.errAccumulator.Write(bytes.TrimSpace(line)); ignoreErr != nil {
+			return ignoreErr
+		}
+		emptyMessagesCount++
+		if emptyMessagesCount > stream.emptyMessagesLimit {
+			ignoreErr = ErrTooManyEmptyStreamMessages
 		}
+		return ignoreErr
+	}
 
-		noSpaceLine := bytes.TrimSpace(rawLine)
-		if errorPrefix.Match(noSpaceLine) {
-			hasErrorPrefix = true
+	for {
+		event, readErr := stream.decoder.Next()
+		if ignoreErr != nil {
+			return ServerSentEvent{}, ignoreErr
 		}
-		if !headerData.Match(noSpaceLine) || hasErrorPrefix {
-			if hasErrorPrefix {
-				noSpaceLine = headerData.ReplaceAll(noSpaceLine, nil)
-			}
-			writeErr := stream.errAccumulator.Write(noSpaceLine)
-			if writeErr != nil {
-				return nil, writeErr
+		if readErr != nil {
+			if respErr := stream.unmarshalError(); respErr != nil {
+				return ServerSentEvent{}, fmt.Errorf("error, %w", respErr.Error)
 			}
-			emptyMessagesCount++
-			if emptyMessagesCount > stream.emptyMessagesLimit {
-				return nil, ErrTooManyEmptyStreamMessages
+			return ServerSentEvent{}, readErr
+		}
+		if hasErrorPrefix {
+			// The error is split across several events.
+			if err := stream.errAccumulator.Write([]byte(event.Data)); err != nil {
+				return ServerSentEvent{}, err
 			}
-
 			continue
 		}
 
-		noPrefixLine := headerData.ReplaceAll(noSpaceLine, nil)
-		if string(noPrefixLine) == "[DONE]" {
+		data := strings.TrimSpace(event.Data)
+		switch {
+		case event.Event == sseErrorEvent:
+			return event, stream.eventError(data)
+		case event.Event != sseMessageEvent:
+			return event, nil
+		case data == "[DONE]":
 			stream.isFinished = true
-			return nil, io.EOF
+			return event, io.EOF
+		case strings.HasPrefix(data, `{"error":`):
+			if err := stream.errAccumulator.Write([]byte(data)); err != nil {
+				return ServerSentEvent{}, err
+			}
+			if respErr := stream.unmarshalError(); respErr != nil {
+				return event, fmt.Errorf("error, %w", respErr.Error)
+			}
+			hasErrorPrefix = true
+			continue
 		}
+		return event, nil
+	}
+}
 
-		return noPrefixLine, nil
+// eventError returns the error reported by an error event, whose data is an
+// error response, an error or a message.
+func (stream *streamReader[T]) eventError(data string) error {
+	var errResp ErrorResponse
+	if err := stream.unmarshaler.Unmarshal([]byte(data), &errResp); err == nil && errResp.Error != nil {
+		return fmt.Errorf("error, %w", errResp.Error)
 	}
+	apiErr := &APIError{}
+	if err := stream.unmarshaler.Unmarshal([]byte(data), apiErr); err == nil && apiErr.Message != "" {
+		return fmt.Errorf("error, %w", apiErr)
+	}
+	return fmt.Errorf("error, %w", &APIError{Message: data})
 }
 
 func (stream *streamReader[T]) unmarshalError() (errResp *ErrorResponse) {
@@ -115,5 +252,58 @@ func (stream *streamReader[T]) unmarshalError() (errResp *ErrorResponse) {
 }
 
 func (stream *streamReader[T]) Close() error {
-	return stream.response.Body.Close()
+	stream.finish(ErrStreamClosed)
+	stream.mu.Lock()
+	stream.closed = true
+	body := stream.response.Body
+	stream.mu.Unlock()
+	return body.Close()
+}
+
+func (stream *streamReader[T]) canResume() bool {
+	return stream.reconnect != nil && !stream.isFinished
+}
+
+// resume replaces the body of the interrupted stream with the one of the
+// stream returned by reconnect. It returns err when the stream cannot be
+// resumed, and the error of the reconnection when it failed.
+func (stream *streamReader[T]) resume(err error) error {
+	next, reconnectErr := stream.reconnect(err)
+	if reconnectErr != nil {
+		stream.reconnect = nil
+		return reconnectErr
+	}
+	if next == nil {
+		stream.reconnect = nil
+		return err
+	}
+
+	stream.mu.Lock()
+	if stream.closed {
+		stream.mu.Unlock()
+		next.response.Body.Close()
+		return ErrStreamClosed
+	}
+	previous := stream.response
+	stream.response = next.response
+	// The observers registered by the middleware for the new request are
+	// notified of the rest of the stream.
+	stream.observers = append(stream.observers, next.observers...)
+	stream.mu.Unlock()
+	previous.Body.Close()
+
+	// The last event ID and the reconnection time are kept across
+	// reconnections.
+	decoder := newSSEDecoder(next.reader)
+	if stream.decoder != nil {
+		decoder.lastEventID, decoder.retry = stream.decoder.lastEventID, stream.decoder.retry
+	}
+	stream.reader = next.reader
+	stream.decoder = decoder
+	stream.errAccumulator = next.errAccumulator
+	stream.httpHeader = next.httpHeader
+	for _, o := range next.observers {
+		o.OnFirstByte()
+	}
+	return nil
 }
@@ -0,0 +1,179 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+const defaultStructuredOutputName = "response"
+
+// ErrStructuredOutputRefused is returned when the model refuses to answer
+// a structured output request.
+var ErrStructuredOutputRefused = errors.New("model refused to produce structured output")
+
+var schemaNameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// StructuredOutputError is returned when the reply of the model still does
+// not conform to the schema after every repair attempt.
+type StructuredOutputError struct {
+	// Content is the last reply of the model.
+	Content string
+	// Repairs is the number of times the model was asked to fix its reply.
+	Repairs int
+	Err     error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("structured output does not match the schema after %d repairs: %v", e.Repairs, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return e.Err
+}
+
+// StructuredOutputOption configures CreateChatCompletionTyped.
+type StructuredOutputOption func(*structuredOutputOptions)
+
+type structuredOutputOptions struct {
+	name        string
+	description string
+	maxRepairs  int
+	nonStrict   bool
+}
+
+// WithSchemaName sets the name and description of the JSON schema sent to
+// the model. The name defaults to the name of the Go type.
+func WithSchemaName(name, description string) StructuredOutputOption {
+	return func(o *structuredOutputOptions) {
+		o.name = name
+		o.description = description
+	}
+}
+
+// WithMaxRepairs allows re-prompting the model with the validation error up
+// to n times when its reply does not conform to the schema.
+func WithMaxRepairs(n int) StructuredOutputOption {
+	return func(o *structuredOutputOptions) {
+		o.maxRepairs = n
+	}
+}
+
+// WithNonStrictSchema sends the schema without strict mode, for types that
+// jsonschema.Strict cannot rewrite, such as those with map fields.
+func WithNonStrictSchema() StructuredOutputOption {
+	return func(o *structuredOutputOptions) {
+		o.nonStrict = true
+	}
+}
+
+// TypedChatCompletionResponse is the result of CreateChatCompletionTyped.
+type TypedChatCompletionResponse[T any] struct {
+	// Value is the reply of the model decoded into T.
+	Value T
+	// Response is the last completion returned by the model.
+	Response ChatCompletionResponse
+	// Repairs is the number of times the model was asked to fix its reply.
+	Repairs int
+}
+
+// CreateChatCompletionTyped requests a chat completion whose reply is a JSON
+// document of type T. The JSON schema is generated from T and sent in strict
+// mode, and the reply is validated and decoded with
+// jsonschema.VerifySchemaAndUnmarshal. The error of jsonschema.Strict is
+// returned when T cannot be made strict, unless WithNonStrictSchema is set.
+func CreateChatCompletionTyped[T any](
+	ctx context.Context,
+	client *Client,
+	request ChatCompletionRequest,
+	opts ...StructuredOutputOption,
+) (result TypedChatCompletionResponse[T], err error) {
+	options := structuredOutputOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.name == "" {
+		options.name = structuredOutputName(reflect.TypeOf(result.Value))
+	}
+
+	schema, err := jsonschema.GenerateSchemaForType(result.Value)
+	if err != nil {
+		return result, err
+	}
+	if !options.nonStrict {
+		strict, strictErr := jsonschema.Strict(*schema)
+		if strictErr != nil {
+			return result, strictErr
+		}
+		schema = &strict
+	}
+	request.ResponseFormat = &ChatCompletionResponseFormat{
+		Type: ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &ChatCompletionResponseFormatJSONSchema{
+			Name:        options.name,
+			Description: options.description,
+			Schema:      schema,
+			Strict:      !options.nonStrict,
+		},
+	}
+	request.Messages = append([]ChatCompletionMessage(nil), request.Messages...)
+
+	for {
+		result.Response, err = client.CreateChatCompletion(ctx, request)
+		if err != nil {
+			return result, err
+		}
+		if len(result.Response.Choices) == 0 {
+			return result, &StructuredOutputError{Repairs: result.Repairs, Err: errors.New("no choices returned")}
+		}
+		message := result.Response.Choices[0].Message
+		if message.Refusal != "" {
+			return result, fmt.Errorf("%w: %s", ErrStructuredOutputRefused, message.Refusal)
+		}
+
+		content := trimCodeFence(message.Content)
+		err = jsonschema.VerifySchemaAndUnmarshal(*schema, []byte(content), &result.Value)
+		if err == nil {
+			return result, nil
+		}
+		if result.Repairs >= options.maxRepairs {
+			return result, &StructuredOutputError{Content: message.Content, Repairs: result.Repairs, Err: err}
+		}
+
+		result.Repairs++
+		request.Messages = append(request.Messages, message, ChatCompletionMessage{
+			Role: ChatMessageRoleUser,
+			Content: fmt.Sprintf("Your previous reply is invalid: %v. "+
+				"Reply again with only a JSON document conforming to the %q schema.", err, options.name),
+		})
+	}
+}
+
+// structuredOutputName derives a valid schema name from a Go type.
+func structuredOutputName(t reflect.Type) string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Name() == "" {
+		return defaultStructuredOutputName
+	}
+	return schemaNameInvalidChars.ReplaceAllString(t.Name(), "_")
+}
+
+// trimCodeFence removes the markdown code fence some models wrap JSON in.
+func trimCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") || !strings.HasSuffix(content, "```") || len(content) < 6 {
+		return content
+	}
+	content = strings.TrimSuffix(strings.TrimPrefix(content, "```"), "```")
+	if i := strings.IndexByte(content, '\n'); i >= 0 && !strings.ContainsAny(content[:i], "{[") {
+		content = content[i+1:]
+	}
+	return strings.TrimSpace(content)
+}
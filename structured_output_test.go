@@ -0,0 +1,132 @@
+package giteeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+type cityInfo struct {
+	Name       string `json:"name"`
+	Population int    `json:"population"`
+}
+
+// replyingHandler answers the successive chat completion requests with the
+// given contents and records the requests.
+func replyingHandler(
+	requests *[]giteeai.ChatCompletionRequest,
+	replies ...giteeai.ChatCompletionMessage,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request giteeai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		*requests = append(*requests, request)
+		reply := replies[len(*requests)-1]
+		reply.Role = giteeai.ChatMessageRoleAssistant
+		_ = json.NewEncoder(w).Encode(giteeai.ChatCompletionResponse{
+			Choices: []giteeai.ChatCompletionChoice{{Message: reply}},
+		})
+	}
+}
+
+func TestCreateChatCompletionTyped(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: "```json\n{\"name\":\"Paris\",\"population\":2100000}\n```"}))
+
+	result, err := giteeai.CreateChatCompletionTyped[cityInfo](context.Background(), client,
+		giteeai.ChatCompletionRequest{Model: giteeai.Qwen2_7B_Instruct})
+	checks.NoError(t, err, "CreateChatCompletionTyped error")
+	if result.Value != (cityInfo{Name: "Paris", Population: 2100000}) || result.Repairs != 0 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	format := requests[0].ResponseFormat
+	if format == nil || format.Type != giteeai.ChatCompletionResponseFormatTypeJSONSchema ||
+		format.JSONSchema.Name != "cityInfo" || !format.JSONSchema.Strict {
+		t.Fatalf("unexpected response format %+v", format)
+	}
+}
+
+func TestCreateChatCompletionTypedRepairs(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: `{"name":"Paris"}`},
+		giteeai.ChatCompletionMessage{Content: `{"name":"Paris","population":2100000}`}))
+
+	result, err := giteeai.CreateChatCompletionTyped[cityInfo](context.Background(), client,
+		giteeai.ChatCompletionRequest{Messages: []giteeai.ChatCompletionMessage{
+			{Role: giteeai.ChatMessageRoleUser, Content: "Describe Paris"},
+		}},
+		giteeai.WithMaxRepairs(2), giteeai.WithSchemaName("city", "A city"))
+	checks.NoError(t, err, "CreateChatCompletionTyped error")
+	if result.Repairs != 1 || result.Value.Population != 2100000 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	repair := requests[1].Messages
+	if len(repair) != 3 || repair[1].Content != `{"name":"Paris"}` ||
+		repair[2].Role != giteeai.ChatMessageRoleUser || !strings.Contains(repair[2].Content, `"city"`) {
+		t.Fatalf("unexpected repair messages %+v", repair)
+	}
+}
+
+func TestCreateChatCompletionTypedInvalid(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: `not json`},
+		giteeai.ChatCompletionMessage{Refusal: "I can't help with that"}))
+
+	_, err := giteeai.CreateChatCompletionTyped[cityInfo](context.Background(), client,
+		giteeai.ChatCompletionRequest{})
+	outputErr := &giteeai.StructuredOutputError{}
+	if !errors.As(err, &outputErr) || outputErr.Content != "not json" || outputErr.Repairs != 0 {
+		t.Fatalf("expected a StructuredOutputError, got %v", err)
+	}
+
+	_, err = giteeai.CreateChatCompletionTyped[cityInfo](context.Background(), client,
+		giteeai.ChatCompletionRequest{})
+	checks.ErrorIs(t, err, giteeai.ErrStructuredOutputRefused, "expected a refusal error")
+}
+
+func TestCreateChatCompletionTypedNotStrict(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests []giteeai.ChatCompletionRequest
+	server.RegisterHandler("/v1/chat/completions", replyingHandler(&requests,
+		giteeai.ChatCompletionMessage{Content: `{"scores":{"a":1}}`}))
+
+	type scores struct {
+		Scores map[string]int `json:"scores"`
+	}
+	_, err := giteeai.CreateChatCompletionTyped[scores](context.Background(), client,
+		giteeai.ChatCompletionRequest{})
+	var strictErrs jsonschema.StrictErrors
+	if !errors.As(err, &strictErrs) || len(requests) != 0 {
+		t.Fatalf("expected StrictErrors before any request, got %v", err)
+	}
+
+	result, err := giteeai.CreateChatCompletionTyped[scores](context.Background(), client,
+		giteeai.ChatCompletionRequest{}, giteeai.WithNonStrictSchema())
+	checks.NoError(t, err, "CreateChatCompletionTyped error")
+	if result.Value.Scores["a"] != 1 || requests[0].ResponseFormat.JSONSchema.Strict {
+		t.Fatalf("unexpected result %+v for %+v", result, requests[0].ResponseFormat.JSONSchema)
+	}
+}
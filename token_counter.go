@@ -0,0 +1,164 @@
+package giteeai
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ChatFormat describes the tokens a chat template adds around the messages
+// and tools of a request. The counts are those of the template text, so
+// counting with a format is exact only as far as the template is.
+type ChatFormat struct {
+	// TokensPerMessage are added around every message, e.g. by the
+	// "<|im_start|>" and "<|im_end|>" markers.
+	TokensPerMessage int
+	// CountRole counts the tokens of the role of every message.
+	CountRole bool
+	// TokensPerName are added for every message with a name.
+	TokensPerName int
+	// TokensPerRequest are added once, e.g. for the assistant reply prefix.
+	TokensPerRequest int
+	// ToolsPrompt is the text introducing the tools, counted once when the
+	// request has tools.
+	ToolsPrompt string
+	// TokensPerTool are added for every tool, besides its JSON definition.
+	TokensPerTool int
+	// TokensPerToolCall are added for every tool call of the assistant,
+	// besides its name and arguments.
+	TokensPerToolCall int
+}
+
+var (
+	// ChatFormatChatML is the format of the Qwen models:
+	// "<|im_start|>role\ncontent<|im_end|>\n".
+	ChatFormatChatML = ChatFormat{
+		TokensPerMessage: 4,
+		CountRole:        true,
+		TokensPerRequest: 3,
+		ToolsPrompt: "\n\n# Tools\n\nYou may call one or more functions to assist with the user query.\n\n" +
+			"You are provided with function signatures within <tools></tools> XML tags:\n<tools>\n</tools>\n\n" +
+			"For each function call, return a json object with function name and arguments within " +
+			"<tool_call></tool_call> XML tags:\n<tool_call>\n" +
+			"{\"name\": <function-name>, \"arguments\": <args-json-object>}\n</tool_call>",
+		TokensPerTool:     1,
+		TokensPerToolCall: 4,
+	}
+	// ChatFormatDeepSeek is the format of the DeepSeek models:
+	// "<｜begin▁of▁sentence｜>system\n\nUser: content\n\nAssistant: content<｜end▁of▁sentence｜>".
+	ChatFormatDeepSeek = ChatFormat{
+		TokensPerMessage:  3,
+		CountRole:         true,
+		TokensPerRequest:  3,
+		TokensPerTool:     1,
+		TokensPerToolCall: 3,
+	}
+	// ChatFormatDefault is used for the other models.
+	ChatFormatDefault = ChatFormat{
+		TokensPerMessage:  3,
+		CountRole:         true,
+		TokensPerName:     1,
+		TokensPerRequest:  3,
+		TokensPerTool:     3,
+		TokensPerToolCall: 3,
+	}
+)
+
+// ChatFormatForModel returns the chat format of a model.
+func ChatFormatForModel(model string) ChatFormat {
+	model = strings.ToLower(model)
+	switch {
+	case strings.HasPrefix(model, "qwen"):
+		return ChatFormatChatML
+	case strings.HasPrefix(model, "deepseek"):
+		return ChatFormatDeepSeek
+	default:
+		return ChatFormatDefault
+	}
+}
+
+// TokenCounter estimates the number of prompt tokens of chat requests, to
+// budget MaxTokens or trim the history before sending a request.
+type TokenCounter struct {
+	Tokenizer Tokenizer
+	Format    ChatFormat
+}
+
+// NewTokenCounter creates a TokenCounter with the chat format of model. A nil
+// tokenizer is replaced by ApproximateTokenizer.
+func NewTokenCounter(model string, tokenizer Tokenizer) *TokenCounter {
+	if tokenizer == nil {
+		tokenizer = ApproximateTokenizer
+	}
+	return &TokenCounter{Tokenizer: tokenizer, Format: ChatFormatForModel(model)}
+}
+
+// CountMessage returns the number of tokens of a message, including the
+// tokens the chat format adds around it. Only the text parts of
+// MultiContent are counted.
+func (c *TokenCounter) CountMessage(message ChatCompletionMessage) int {
+	tokens := c.Format.TokensPerMessage
+	if c.Format.CountRole {
+		tokens += c.Tokenizer.CountTokens(message.Role)
+	}
+	if message.Name != "" {
+		tokens += c.Format.TokensPerName + c.Tokenizer.CountTokens(message.Name)
+	}
+	tokens += c.Tokenizer.CountTokens(message.Content)
+	for _, part := range message.MultiContent {
+		if part.Type == ChatMessagePartTypeText {
+			tokens += c.Tokenizer.CountTokens(part.Text)
+		}
+	}
+	if message.FunctionCall != nil {
+		tokens += c.countCall(*message.FunctionCall)
+	}
+	for _, call := range message.ToolCalls {
+		tokens += c.countCall(call.Function)
+	}
+	return tokens
+}
+
+func (c *TokenCounter) countCall(call FunctionCall) int {
+	return c.Format.TokensPerToolCall + c.Tokenizer.CountTokens(call.Name) + c.Tokenizer.CountTokens(call.Arguments)
+}
+
+// CountMessages returns the number of tokens of messages, without the
+// tokens added once per request.
+func (c *TokenCounter) CountMessages(messages []ChatCompletionMessage) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += c.CountMessage(message)
+	}
+	return tokens
+}
+
+// CountTools returns the number of tokens of the tool definitions, which
+// chat templates render as JSON.
+func (c *TokenCounter) CountTools(tools []Tool) int {
+	if len(tools) == 0 {
+		return 0
+	}
+	tokens := c.Tokenizer.CountTokens(c.Format.ToolsPrompt)
+	for _, tool := range tools {
+		definition, err := json.Marshal(tool)
+		if err != nil {
+			continue
+		}
+		tokens += c.Format.TokensPerTool + c.Tokenizer.CountTokens(string(definition))
+	}
+	return tokens
+}
+
+// CountRequest returns the number of prompt tokens of a request: its
+// messages, its tools and the tokens added once per request.
+func (c *TokenCounter) CountRequest(request ChatCompletionRequest) int {
+	tools := request.Tools
+	if len(request.Functions) > 0 {
+		tools = append([]Tool(nil), request.Tools...)
+	}
+	for _, function := range request.Functions {
+		function := function
+		tools = append(tools, Tool{Type: ToolTypeFunction, Function: &function})
+	}
+	return c.Format.TokensPerRequest + c.CountMessages(request.Messages) + c.CountTools(tools)
+}
@@ -0,0 +1,77 @@
+package giteeai_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+// wordTokenizer counts one token per word.
+var wordTokenizer = giteeai.TokenizerFunc(func(text string) int {
+	return len(strings.Fields(text))
+})
+
+func TestTokenCounterMessages(t *testing.T) {
+	counter := &giteeai.TokenCounter{Tokenizer: wordTokenizer, Format: giteeai.ChatFormatDefault}
+
+	messages := []giteeai.ChatCompletionMessage{
+		{Role: giteeai.ChatMessageRoleSystem, Content: "be brief"},
+		{Role: giteeai.ChatMessageRoleUser, Name: "ann", MultiContent: []giteeai.ChatMessagePart{
+			{Type: giteeai.ChatMessagePartTypeText, Text: "what is this"},
+			{Type: giteeai.ChatMessagePartTypeImageURL, ImageURL: &giteeai.ChatMessageImageURL{URL: "https://x/a b.png"}},
+		}},
+		{Role: giteeai.ChatMessageRoleAssistant, ToolCalls: []giteeai.ToolCall{
+			{ID: "1", Function: giteeai.FunctionCall{Name: "lookup", Arguments: `{"q": "a b"}`}},
+		}},
+	}
+	// role + content + 3 per message, 1 + name per name, 3 + name + arguments per tool call.
+	want := (1 + 2 + 3) + (1 + 3 + 3 + 1 + 1) + (1 + 3 + 3 + 1 + 3)
+	if got := counter.CountMessages(messages); got != want {
+		t.Errorf("CountMessages() = %d, want %d", got, want)
+	}
+}
+
+func TestTokenCounterRequest(t *testing.T) {
+	counter := giteeai.NewTokenCounter(giteeai.Qwen2_7B_Instruct, wordTokenizer)
+	if counter.Format.ToolsPrompt != giteeai.ChatFormatChatML.ToolsPrompt {
+		t.Fatal("expected the ChatML format for Qwen2")
+	}
+
+	request := giteeai.ChatCompletionRequest{
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "hi there"}},
+	}
+	withoutTools := counter.CountRequest(request)
+	if want := 3 + (4 + 1 + 2); withoutTools != want {
+		t.Errorf("CountRequest() = %d, want %d", withoutTools, want)
+	}
+
+	tool := giteeai.Tool{Type: giteeai.ToolTypeFunction, Function: &giteeai.FunctionDefinition{
+		Name:        "weather",
+		Description: "Get the weather of a city",
+		Parameters:  jsonschema.Definition{Type: jsonschema.Object},
+	}}
+	request.Tools = []giteeai.Tool{tool}
+	withTools := counter.CountRequest(request)
+	if withTools-withoutTools != counter.CountTools(request.Tools) || counter.CountTools(request.Tools) <= 1 {
+		t.Errorf("unexpected token count with tools %d", withTools)
+	}
+
+	request.Tools = nil
+	request.Functions = []giteeai.FunctionDefinition{*tool.Function}
+	if got := counter.CountRequest(request); got != withTools {
+		t.Errorf("expected functions to be counted as tools, got %d instead of %d", got, withTools)
+	}
+}
+
+func TestNewTokenCounterDefaults(t *testing.T) {
+	counter := giteeai.NewTokenCounter(giteeai.DeepSeek_V2, nil)
+	if counter.Tokenizer.CountTokens("你好") != 2 ||
+		counter.Format.TokensPerMessage != giteeai.ChatFormatDeepSeek.TokensPerMessage {
+		t.Fatalf("unexpected counter %+v", counter)
+	}
+	if got := giteeai.ChatFormatForModel("other").TokensPerName; got != 1 {
+		t.Errorf("expected the default format, got %d tokens per name", got)
+	}
+}
@@ -0,0 +1,221 @@
+package giteeai
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	tokenizerCacheSize = 10000
+	// approximateCharsPerToken is the average number of characters of a
+	// token in English text.
+	approximateCharsPerToken = 4
+)
+
+// Tokenizer counts the tokens of a text as a model sees them.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface.
+type TokenizerFunc func(text string) int
+
+// CountTokens calls f(text).
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// ApproximateTokenizer estimates the number of tokens without a vocabulary:
+// one token per 4 ASCII characters and per other character, such as CJK,
+// Cyrillic or Arabic text, which vocabularies rarely merge. It is meant for
+// models whose vocabulary is not available.
+var ApproximateTokenizer Tokenizer = TokenizerFunc(approximateTokenCount)
+
+func approximateTokenCount(text string) int {
+	tokens, ascii := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			tokens++
+		}
+	}
+	return tokens + (ascii+approximateCharsPerToken-1)/approximateCharsPerToken
+}
+
+// BPETokenizer is a byte pair encoding tokenizer loaded with LoadTokenizer.
+// It is safe for concurrent use.
+type BPETokenizer struct {
+	vocab map[string]int
+	// added holds the added and special tokens, matched before the model.
+	added        map[string]int
+	addedPattern *regexp.Regexp
+	// normalize and preTokenize split the text into the pieces merged by BPE.
+	normalize   func(string) string
+	preTokenize func(string) []string
+	// symbols splits a piece into its initial symbols.
+	symbols func(string) []string
+	// rank returns the priority of merging a and b, lower first.
+	rank         func(a, b string) (float64, bool)
+	byteFallback bool
+	unknown      int
+
+	mu    sync.Mutex
+	cache map[string][]int
+}
+
+func (t *BPETokenizer) init(added map[string]int) {
+	t.added = added
+	if len(added) > 0 {
+		tokens := make([]string, 0, len(added))
+		for token := range added {
+			tokens = append(tokens, token)
+		}
+		// Longest first, so that a token is not shadowed by its prefix.
+		sort.Slice(tokens, func(i, j int) bool {
+			if len(tokens[i]) != len(tokens[j]) {
+				return len(tokens[i]) > len(tokens[j])
+			}
+			return tokens[i] < tokens[j]
+		})
+		for i, token := range tokens {
+			tokens[i] = regexp.QuoteMeta(token)
+		}
+		t.addedPattern = regexp.MustCompile(strings.Join(tokens, "|"))
+	}
+	if t.normalize == nil {
+		t.normalize = func(s string) string { return s }
+	}
+	if t.preTokenize == nil {
+		t.preTokenize = func(s string) []string { return []string{s} }
+	}
+	t.cache = make(map[string][]int)
+}
+
+// VocabularySize returns the number of tokens of the vocabulary, including
+// the added tokens.
+func (t *BPETokenizer) VocabularySize() int {
+	size := len(t.vocab)
+	for token := range t.added {
+		if _, ok := t.vocab[token]; !ok {
+			size++
+		}
+	}
+	return size
+}
+
+// CountTokens returns the number of tokens of text.
+func (t *BPETokenizer) CountTokens(text string) int {
+	return len(t.Encode(text))
+}
+
+// Encode returns the token IDs of text. Added and special tokens, such as
+// "<|im_start|>", are recognized in text.
+func (t *BPETokenizer) Encode(text string) []int {
+	var ids []int
+	if t.addedPattern == nil {
+		return t.encodeText(text, ids)
+	}
+	start := 0
+	for _, loc := range t.addedPattern.FindAllStringIndex(text, -1) {
+		ids = t.encodeText(text[start:loc[0]], ids)
+		ids = append(ids, t.added[text[loc[0]:loc[1]]])
+		start = loc[1]
+	}
+	return t.encodeText(text[start:], ids)
+}
+
+func (t *BPETokenizer) encodeText(text string, ids []int) []int {
+	if text == "" {
+		return ids
+	}
+	for _, piece := range t.preTokenize(t.normalize(text)) {
+		ids = append(ids, t.encodePiece(piece)...)
+	}
+	return ids
+}
+
+func (t *BPETokenizer) encodePiece(piece string) []int {
+	if id, ok := t.vocab[piece]; ok {
+		return []int{id}
+	}
+	t.mu.Lock()
+	ids, ok := t.cache[piece]
+	t.mu.Unlock()
+	if ok {
+		return ids
+	}
+
+	for _, symbol := range t.merge(t.symbols(piece)) {
+		ids = append(ids, t.symbolIDs(symbol)...)
+	}
+
+	t.mu.Lock()
+	if len(t.cache) >= tokenizerCacheSize {
+		t.cache = make(map[string][]int)
+	}
+	t.cache[piece] = ids
+	t.mu.Unlock()
+	return ids
+}
+
+// merge applies the merges to the symbols, best ranked and leftmost first.
+func (t *BPETokenizer) merge(symbols []string) []string {
+	for len(symbols) > 1 {
+		best, bestRank := -1, 0.0
+		for i := 0; i < len(symbols)-1; i++ {
+			if rank, ok := t.rank(symbols[i], symbols[i+1]); ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		symbols[best] += symbols[best+1]
+		symbols = append(symbols[:best+1], symbols[best+2:]...)
+	}
+	return symbols
+}
+
+func (t *BPETokenizer) symbolIDs(symbol string) []int {
+	if id, ok := t.vocab[symbol]; ok {
+		return []int{id}
+	}
+	if t.byteFallback {
+		ids := make([]int, 0, len(symbol))
+		for i := 0; i < len(symbol); i++ {
+			if id, ok := t.vocab[fmt.Sprintf("<0x%02X>", symbol[i])]; ok {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == len(symbol) {
+			return ids
+		}
+	}
+	if t.unknown >= 0 {
+		return []int{t.unknown}
+	}
+	return nil
+}
+
+// splitRunes returns the characters of s.
+func splitRunes(s string) []string {
+	symbols := make([]string, 0, utf8.RuneCountInString(s))
+	for _, r := range s {
+		symbols = append(symbols, string(r))
+	}
+	return symbols
+}
+
+// splitBytes returns the bytes of s.
+func splitBytes(s string) []string {
+	symbols := make([]string, len(s))
+	for i := 0; i < len(s); i++ {
+		symbols[i] = s[i : i+1]
+	}
+	return symbols
+}
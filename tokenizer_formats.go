@@ -0,0 +1,609 @@
+package giteeai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// gpt2Pattern is the pre-tokenization pattern of the ByteLevel
+	// pre-tokenizer.
+	gpt2Pattern = `'s|'t|'re|'ve|'m|'ll|'d| ?\p{L}+| ?\p{N}+| ?[^\s\p{L}\p{N}]+|\s+(?!\S)|\s+`
+	// qwenPattern is the pre-tokenization pattern of the Qwen models.
+	qwenPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|` +
+		`\s*[\r\n]+|\s+(?!\S)|\s+`
+	// trailingSpaceLookahead is the part of the patterns unsupported by RE2:
+	// a whitespace run not followed by a non-space character.
+	trailingSpaceLookahead = `\s+(?!\S)|`
+
+	sentencePieceSpace = "▁"
+
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+
+	sentencePieceTypeNormal      = 1
+	sentencePieceTypeUnknown     = 2
+	sentencePieceTypeUserDefined = 4
+	sentencePieceTypeByte        = 6
+	sentencePieceModelBPE        = 2
+)
+
+// ErrUnsupportedTokenizer is returned by LoadTokenizer for tokenizer files
+// using features that are not implemented.
+var ErrUnsupportedTokenizer = errors.New("unsupported tokenizer")
+
+// qwenSpecialTokens are the special tokens following the vocabulary of the
+// qwen.tiktoken file.
+var qwenSpecialTokens = []string{"<|endoftext|>", "<|im_start|>", "<|im_end|>"}
+
+// LoadTokenizer loads the vocabulary distributed with the weights of a model.
+// path is one of:
+//
+//   - a Hugging Face tokenizer.json file with a BPE model, as distributed with
+//     Qwen2 and DeepSeek;
+//   - a tiktoken file (".tiktoken"), such as qwen.tiktoken;
+//   - a SentencePiece BPE model (".model"), such as tokenizer.model;
+//   - a directory holding one of these files.
+//
+// Unicode normalization is not applied.
+func LoadTokenizer(path string) (*BPETokenizer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadTokenizerDir(path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenizer *BPETokenizer
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tiktoken":
+		tokenizer, err = parseTiktoken(content)
+	case ".model":
+		tokenizer, err = parseSentencePiece(content)
+	default:
+		tokenizer, err = parseHuggingFaceTokenizer(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading %s: %w", path, err)
+	}
+	return tokenizer, nil
+}
+
+func loadTokenizerDir(dir string) (*BPETokenizer, error) {
+	for _, pattern := range []string{"tokenizer.json", "*.tiktoken", "tokenizer.model"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) > 0 {
+			return LoadTokenizer(matches[0])
+		}
+	}
+	return nil, fmt.Errorf("no tokenizer file in %s", dir)
+}
+
+// Hugging Face tokenizer.json
+
+type hfTokenizerFile struct {
+	AddedTokens []struct {
+		ID      int    `json:"id"`
+		Content string `json:"content"`
+	} `json:"added_tokens"`
+	Normalizer   *hfComponent `json:"normalizer"`
+	PreTokenizer *hfComponent `json:"pre_tokenizer"`
+	Model        struct {
+		Type         string            `json:"type"`
+		Vocab        map[string]int    `json:"vocab"`
+		Merges       []json.RawMessage `json:"merges"`
+		UnkToken     *string           `json:"unk_token"`
+		ByteFallback bool              `json:"byte_fallback"`
+	} `json:"model"`
+}
+
+// hfComponent is a normalizer or pre-tokenizer of a tokenizer.json file.
+type hfComponent struct {
+	Type          string        `json:"type"`
+	Normalizers   []hfComponent `json:"normalizers"`
+	Pretokenizers []hfComponent `json:"pretokenizers"`
+	Pattern       struct {
+		Regex  *string `json:"Regex"`
+		String *string `json:"String"`
+	} `json:"pattern"`
+	Content          string `json:"content"`
+	Prepend          string `json:"prepend"`
+	Behavior         string `json:"behavior"`
+	Invert           bool   `json:"invert"`
+	UseRegex         *bool  `json:"use_regex"`
+	AddPrefixSpace   bool   `json:"add_prefix_space"`
+	Replacement      string `json:"replacement"`
+	PrependScheme    string `json:"prepend_scheme"`
+	Split            *bool  `json:"split"`
+	IndividualDigits bool   `json:"individual_digits"`
+}
+
+func parseHuggingFaceTokenizer(content []byte) (*BPETokenizer, error) {
+	var file hfTokenizerFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	if file.Model.Type != "" && file.Model.Type != "BPE" {
+		return nil, fmt.Errorf("%w: %s model", ErrUnsupportedTokenizer, file.Model.Type)
+	}
+
+	merges := make(map[[2]string]float64, len(file.Model.Merges))
+	for i, raw := range file.Model.Merges {
+		var pair [2]string
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			left, right, ok := strings.Cut(s, " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid merge %q", s)
+			}
+			pair = [2]string{left, right}
+		} else if err = json.Unmarshal(raw, &pair); err != nil {
+			return nil, fmt.Errorf("invalid merge %s: %w", raw, err)
+		}
+		if _, ok := merges[pair]; !ok {
+			merges[pair] = float64(i)
+		}
+	}
+
+	t := &BPETokenizer{
+		vocab:        file.Model.Vocab,
+		symbols:      splitRunes,
+		byteFallback: file.Model.ByteFallback,
+		unknown:      -1,
+		rank: func(a, b string) (float64, bool) {
+			rank, ok := merges[[2]string{a, b}]
+			return rank, ok
+		},
+	}
+	if file.Model.UnkToken != nil {
+		if id, ok := file.Model.Vocab[*file.Model.UnkToken]; ok {
+			t.unknown = id
+		}
+	}
+
+	var err error
+	if file.Normalizer != nil {
+		if t.normalize, err = hfNormalizer(*file.Normalizer); err != nil {
+			return nil, err
+		}
+	}
+	if file.PreTokenizer != nil {
+		if t.preTokenize, err = hfPreTokenizer(*file.PreTokenizer); err != nil {
+			return nil, err
+		}
+	}
+
+	added := make(map[string]int, len(file.AddedTokens))
+	for _, token := range file.AddedTokens {
+		added[token.Content] = token.ID
+	}
+	t.init(added)
+	return t, nil
+}
+
+func hfNormalizer(c hfComponent) (func(string) string, error) {
+	switch c.Type {
+	case "Sequence":
+		steps := make([]func(string) string, len(c.Normalizers))
+		for i, normalizer := range c.Normalizers {
+			step, err := hfNormalizer(normalizer)
+			if err != nil {
+				return nil, err
+			}
+			steps[i] = step
+		}
+		return func(s string) string {
+			for _, step := range steps {
+				s = step(s)
+			}
+			return s
+		}, nil
+	case "Prepend":
+		return func(s string) string { return c.Prepend + s }, nil
+	case "Replace":
+		if c.Pattern.String == nil {
+			return nil, fmt.Errorf("%w: Replace normalizer with a regex", ErrUnsupportedTokenizer)
+		}
+		return func(s string) string { return strings.ReplaceAll(s, *c.Pattern.String, c.Content) }, nil
+	case "NFC", "NFKC", "NFD", "NFKD":
+		// Unicode normalization is not applied, the text is assumed normalized.
+		return func(s string) string { return s }, nil
+	default:
+		return nil, fmt.Errorf("%w: %s normalizer", ErrUnsupportedTokenizer, c.Type)
+	}
+}
+
+func hfPreTokenizer(c hfComponent) (func(string) []string, error) {
+	var split func(string) []string
+	switch c.Type {
+	case "Sequence":
+		steps := make([]func(string) []string, len(c.Pretokenizers))
+		for i, preTokenizer := range c.Pretokenizers {
+			step, err := hfPreTokenizer(preTokenizer)
+			if err != nil {
+				return nil, err
+			}
+			steps[i] = step
+		}
+		return func(s string) []string {
+			pieces := []string{s}
+			for _, step := range steps {
+				pieces = flatMap(pieces, step)
+			}
+			return pieces
+		}, nil
+	case "Split":
+		if c.Pattern.Regex == nil && c.Pattern.String == nil {
+			return nil, fmt.Errorf("%w: Split without a pattern", ErrUnsupportedTokenizer)
+		}
+		pattern := ""
+		if c.Pattern.Regex != nil {
+			pattern = *c.Pattern.Regex
+		} else {
+			pattern = regexp.QuoteMeta(*c.Pattern.String)
+		}
+		if c.Behavior != "Isolated" || c.Invert {
+			return nil, fmt.Errorf("%w: Split with behavior %s", ErrUnsupportedTokenizer, c.Behavior)
+		}
+		return newRegexSplitter(pattern)
+	case "ByteLevel":
+		if c.UseRegex == nil || *c.UseRegex {
+			var err error
+			if split, err = newRegexSplitter(gpt2Pattern); err != nil {
+				return nil, err
+			}
+		}
+		return func(s string) []string {
+			if c.AddPrefixSpace && !strings.HasPrefix(s, " ") {
+				s = " " + s
+			}
+			pieces := []string{s}
+			if split != nil {
+				pieces = split(s)
+			}
+			for i, piece := range pieces {
+				pieces[i] = byteLevelEncode(piece)
+			}
+			return pieces
+		}, nil
+	case "Metaspace":
+		prepend := c.PrependScheme != "never" && (c.PrependScheme != "" || c.AddPrefixSpace)
+		return metaspaceSplitter(c.Replacement, prepend, c.Split == nil || *c.Split), nil
+	case "Digits":
+		return digitsSplitter(c.IndividualDigits), nil
+	default:
+		return nil, fmt.Errorf("%w: %s pre-tokenizer", ErrUnsupportedTokenizer, c.Type)
+	}
+}
+
+func flatMap(pieces []string, split func(string) []string) []string {
+	var result []string
+	for _, piece := range pieces {
+		result = append(result, split(piece)...)
+	}
+	return result
+}
+
+// newRegexSplitter returns a function splitting a text into the matches of
+// pattern and the text between them. The lookahead of `\s+(?!\S)`, which is
+// not supported by RE2, is emulated by leaving the last space of a
+// whitespace run to the following word.
+func newRegexSplitter(pattern string) (func(string) []string, error) {
+	emulateLookahead := strings.Contains(pattern, trailingSpaceLookahead)
+	pattern = strings.Replace(pattern, trailingSpaceLookahead, "", 1)
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: pattern %q: %v", ErrUnsupportedTokenizer, pattern, err)
+	}
+
+	return func(s string) []string {
+		var pieces []string
+		for start := 0; start < len(s); {
+			loc := re.FindStringIndex(s[start:])
+			if loc == nil || loc[1] == 0 {
+				pieces = append(pieces, s[start:])
+				break
+			}
+			matchStart, matchEnd := start+loc[0], start+loc[1]
+			match := s[matchStart:matchEnd]
+			if emulateLookahead && matchEnd < len(s) && isHorizontalSpace(match) && utf8.RuneCountInString(match) > 1 {
+				_, size := utf8.DecodeLastRuneInString(match)
+				matchEnd -= size
+			}
+			if matchStart > start {
+				pieces = append(pieces, s[start:matchStart])
+			}
+			pieces = append(pieces, s[matchStart:matchEnd])
+			start = matchEnd
+		}
+		return pieces
+	}, nil
+}
+
+// isHorizontalSpace reports whether s is only made of spaces other than
+// line breaks.
+func isHorizontalSpace(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) || r == '\n' || r == '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+func metaspaceSplitter(replacement string, prepend, split bool) func(string) []string {
+	if replacement == "" {
+		replacement = sentencePieceSpace
+	}
+	return func(s string) []string {
+		s = strings.ReplaceAll(s, " ", replacement)
+		if prepend && !strings.HasPrefix(s, replacement) {
+			s = replacement + s
+		}
+		if !split {
+			return []string{s}
+		}
+		var pieces []string
+		for s != "" {
+			_, size := utf8.DecodeRuneInString(s)
+			next := strings.Index(s[size:], replacement)
+			if next < 0 {
+				pieces = append(pieces, s)
+				break
+			}
+			pieces = append(pieces, s[:size+next])
+			s = s[size+next:]
+		}
+		return pieces
+	}
+}
+
+func digitsSplitter(individual bool) func(string) []string {
+	return func(s string) []string {
+		var pieces []string
+		start := 0
+		var previousDigit bool
+		for i, r := range s {
+			digit := unicode.IsDigit(r)
+			if i > start && (digit != previousDigit || (digit && individual)) {
+				pieces = append(pieces, s[start:i])
+				start = i
+			}
+			previousDigit = digit
+		}
+		if start < len(s) {
+			pieces = append(pieces, s[start:])
+		}
+		return pieces
+	}
+}
+
+// byteLevelAlphabet maps every byte to the printable character representing
+// it in byte-level vocabularies.
+var byteLevelAlphabet = func() [256]rune {
+	var alphabet [256]rune
+	n := 0
+	for b := 0; b < 256; b++ {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			alphabet[b] = rune(b)
+		} else {
+			alphabet[b] = rune(256 + n)
+			n++
+		}
+	}
+	return alphabet
+}()
+
+func byteLevelEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		b.WriteRune(byteLevelAlphabet[s[i]])
+	}
+	return b.String()
+}
+
+// tiktoken
+
+func parseTiktoken(content []byte) (*BPETokenizer, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		encoded, rankText, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token %q: %w", encoded, err)
+		}
+		rank, err := strconv.Atoi(rankText)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank %q: %w", rankText, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	split, err := newRegexSplitter(qwenPattern)
+	if err != nil {
+		return nil, err
+	}
+	t := &BPETokenizer{
+		vocab:       ranks,
+		preTokenize: split,
+		symbols:     splitBytes,
+		unknown:     -1,
+		rank: func(a, b string) (float64, bool) {
+			rank, ok := ranks[a+b]
+			return float64(rank), ok
+		},
+	}
+	added := make(map[string]int, len(qwenSpecialTokens))
+	for i, token := range qwenSpecialTokens {
+		added[token] = len(ranks) + i
+	}
+	t.init(added)
+	return t, nil
+}
+
+// SentencePiece
+
+type sentencePiece struct {
+	piece     string
+	score     float32
+	pieceType uint64
+}
+
+func parseSentencePiece(content []byte) (*BPETokenizer, error) {
+	var pieces []sentencePiece
+	var modelType uint64 = 1
+	var byteFallback bool
+	err := walkProto(content, func(field int, wire int, value uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == protoWireBytes:
+			piece, err := parseSentencePieceEntry(data)
+			pieces = append(pieces, piece)
+			return err
+		case field == 2 && wire == protoWireBytes:
+			// TrainerSpec: model_type (3) and byte_fallback (35).
+			return walkProto(data, func(field int, wire int, value uint64, _ []byte) error {
+				switch {
+				case field == 3 && wire == protoWireVarint:
+					modelType = value
+				case field == 35 && wire == protoWireVarint:
+					byteFallback = value != 0
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if modelType != sentencePieceModelBPE {
+		return nil, fmt.Errorf("%w: SentencePiece model type %d, only BPE is supported", ErrUnsupportedTokenizer, modelType)
+	}
+
+	vocab := make(map[string]int, len(pieces))
+	scores := make(map[string]float64, len(pieces))
+	added := make(map[string]int)
+	unknown := -1
+	for id, piece := range pieces {
+		vocab[piece.piece] = id
+		switch piece.pieceType {
+		case sentencePieceTypeNormal:
+			scores[piece.piece] = float64(piece.score)
+		case sentencePieceTypeUnknown:
+			unknown = id
+		case sentencePieceTypeUserDefined:
+			added[piece.piece] = id
+		case sentencePieceTypeByte:
+		}
+	}
+
+	t := &BPETokenizer{
+		vocab:        vocab,
+		preTokenize:  metaspaceSplitter(sentencePieceSpace, true, true),
+		symbols:      splitRunes,
+		byteFallback: byteFallback,
+		unknown:      unknown,
+		rank: func(a, b string) (float64, bool) {
+			score, ok := scores[a+b]
+			return -score, ok
+		},
+	}
+	t.init(added)
+	return t, nil
+}
+
+func parseSentencePieceEntry(data []byte) (sentencePiece, error) {
+	piece := sentencePiece{pieceType: sentencePieceTypeNormal}
+	err := walkProto(data, func(field int, wire int, value uint64, data []byte) error {
+		switch {
+		case field == 1 && wire == protoWireBytes:
+			piece.piece = string(data)
+		case field == 2 && wire == protoWireFixed32:
+			piece.score = math.Float32frombits(uint32(value))
+		case field == 3 && wire == protoWireVarint:
+			piece.pieceType = value
+		}
+		return nil
+	})
+	return piece, err
+}
+
+// walkProto calls fn for every field of a protocol buffers message. value
+// holds varint and fixed values, data length-delimited values.
+func walkProto(message []byte, fn func(field int, wire int, value uint64, data []byte) error) error {
+	for len(message) > 0 {
+		key, n := binary.Uvarint(message)
+		if n <= 0 {
+			return errors.New("invalid protocol buffers field")
+		}
+		message = message[n:]
+		field, wire := int(key>>3), int(key&7)
+
+		var value uint64
+		var data []byte
+		switch wire {
+		case protoWireVarint:
+			value, n = binary.Uvarint(message)
+			if n <= 0 {
+				return errors.New("invalid protocol buffers varint")
+			}
+			message = message[n:]
+		case protoWireFixed64:
+			if len(message) < 8 {
+				return errors.New("truncated protocol buffers message")
+			}
+			value, message = binary.LittleEndian.Uint64(message), message[8:]
+		case protoWireFixed32:
+			if len(message) < 4 {
+				return errors.New("truncated protocol buffers message")
+			}
+			value, message = uint64(binary.LittleEndian.Uint32(message)), message[4:]
+		case protoWireBytes:
+			length, n := binary.Uvarint(message)
+			if n <= 0 || uint64(len(message)-n) < length {
+				return errors.New("truncated protocol buffers message")
+			}
+			data, message = message[n:n+int(length)], message[n+int(length):]
+		default:
+			return fmt.Errorf("unsupported protocol buffers wire type %d", wire)
+		}
+		if err := fn(field, wire, value, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
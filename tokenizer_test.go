@@ -0,0 +1,184 @@
+package giteeai_test
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+const qwenSplitPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|` +
+	`\s*[\r\n]+|\s+(?!\S)|\s+`
+
+// writeByteLevelTokenizer writes a tokenizer.json in the format of Qwen2,
+// whose vocabulary knows "hello", " world" and the ChatML markers.
+func writeByteLevelTokenizer(t *testing.T, dir string) string {
+	t.Helper()
+	vocab := map[string]int{}
+	for _, token := range []string{
+		"h", "e", "l", "o", "w", "r", "d", "Ġ", "Ċ", "u", "s",
+		"he", "ll", "hell", "hello", "Ġw", "or", "Ġwor", "Ġworl", "Ġworld", "us", "er", "user",
+	} {
+		vocab[token] = len(vocab)
+	}
+	file := map[string]any{
+		"added_tokens": []map[string]any{
+			{"id": 100, "content": "<|im_start|>", "special": true},
+			{"id": 101, "content": "<|im_end|>", "special": true},
+		},
+		"normalizer": map[string]any{"type": "NFC"},
+		"pre_tokenizer": map[string]any{
+			"type": "Sequence",
+			"pretokenizers": []map[string]any{
+				{"type": "Split", "pattern": map[string]any{"Regex": qwenSplitPattern}, "behavior": "Isolated"},
+				{"type": "ByteLevel", "add_prefix_space": false, "use_regex": false},
+			},
+		},
+		"model": map[string]any{
+			"type":  "BPE",
+			"vocab": vocab,
+			"merges": []any{
+				"h e", "l l", "he ll", "hell o",
+				[]string{"Ġ", "w"}, "o r", "Ġw or", "Ġwor l", "Ġworl d", "u s", "e r", "us er",
+			},
+		},
+	}
+	content, err := json.Marshal(file)
+	checks.NoError(t, err)
+	path := filepath.Join(dir, "tokenizer.json")
+	checks.NoError(t, os.WriteFile(path, content, 0o600))
+	return path
+}
+
+func TestLoadTokenizerHuggingFace(t *testing.T) {
+	dir := t.TempDir()
+	writeByteLevelTokenizer(t, dir)
+
+	// The directory is searched for the tokenizer file.
+	tokenizer, err := giteeai.LoadTokenizer(dir)
+	checks.NoError(t, err, "LoadTokenizer error")
+
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"hello world", []int{14, 19}},
+		{"hello  world", []int{14, 7, 19}},
+		{"<|im_start|>user\nhello<|im_end|>", []int{100, 22, 8, 14, 101}},
+		{"world", []int{4, 16, 2, 6}},
+	}
+	for _, tt := range tests {
+		if got := tokenizer.Encode(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+		if got := tokenizer.CountTokens(tt.text); got != len(tt.want) {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, len(tt.want))
+		}
+	}
+	if tokenizer.VocabularySize() != 25 {
+		t.Errorf("unexpected vocabulary size %d", tokenizer.VocabularySize())
+	}
+}
+
+func TestLoadTokenizerTiktoken(t *testing.T) {
+	ranks := []string{"h", "e", "l", "o", " ", "w", "he", "ll", "hell", "hello", " w"}
+	var content string
+	for rank, token := range ranks {
+		content += fmt.Sprintf("%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	path := filepath.Join(t.TempDir(), "qwen.tiktoken")
+	checks.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	tokenizer, err := giteeai.LoadTokenizer(path)
+	checks.NoError(t, err, "LoadTokenizer error")
+	if got, want := tokenizer.Encode("hello<|im_end|> wow"), []int{9, 13, 10, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode() = %v, want %v", got, want)
+	}
+}
+
+// protoField encodes a length-delimited or varint protocol buffers field.
+func protoField(field int, value any) []byte {
+	varint := func(v uint64) []byte {
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutUvarint(buf, v)]
+	}
+	switch v := value.(type) {
+	case []byte:
+		return append(append(varint(uint64(field<<3|2)), varint(uint64(len(v)))...), v...)
+	case string:
+		return protoField(field, []byte(v))
+	case float32:
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint32(b, math.Float32bits(v))
+		return append(varint(uint64(field<<3|5)), b...)
+	default:
+		return append(varint(uint64(field<<3)), varint(uint64(v.(int)))...)
+	}
+}
+
+func TestLoadTokenizerSentencePiece(t *testing.T) {
+	var model []byte
+	pieces := []struct {
+		piece     string
+		score     float32
+		pieceType int
+	}{
+		{"<unk>", 0, 2}, {"<0xE2>", 0, 6}, {"<0x82>", 0, 6}, {"<0xAC>", 0, 6},
+		{"▁", -1, 1}, {"h", -1, 1}, {"i", -1, 1}, {"▁h", -2, 1}, {"▁hi", -3, 1}, {"<tool>", 0, 4},
+	}
+	for _, p := range pieces {
+		entry := append(append(protoField(1, p.piece), protoField(2, p.score)...), protoField(3, p.pieceType)...)
+		model = append(model, protoField(1, entry)...)
+	}
+	trainer := append(protoField(3, 2), protoField(35, 1)...)
+	model = append(model, protoField(2, trainer)...)
+
+	path := filepath.Join(t.TempDir(), "tokenizer.model")
+	checks.NoError(t, os.WriteFile(path, model, 0o600))
+
+	tokenizer, err := giteeai.LoadTokenizer(path)
+	checks.NoError(t, err, "LoadTokenizer error")
+	// "€" is unknown and encoded with its bytes.
+	if got, want := tokenizer.Encode("hi<tool> hi€"), []int{8, 9, 8, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Encode() = %v, want %v", got, want)
+	}
+
+	unigram := append(append([]byte(nil), model...), protoField(2, protoField(3, 1))...)
+	checks.NoError(t, os.WriteFile(path, unigram, 0o600))
+	_, err = giteeai.LoadTokenizer(path)
+	if !errors.Is(err, giteeai.ErrUnsupportedTokenizer) {
+		t.Errorf("expected ErrUnsupportedTokenizer for a unigram model, got %v", err)
+	}
+}
+
+func TestLoadTokenizerErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := giteeai.LoadTokenizer(dir)
+	checks.HasError(t, err, "expected an error for a directory without tokenizer")
+
+	path := filepath.Join(dir, "tokenizer.json")
+	checks.NoError(t, os.WriteFile(path, []byte(`{"model":{"type":"Unigram"}}`), 0o600))
+	_, err = giteeai.LoadTokenizer(path)
+	checks.ErrorIs(t, err, giteeai.ErrUnsupportedTokenizer)
+}
+
+func TestApproximateTokenizer(t *testing.T) {
+	if got := giteeai.ApproximateTokenizer.CountTokens("hello world!"); got != 3 {
+		t.Errorf("expected 3 tokens, got %d", got)
+	}
+	if got := giteeai.ApproximateTokenizer.CountTokens("你好, world"); got != 4 {
+		t.Errorf("expected 4 tokens, got %d", got)
+	}
+	if got := giteeai.ApproximateTokenizer.CountTokens("привет мир"); got != 10 {
+		t.Errorf("expected one token per Cyrillic letter, got %d", got)
+	}
+}
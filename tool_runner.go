@@ -0,0 +1,329 @@
+package giteeai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai/jsonschema"
+)
+
+const defaultToolRunnerMaxIterations = 10
+
+var (
+	// ErrToolRunnerMaxIterations is returned when the model still requests
+	// tool calls after ToolRunner.MaxIterations completions.
+	ErrToolRunnerMaxIterations = errors.New("tool runner reached the maximum number of iterations")
+	// ErrToolAlreadyRegistered is returned when two tools share a name.
+	ErrToolAlreadyRegistered = errors.New("tool already registered")
+	// ErrToolRunnerMultipleChoices is returned when the request asks for more
+	// than one choice, since only one conversation can be continued.
+	ErrToolRunnerMultipleChoices = errors.New("tool runner does not support N > 1")
+)
+
+// ToolHandler executes a tool call. It receives the raw JSON arguments
+// generated by the model and returns the content of the tool message.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// ToolOption configures a registered tool.
+type ToolOption func(*registeredTool)
+
+// WithToolTimeout limits the duration of every call of the tool,
+// overriding ToolRunner.ToolTimeout.
+func WithToolTimeout(timeout time.Duration) ToolOption {
+	return func(t *registeredTool) {
+		t.timeout = timeout
+	}
+}
+
+type registeredTool struct {
+	definition FunctionDefinition
+	handler    ToolHandler
+	timeout    time.Duration
+}
+
+// ToolRegistry holds the Go functions the model may call.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]*registeredTool
+	order []string
+}
+
+// NewToolRegistry creates an empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]*registeredTool)}
+}
+
+// Register adds a tool described by definition.
+func (r *ToolRegistry) Register(definition FunctionDefinition, handler ToolHandler, opts ...ToolOption) error {
+	tool := &registeredTool{definition: definition, handler: handler}
+	for _, opt := range opts {
+		opt(tool)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tools == nil {
+		r.tools = make(map[string]*registeredTool)
+	}
+	if _, ok := r.tools[definition.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrToolAlreadyRegistered, definition.Name)
+	}
+	r.tools[definition.Name] = tool
+	r.order = append(r.order, definition.Name)
+	return nil
+}
+
+// RegisterTool adds fn as a tool. Its parameters schema is generated from the
+// argument type A with jsonschema.GenerateSchemaForType, so the struct tags
+// json, description, enum and required describe the arguments to the model.
+// Arguments that do not conform to the schema are reported to the model with
+// the validation errors. A string result is returned to the model as is, any
+// other result as JSON.
+func RegisterTool[A, R any](
+	r *ToolRegistry,
+	name, description string,
+	fn func(ctx context.Context, args A) (R, error),
+	opts ...ToolOption,
+) error {
+	var zero A
+	schema, err := jsonschema.GenerateSchemaForType(zero)
+	if err != nil {
+		return fmt.Errorf("generating the schema of %s: %w", name, err)
+	}
+	definition := FunctionDefinition{
+		Name:        name,
+		Description: description,
+		Parameters:  schema,
+	}
+	return r.Register(definition, func(ctx context.Context, arguments string) (string, error) {
+		var args A
+		if arguments == "" {
+			arguments = "{}"
+		}
+		if err := jsonschema.VerifySchemaAndUnmarshal(*schema, []byte(arguments), &args); err != nil {
+			return "", fmt.Errorf("invalid arguments: %w", err)
+		}
+		result, err := fn(ctx, args)
+		if err != nil {
+			return "", err
+		}
+		if s, ok := any(result).(string); ok {
+			return s, nil
+		}
+		b, err := json.Marshal(result)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}, opts...)
+}
+
+// Tools returns the registered tools, in registration order, to be sent
+// with a chat completion request.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tools := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		definition := r.tools[name].definition
+		tools = append(tools, Tool{Type: ToolTypeFunction, Function: &definition})
+	}
+	return tools
+}
+
+func (r *ToolRegistry) lookup(name string) (*registeredTool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// ToolRunner drives a conversation in which the model calls Go functions:
+// it sends the request, executes the requested tool calls concurrently,
+// appends their results and asks the model again until it answers without
+// calling a tool.
+type ToolRunner struct {
+	Client   *Client
+	Registry *ToolRegistry
+	// MaxIterations is the maximum number of completions requested, 10 by default.
+	MaxIterations int
+	// ToolTimeout limits every tool call that has no timeout of its own. Zero
+	// means no limit.
+	ToolTimeout time.Duration
+}
+
+// NewToolRunner creates a ToolRunner with the default limits.
+func NewToolRunner(client *Client, registry *ToolRegistry) *ToolRunner {
+	return &ToolRunner{Client: client, Registry: registry}
+}
+
+// ToolRunResult is the outcome of a ToolRunner run.
+type ToolRunResult struct {
+	// Response is the last completion returned by the model.
+	Response ChatCompletionResponse
+	// Messages is the whole conversation: the request messages followed by
+	// the assistant and tool messages of every iteration, including the final answer.
+	Messages []ChatCompletionMessage
+	// Iterations is the number of completions requested.
+	Iterations int
+}
+
+// Run executes the tool calling loop with blocking completions.
+func (r *ToolRunner) Run(ctx context.Context, request ChatCompletionRequest) (ToolRunResult, error) {
+	return r.run(ctx, request, func(request ChatCompletionRequest) (ChatCompletionResponse, error) {
+		return r.Client.CreateChatCompletion(ctx, request)
+	})
+}
+
+// RunStream executes the tool calling loop with streaming completions. Every
+// chunk of every iteration is passed to onChunk; an error returned by
+// onChunk stops the run.
+func (r *ToolRunner) RunStream(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	onChunk func(ChatCompletionStreamResponse) error,
+) (ToolRunResult, error) {
+	return r.run(ctx, request, func(request ChatCompletionRequest) (ChatCompletionResponse, error) {
+		stream, err := r.Client.CreateChatCompletionStream(ctx, request)
+		if err != nil {
+			return ChatCompletionResponse{}, err
+		}
+		defer stream.Close()
+
+		acc := NewChatCompletionAccumulator()
+		for {
+			chunk, recvErr := stream.Recv()
+			if errors.Is(recvErr, io.EOF) {
+				break
+			}
+			if recvErr != nil {
+				return acc.Response(), recvErr
+			}
+			acc.Add(chunk)
+			if onChunk != nil {
+				if err = onChunk(chunk); err != nil {
+					return acc.Response(), err
+				}
+			}
+		}
+		response := acc.Response()
+		response.httpHeader = stream.httpHeader
+		return response, nil
+	})
+}
+
+func (r *ToolRunner) run(
+	ctx context.Context,
+	request ChatCompletionRequest,
+	complete func(ChatCompletionRequest) (ChatCompletionResponse, error),
+) (result ToolRunResult, err error) {
+	if request.N > 1 {
+		return result, ErrToolRunnerMultipleChoices
+	}
+	if len(request.Tools) == 0 {
+		request.Tools = r.Registry.Tools()
+	}
+	maxIterations := r.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultToolRunnerMaxIterations
+	}
+
+	result.Messages = append([]ChatCompletionMessage(nil), request.Messages...)
+	for {
+		request.Messages = result.Messages
+		result.Response, err = complete(request)
+		if err != nil {
+			return result, err
+		}
+		result.Iterations++
+		if len(result.Response.Choices) == 0 {
+			return result, nil
+		}
+
+		message := result.Response.Choices[0].Message
+		result.Messages = append(result.Messages, message)
+		if len(message.ToolCalls) == 0 {
+			return result, nil
+		}
+		if result.Iterations >= maxIterations {
+			return result, ErrToolRunnerMaxIterations
+		}
+		result.Messages = append(result.Messages, r.callTools(ctx, message.ToolCalls)...)
+	}
+}
+
+// callTools executes the tool calls concurrently and returns their tool
+// messages in the order of the calls.
+func (r *ToolRunner) callTools(ctx context.Context, calls []ToolCall) []ChatCompletionMessage {
+	messages := make([]ChatCompletionMessage, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ToolCall) {
+			defer wg.Done()
+			content, err := r.callTool(ctx, call)
+			if err != nil {
+				content = "error: " + err.Error()
+			}
+			messages[i] = ChatCompletionMessage{
+				Role:       ChatMessageRoleTool,
+				Content:    content,
+				Name:       call.Function.Name,
+				ToolCallID: call.ID,
+			}
+		}(i, call)
+	}
+	wg.Wait()
+	return messages
+}
+
+// callTool executes a single call. Failures, including unknown tools,
+// timeouts and panics, are reported to the model rather than aborting the run.
+func (r *ToolRunner) callTool(ctx context.Context, call ToolCall) (content string, err error) {
+	tool, ok := r.Registry.lookup(call.Function.Name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Function.Name)
+	}
+
+	timeout := tool.timeout
+	if timeout <= 0 {
+		timeout = r.ToolTimeout
+	}
+	parent := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		content string
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{err: fmt.Errorf("tool %s panicked: %v", call.Function.Name, p)}
+			}
+		}()
+		content, err := tool.handler(ctx, call.Function.Arguments)
+		done <- outcome{content, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.content, o.err
+	case <-ctx.Done():
+		// The deadline of the caller is not the timeout of the tool.
+		if timeout > 0 && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("tool %s timed out after %s", call.Function.Name, timeout)
+		}
+		return "", fmt.Errorf("tool %s: %w", call.Function.Name, ctx.Err())
+	}
+}
@@ -0,0 +1,28 @@
+package giteeai //nolint:testpackage // testing the unexported callTool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallToolParentDeadline(t *testing.T) {
+	registry := NewToolRegistry()
+	err := registry.Register(FunctionDefinition{Name: "slow"}, func(ctx context.Context, _ string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := &ToolRunner{Registry: registry}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = runner.callTool(ctx, ToolCall{Function: FunctionCall{Name: "slow"}})
+	if !errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected the deadline of the caller, got %v", err)
+	}
+}
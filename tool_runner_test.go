@@ -0,0 +1,242 @@
+package giteeai_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+type weatherArgs struct {
+	City string `json:"city" description:"The city name"`
+	Unit string `json:"unit,omitempty" enum:"celsius,fahrenheit"`
+}
+
+type weatherReport struct {
+	City        string `json:"city"`
+	Temperature int    `json:"temperature"`
+}
+
+// toolCallingHandler answers the first request with the given tool calls and
+// the following ones with the content of the tool messages it received.
+func toolCallingHandler(calls []giteeai.ToolCall, requests *int32) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		var request giteeai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		last := request.Messages[len(request.Messages)-1]
+		message := giteeai.ChatCompletionMessage{Role: giteeai.ChatMessageRoleAssistant}
+		finishReason := giteeai.FinishReasonStop
+		if last.Role == giteeai.ChatMessageRoleUser {
+			message.ToolCalls = calls
+			finishReason = giteeai.FinishReasonToolCalls
+		} else {
+			var results []string
+			for _, m := range request.Messages {
+				if m.Role == giteeai.ChatMessageRoleTool {
+					results = append(results, m.ToolCallID+"="+m.Content)
+				}
+			}
+			message.Content = strings.Join(results, ";")
+		}
+		_ = json.NewEncoder(w).Encode(giteeai.ChatCompletionResponse{
+			Choices: []giteeai.ChatCompletionChoice{{Message: message, FinishReason: finishReason}},
+		})
+	}
+}
+
+func userRequest() giteeai.ChatCompletionRequest {
+	return giteeai.ChatCompletionRequest{
+		Model:    giteeai.Qwen2_7B_Instruct,
+		Messages: []giteeai.ChatCompletionMessage{{Role: giteeai.ChatMessageRoleUser, Content: "Weather?"}},
+	}
+}
+
+func TestToolRunnerParallelCalls(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests int32
+	server.RegisterHandler("/v1/chat/completions", toolCallingHandler([]giteeai.ToolCall{
+		{ID: "call_1", Type: giteeai.ToolTypeFunction, Function: giteeai.FunctionCall{
+			Name: "weather", Arguments: `{"city":"Paris"}`,
+		}},
+		{ID: "call_2", Type: giteeai.ToolTypeFunction, Function: giteeai.FunctionCall{
+			Name: "weather", Arguments: `{"city":"Oslo"}`,
+		}},
+	}, &requests))
+
+	// Both calls must run at the same time to get past the barrier.
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+	registry := giteeai.NewToolRegistry()
+	err := giteeai.RegisterTool(registry, "weather", "Get the weather",
+		func(_ context.Context, args weatherArgs) (weatherReport, error) {
+			barrier.Done()
+			barrier.Wait()
+			return weatherReport{City: args.City, Temperature: len(args.City)}, nil
+		})
+	checks.NoError(t, err, "RegisterTool error")
+
+	result, err := giteeai.NewToolRunner(client, registry).Run(context.Background(), userRequest())
+	checks.NoError(t, err, "Run error")
+
+	expected := `call_1={"city":"Paris","temperature":5};call_2={"city":"Oslo","temperature":4}`
+	if got := result.Response.Choices[0].Message.Content; got != expected {
+		t.Fatalf("unexpected answer %q", got)
+	}
+	if result.Iterations != 2 || len(result.Messages) != 5 {
+		t.Fatalf("unexpected result: %d iterations, %d messages", result.Iterations, len(result.Messages))
+	}
+	if result.Messages[2].Role != giteeai.ChatMessageRoleTool || result.Messages[2].Name != "weather" {
+		t.Fatalf("unexpected tool message %+v", result.Messages[2])
+	}
+}
+
+func TestToolRunnerSchema(t *testing.T) {
+	registry := giteeai.NewToolRegistry()
+	err := giteeai.RegisterTool(registry, "weather", "Get the weather",
+		func(_ context.Context, _ weatherArgs) (string, error) { return "", nil })
+	checks.NoError(t, err, "RegisterTool error")
+	err = giteeai.RegisterTool(registry, "weather", "Duplicate",
+		func(_ context.Context, _ weatherArgs) (string, error) { return "", nil })
+	checks.ErrorIs(t, err, giteeai.ErrToolAlreadyRegistered, "expected a duplicate tool error")
+
+	tools := registry.Tools()
+	if len(tools) != 1 || tools[0].Type != giteeai.ToolTypeFunction {
+		t.Fatalf("unexpected tools %+v", tools)
+	}
+	b, err := json.Marshal(tools[0].Function.Parameters)
+	checks.NoError(t, err, "Marshal error")
+	var schema map[string]any
+	checks.NoError(t, json.Unmarshal(b, &schema), "Unmarshal error")
+	if fmt.Sprint(schema["required"]) != "[city]" {
+		t.Fatalf("unexpected schema %s", b)
+	}
+}
+
+func TestToolRunnerErrorsAreReported(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests int32
+	server.RegisterHandler("/v1/chat/completions", toolCallingHandler([]giteeai.ToolCall{
+		{ID: "slow", Function: giteeai.FunctionCall{Name: "slow"}},
+		{ID: "failing", Function: giteeai.FunctionCall{Name: "failing"}},
+		{ID: "missing", Function: giteeai.FunctionCall{Name: "missing"}},
+		{ID: "invalid", Function: giteeai.FunctionCall{Name: "failing", Arguments: "{"}},
+		{ID: "mistyped", Function: giteeai.FunctionCall{Name: "weather", Arguments: `{"city":1}`}},
+	}, &requests))
+
+	registry := giteeai.NewToolRegistry()
+	checks.NoError(t, giteeai.RegisterTool(registry, "slow", "",
+		func(ctx context.Context, _ struct{}) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}, giteeai.WithToolTimeout(10*time.Millisecond)), "RegisterTool error")
+	checks.NoError(t, giteeai.RegisterTool(registry, "failing", "",
+		func(_ context.Context, _ struct{}) (string, error) {
+			return "", errors.New("boom")
+		}), "RegisterTool error")
+	checks.NoError(t, giteeai.RegisterTool(registry, "weather", "",
+		func(_ context.Context, _ weatherArgs) (string, error) {
+			return "sunny", nil
+		}), "RegisterTool error")
+
+	result, err := giteeai.NewToolRunner(client, registry).Run(context.Background(), userRequest())
+	checks.NoError(t, err, "Run error")
+
+	answer := result.Response.Choices[0].Message.Content
+	for _, expected := range []string{
+		"slow=error: tool slow timed out",
+		"failing=error: boom",
+		`missing=error: unknown tool "missing"`,
+		"invalid=error: invalid arguments",
+		"mistyped=error: invalid arguments: data validation failed against the provided schema: " +
+			"/city: expected string, got integer",
+	} {
+		if !strings.Contains(answer, expected) {
+			t.Errorf("expected %q in %q", expected, answer)
+		}
+	}
+}
+
+func TestToolRunnerMaxIterations(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	var requests int32
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","tool_calls":`+
+			`[{"id":"c","type":"function","function":{"name":"noop","arguments":"{}"}}]}}]}`)
+	})
+
+	registry := giteeai.NewToolRegistry()
+	checks.NoError(t, giteeai.RegisterTool(registry, "noop", "",
+		func(_ context.Context, _ struct{}) (string, error) { return "ok", nil }), "RegisterTool error")
+	runner := giteeai.NewToolRunner(client, registry)
+	runner.MaxIterations = 3
+
+	result, err := runner.Run(context.Background(), userRequest())
+	checks.ErrorIs(t, err, giteeai.ErrToolRunnerMaxIterations, "expected the iteration budget to be enforced")
+	if requests != 3 || result.Iterations != 3 {
+		t.Fatalf("expected 3 completions, got %d requests and %d iterations", requests, result.Iterations)
+	}
+}
+
+func TestToolRunnerStream(t *testing.T) {
+	client, server, teardown := setupGiteeAITestServer()
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var request giteeai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if request.Messages[len(request.Messages)-1].Role == giteeai.ChatMessageRoleUser {
+			fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":"+
+				"[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\","+
+				"\"function\":{\"name\":\"weather\",\"arguments\":\"\"}}]}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":"+
+				"[{\"index\":0,\"function\":{\"arguments\":\"{\\\"city\\\":\\\"Rome\\\"}\"}}]},"+
+				"\"finish_reason\":\"tool_calls\"}]}\n\n")
+		} else {
+			content, _ := json.Marshal(request.Messages[len(request.Messages)-1].Content)
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%s},\"finish_reason\":\"stop\"}]}\n\n",
+				content)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	registry := giteeai.NewToolRegistry()
+	checks.NoError(t, giteeai.RegisterTool(registry, "weather", "",
+		func(_ context.Context, args weatherArgs) (string, error) {
+			return "sunny in " + args.City, nil
+		}), "RegisterTool error")
+
+	chunks := 0
+	result, err := giteeai.NewToolRunner(client, registry).RunStream(context.Background(), userRequest(),
+		func(giteeai.ChatCompletionStreamResponse) error {
+			chunks++
+			return nil
+		})
+	checks.NoError(t, err, "RunStream error")
+	if got := result.Response.Choices[0].Message.Content; got != "sunny in Rome" || chunks != 3 {
+		t.Fatalf("unexpected answer %q after %d chunks", got, chunks)
+	}
+	if call := result.Messages[1].ToolCalls[0]; call.ID != "call_1" || call.Function.Arguments != `{"city":"Rome"}` {
+		t.Fatalf("unexpected accumulated tool call %+v", call)
+	}
+}
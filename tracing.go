@@ -0,0 +1,246 @@
+package giteeai
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Tracer starts spans for the calls made by the client. The interface is
+// dependency free; an adapter for OpenTelemetry or any other tracing system
+// only needs to forward the calls.
+type Tracer interface {
+	// Start creates a span and returns a context carrying it.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a key/value pair attached to a span or an event.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Attr creates an Attribute.
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Span event names recorded for streaming calls.
+const (
+	SpanEventFirstByte     = "stream.first_byte"
+	SpanEventFirstToken    = "stream.first_token"
+	SpanEventToolCallDelta = "stream.tool_call_delta"
+	SpanEventDone          = "stream.done"
+	SpanEventClosed        = "stream.closed"
+)
+
+// NewTracingMiddleware returns a middleware creating one span per call. For
+// streaming calls the span stays open until the stream ends and records
+// events for the first byte, the first token, every tool call delta and the
+// end of the stream. It is installed automatically when ClientConfig.Tracer is set.
+func NewTracingMiddleware(tracer Tracer) Middleware {
+	return func(next Doer) Doer {
+		return DoerFunc(func(call *Call) error {
+			attrs := []Attribute{
+				Attr("giteeai.operation", string(call.Operation)),
+				Attr("http.method", call.Request.Method),
+				Attr("http.url", call.Request.URL.String()),
+			}
+			if model := requestModel(call.Body); model != "" {
+				attrs = append(attrs, Attr("giteeai.request.model", model))
+			}
+			if call.Stream {
+				attrs = append(attrs, Attr("giteeai.stream", true))
+			}
+			ctx, span := tracer.Start(call.Request.Context(), "giteeai."+string(call.Operation), attrs...)
+			call.Request = call.Request.WithContext(ctx)
+
+			observer := &spanStreamObserver{span: span}
+			if call.Stream {
+				call.ObserveStream(observer)
+			}
+
+			err := next.Do(call)
+			if call.HTTPResponse != nil {
+				span.SetAttributes(Attr("http.status_code", call.HTTPResponse.StatusCode))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetAttributes(Attr("giteeai.error.type", errorType(err)))
+				observer.end()
+				return err
+			}
+			if call.Stream && call.HTTPResponse != nil {
+				// The stream observer ends the span.
+				return nil
+			}
+
+			span.SetAttributes(responseAttributes(call.Response)...)
+			observer.end()
+			return nil
+		})
+	}
+}
+
+// responseAttributes describes a decoded response: its model, finish
+// reasons and token usage.
+func responseAttributes(response any) []Attribute {
+	var attrs []Attribute
+	switch r := response.(type) {
+	case *ChatCompletionResponse:
+		attrs = append(attrs, Attr("giteeai.response.model", r.Model), Attr("giteeai.response.id", r.ID))
+		reasons := make([]string, 0, len(r.Choices))
+		for _, choice := range r.Choices {
+			reasons = append(reasons, string(choice.FinishReason))
+		}
+		attrs = append(attrs, Attr("giteeai.response.finish_reasons", reasons))
+	case *CompletionResponse:
+		attrs = append(attrs, Attr("giteeai.response.model", r.Model), Attr("giteeai.response.id", r.ID))
+		reasons := make([]string, 0, len(r.Choices))
+		for _, choice := range r.Choices {
+			reasons = append(reasons, choice.FinishReason)
+		}
+		attrs = append(attrs, Attr("giteeai.response.finish_reasons", reasons))
+	}
+	if usage, ok := responseUsage(response); ok {
+		attrs = append(attrs, usageAttributes(usage)...)
+	}
+	return attrs
+}
+
+func usageAttributes(usage Usage) []Attribute {
+	attrs := []Attribute{
+		Attr("giteeai.usage.prompt_tokens", usage.PromptTokens),
+		Attr("giteeai.usage.completion_tokens", usage.CompletionTokens),
+		Attr("giteeai.usage.total_tokens", usage.TotalTokens),
+	}
+	if usage.CompletionTokensDetails != nil {
+		attrs = append(attrs, Attr("giteeai.usage.reasoning_tokens", usage.CompletionTokensDetails.ReasoningTokens))
+	}
+	return attrs
+}
+
+// spanStreamObserver records the lifecycle of a stream on its span.
+type spanStreamObserver struct {
+	span Span
+
+	mu            sync.Mutex
+	ended         bool
+	hasFirstToken bool
+	finishReasons map[int]string
+}
+
+func (o *spanStreamObserver) OnFirstByte() {
+	o.span.AddEvent(SpanEventFirstByte)
+}
+
+func (o *spanStreamObserver) OnChunk(chunk any) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch c := chunk.(type) {
+	case *ChatCompletionStreamResponse:
+		if c.Usage != nil {
+			o.span.SetAttributes(usageAttributes(*c.Usage)...)
+		}
+		if c.Model != "" {
+			o.span.SetAttributes(Attr("giteeai.response.model", c.Model))
+		}
+		for _, choice := range c.Choices {
+			delta := choice.Delta
+			if delta.Content != "" || delta.ReasoningContent != "" {
+				o.firstToken()
+			}
+			for _, toolCall := range delta.ToolCalls {
+				o.firstToken()
+				attrs := []Attribute{Attr("choice.index", choice.Index)}
+				if toolCall.Index != nil {
+					attrs = append(attrs, Attr("tool_call.index", *toolCall.Index))
+				}
+				if toolCall.ID != "" {
+					attrs = append(attrs, Attr("tool_call.id", toolCall.ID))
+				}
+				if toolCall.Function.Name != "" {
+					attrs = append(attrs, Attr("tool_call.name", toolCall.Function.Name))
+				}
+				attrs = append(attrs, Attr("tool_call.arguments_length", len(toolCall.Function.Arguments)))
+				o.span.AddEvent(SpanEventToolCallDelta, attrs...)
+			}
+			o.finishReason(choice.Index, string(choice.FinishReason))
+		}
+	case *CompletionResponse:
+		if c.Usage != nil {
+			o.span.SetAttributes(usageAttributes(*c.Usage)...)
+		}
+		for _, choice := range c.Choices {
+			if choice.Text != "" {
+				o.firstToken()
+			}
+			o.finishReason(choice.Index, choice.FinishReason)
+		}
+	}
+}
+
+func (o *spanStreamObserver) firstToken() {
+	if o.hasFirstToken {
+		return
+	}
+	o.hasFirstToken = true
+	o.span.AddEvent(SpanEventFirstToken)
+}
+
+func (o *spanStreamObserver) finishReason(index int, reason string) {
+	if reason == "" || reason == string(FinishReasonNull) {
+		return
+	}
+	if o.finishReasons == nil {
+		o.finishReasons = make(map[int]string)
+	}
+	o.finishReasons[index] = reason
+}
+
+func (o *spanStreamObserver) OnFinish(err error) {
+	o.mu.Lock()
+	if len(o.finishReasons) > 0 {
+		maxIndex := 0
+		for i := range o.finishReasons {
+			if i > maxIndex {
+				maxIndex = i
+			}
+		}
+		reasons := make([]string, maxIndex+1)
+		for i, reason := range o.finishReasons {
+			reasons[i] = reason
+		}
+		o.span.SetAttributes(Attr("giteeai.response.finish_reasons", reasons))
+	}
+	o.mu.Unlock()
+
+	switch {
+	case err == nil:
+		o.span.AddEvent(SpanEventDone)
+	case errors.Is(err, ErrStreamClosed):
+		o.span.AddEvent(SpanEventClosed)
+	default:
+		o.span.RecordError(err)
+	}
+	o.end()
+}
+
+func (o *spanStreamObserver) end() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.ended {
+		return
+	}
+	o.ended = true
+	o.span.End()
+}
@@ -0,0 +1,165 @@
+package giteeai_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/edmondfrank/go-giteeai"
+	"github.com/edmondfrank/go-giteeai/internal/test/checks"
+)
+
+type recordedSpan struct {
+	name   string
+	attrs  map[string]any
+	events []string
+	errs   []error
+	ended  int
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...giteeai.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) AddEvent(name string, _ ...giteeai.Attribute) {
+	s.events = append(s.events, name)
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *recordedSpan) End() {
+	s.ended++
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (tr *recordingTracer) Start(
+	ctx context.Context,
+	name string,
+	attrs ...giteeai.Attribute,
+) (context.Context, giteeai.Span) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	span := &recordedSpan{name: name, attrs: make(map[string]any)}
+	span.SetAttributes(attrs...)
+	tr.spans = append(tr.spans, span)
+	return ctx, span
+}
+
+func TestTracingChatCompletion(t *testing.T) {
+	tracer := &recordingTracer{}
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Tracer = tracer
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"model":"qwen","choices":[{"finish_reason":"stop"}],"usage":{"prompt_tokens":2,"total_tokens":5}}`)
+	})
+
+	_, err := client.CreateChatCompletion(context.Background(), giteeai.ChatCompletionRequest{
+		Model: giteeai.Qwen2_7B_Instruct,
+	})
+	checks.NoError(t, err, "CreateChatCompletion error")
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("expected one span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "giteeai.CreateChatCompletion" || span.ended != 1 {
+		t.Fatalf("unexpected span %+v", span)
+	}
+	if span.attrs["giteeai.request.model"] != giteeai.Qwen2_7B_Instruct ||
+		span.attrs["giteeai.usage.total_tokens"] != 5 ||
+		span.attrs["http.status_code"] != http.StatusOK ||
+		fmt.Sprint(span.attrs["giteeai.response.finish_reasons"]) != "[stop]" {
+		t.Fatalf("unexpected attributes %v", span.attrs)
+	}
+}
+
+func TestTracingChatCompletionStream(t *testing.T) {
+	tracer := &recordingTracer{}
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Tracer = tracer
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":"+
+			"[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"f\",\"arguments\":\"{\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":"+
+			"[{\"index\":0,\"function\":{\"arguments\":\"}\"}}]},\"finish_reason\":\"tool_calls\"}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":4,\"total_tokens\":7}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{
+		Model: giteeai.Qwen2_7B_Instruct,
+	})
+	checks.NoError(t, err, "CreateChatCompletionStream error")
+
+	span := tracer.spans[0]
+	if span.ended != 0 {
+		t.Fatal("span must stay open while the stream is consumed")
+	}
+	for {
+		_, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		checks.NoError(t, err, "Recv error")
+	}
+	stream.Close()
+
+	expected := []string{
+		giteeai.SpanEventFirstByte,
+		giteeai.SpanEventFirstToken,
+		giteeai.SpanEventToolCallDelta,
+		giteeai.SpanEventToolCallDelta,
+		giteeai.SpanEventDone,
+	}
+	if fmt.Sprint(span.events) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected events %v", span.events)
+	}
+	if span.ended != 1 || len(span.errs) != 0 {
+		t.Fatalf("span ended %d times with errors %v", span.ended, span.errs)
+	}
+	if span.attrs["giteeai.usage.total_tokens"] != 7 ||
+		fmt.Sprint(span.attrs["giteeai.response.finish_reasons"]) != "[tool_calls]" {
+		t.Fatalf("unexpected attributes %v", span.attrs)
+	}
+}
+
+func TestTracingRecordsErrors(t *testing.T) {
+	tracer := &recordingTracer{}
+	client, server, teardown := setupGiteeAITestServerWithConfig(func(c *giteeai.ClientConfig) {
+		c.Tracer = tracer
+	})
+	defer teardown()
+
+	server.RegisterHandler("/v1/chat/completions", func(w http.ResponseWriter, _ *http.Request) {
+		writeServerError(w, http.StatusInternalServerError, "server_error")
+	})
+
+	_, err := client.CreateChatCompletionStream(context.Background(), giteeai.ChatCompletionRequest{})
+	checks.HasError(t, err, "CreateChatCompletionStream should fail")
+
+	span := tracer.spans[0]
+	if span.ended != 1 || len(span.errs) != 1 || span.attrs["giteeai.error.type"] != "server_error" {
+		t.Fatalf("unexpected span %+v", span)
+	}
+}